@@ -0,0 +1,419 @@
+// Package address splits the free text Turkish addresses written by the volunteers into il, ilçe, mahalle, street,
+// building number, floor, flat and building name. It is rule based like the extract package.
+package address
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Version is stored with every parse, the backfill parses the documents of older versions again.
+const Version = 1
+
+type Address struct {
+	Il       string `json:"il,omitempty" bson:"il,omitempty"`
+	Ilce     string `json:"ilce,omitempty" bson:"ilce,omitempty"`
+	Mahalle  string `json:"mahalle,omitempty" bson:"mahalle,omitempty"`
+	Cadde    string `json:"cadde,omitempty" bson:"cadde,omitempty"`
+	Sokak    string `json:"sokak,omitempty" bson:"sokak,omitempty"`
+	No       string `json:"no,omitempty" bson:"no,omitempty"`
+	Kat      string `json:"kat,omitempty" bson:"kat,omitempty"`
+	Daire    string `json:"daire,omitempty" bson:"daire,omitempty"`
+	Building string `json:"building,omitempty" bson:"building,omitempty"`
+	// Confidence is between 0 and 1, it grows with the components found and the share of the text they explain.
+	Confidence float64 `json:"confidence" bson:"confidence"`
+	Version    int     `json:"version" bson:"version"`
+}
+
+var mahalleKeywords = map[string]bool{
+	"mahallesi": true, "mahalle": true, "mahalesi": true, "mah": true, "mh": true,
+}
+
+// streetKeywords map the abbreviations to the canonical suffix, cadde means the street goes to Cadde.
+var streetKeywords = map[string]struct {
+	suffix string
+	cadde  bool
+}{
+	"cadde": {"Caddesi", true}, "caddesi": {"Caddesi", true}, "cad": {"Caddesi", true}, "cd": {"Caddesi", true},
+	"bulvar": {"Bulvarı", true}, "bulvari": {"Bulvarı", true}, "blv": {"Bulvarı", true}, "bulv": {"Bulvarı", true},
+	"yolu":  {"Yolu", true},
+	"sokak": {"Sokak", false}, "sokagi": {"Sokak", false}, "sok": {"Sokak", false}, "sk": {"Sokak", false},
+	"cikmazi": {"Çıkmazı", false}, "cikmaz": {"Çıkmazı", false},
+}
+
+var buildingKeywords = map[string]string{
+	"apartmani": "Apartmanı", "apartman": "Apartmanı", "apt": "Apartmanı", "ap": "Apartmanı",
+	"sitesi": "Sitesi", "site": "Sitesi", "sit": "Sitesi",
+	"blok": "Blok", "blogu": "Blok", "bl": "Blok",
+	"rezidans": "Rezidans", "residence": "Rezidans",
+	"konutlari": "Konutları", "evleri": "Evleri", "lojmanlari": "Lojmanları", "plaza": "Plaza",
+}
+
+var (
+	noKeywords    = map[string]bool{"no": true, "numara": true, "num": true}
+	katKeywords   = map[string]bool{"kat": true}
+	daireKeywords = map[string]bool{"daire": true, "dr": true, "d": true}
+	// Bir şey ifade etmeyen ama adresin parçası olan kelimeler, kapsama oranını düşürmesinler
+	fillers = map[string]bool{"il": true, "ili": true, "ilce": true, "ilcesi": true, "turkiye": true, "nolu": true}
+)
+
+const (
+	weightIl       = 0.1
+	weightIlce     = 0.15
+	weightMahalle  = 0.25
+	weightStreet   = 0.2
+	weightNo       = 0.15
+	weightFlat     = 0.05
+	weightBuilding = 0.1
+)
+
+var turkishUpper = map[rune]rune{'i': 'İ', 'ı': 'I'}
+var turkishLower = map[rune]rune{'İ': 'i', 'I': 'ı'}
+var asciiFold = map[rune]rune{'ı': 'i', 'ş': 's', 'ğ': 'g', 'ü': 'u', 'ö': 'o', 'ç': 'c', 'â': 'a', 'î': 'i', 'û': 'u'}
+
+func lower(r rune) rune {
+	if l, ok := turkishLower[r]; ok {
+		return l
+	}
+
+	return unicode.ToLower(r)
+}
+
+func upper(r rune) rune {
+	if u, ok := turkishUpper[r]; ok {
+		return u
+	}
+
+	return unicode.ToUpper(r)
+}
+
+// fold lowercases with the Turkish rules and folds the Turkish letters to ASCII, rune by rune.
+func fold(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		r = lower(r)
+		if f, ok := asciiFold[r]; ok {
+			r = f
+		}
+		runes[i] = r
+	}
+
+	return string(runes)
+}
+
+// title writes every word with a capital first letter using the Turkish rules, İnönü rather than Inönü.
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		runes := []rune(word)
+		for j, r := range runes {
+			if j == 0 {
+				runes[j] = upper(r)
+			} else {
+				runes[j] = lower(r)
+			}
+		}
+		words[i] = string(runes)
+	}
+
+	return strings.Join(words, " ")
+}
+
+type token struct {
+	text string
+	fold string
+	// delim is set when a comma, slash or similar separates the token from the previous one.
+	delim bool
+	slash bool
+	used  bool
+}
+
+func isDelimiter(r rune) bool {
+	return strings.ContainsRune(",;/\\()\n-", r)
+}
+
+func tokenize(text string) []*token {
+	tokens := make([]*token, 0)
+	current := make([]rune, 0)
+	delim, slash := true, false
+
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, &token{text: string(current), fold: fold(string(current)), delim: delim, slash: slash})
+			current = current[:0]
+			delim, slash = false, false
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case isDelimiter(r):
+			flush()
+			delim = true
+			slash = slash || r == '/'
+		case unicode.IsSpace(r) || r == ':' || r == '.' || r == '\'' || r == '"':
+			flush()
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func isNumber(t *token) bool {
+	return len(t.fold) > 0 && unicode.IsDigit(rune(t.fold[0]))
+}
+
+func isKeyword(t *token) bool {
+	_, street := streetKeywords[t.fold]
+	_, building := buildingKeywords[t.fold]
+
+	return mahalleKeywords[t.fold] || street || building || noKeywords[t.fold] || katKeywords[t.fold] || daireKeywords[t.fold]
+}
+
+func isPlace(t *token) bool {
+	_, il := ilByFold[t.fold]
+	_, ilce := ilceByFold[t.fold]
+
+	return il || ilce
+}
+
+type parser struct {
+	tokens []*token
+}
+
+// name collects up to max words before the keyword at i. A delimiter ends the name, so does an il or ilçe in front
+// of it, "Antakya Cumhuriyet Mah." is in Antakya.
+func (p *parser) name(i, max int) string {
+	start := i
+	for j := i - 1; j >= 0 && i-j <= max; j-- {
+		t := p.tokens[j]
+		if t.used || isKeyword(t) || (isPlace(t) && start < i) {
+			break
+		}
+
+		start = j
+		if t.delim {
+			break
+		}
+	}
+
+	words := make([]string, 0, i-start)
+	for _, t := range p.tokens[start:i] {
+		t.used = true
+		words = append(words, t.text)
+	}
+
+	return title(strings.Join(words, " "))
+}
+
+// number returns the number after the keyword at i.
+func (p *parser) number(i int) string {
+	if i+1 >= len(p.tokens) || p.tokens[i+1].used || !isNumber(p.tokens[i+1]) {
+		return ""
+	}
+
+	p.tokens[i+1].used = true
+
+	return strings.ToUpper(p.tokens[i+1].fold)
+}
+
+// Parse never returns nil, an address without any component has 0 confidence.
+func Parse(text string) *Address {
+	p := &parser{tokens: tokenize(text)}
+	a := &Address{Version: Version}
+
+	for i, t := range p.tokens {
+		if t.used {
+			continue
+		}
+
+		switch {
+		case mahalleKeywords[t.fold]:
+			if name := p.name(i, 3); len(name) > 0 && len(a.Mahalle) == 0 {
+				t.used = true
+				a.Mahalle = name
+			}
+		case noKeywords[t.fold]:
+			if no := p.number(i); len(no) > 0 {
+				t.used = true
+				a.No = no
+
+				// No: 12/3 bina ve daire numarası
+				if next := i + 2; next < len(p.tokens) && p.tokens[next].slash && isNumber(p.tokens[next]) && len(a.Daire) == 0 {
+					p.tokens[next].used = true
+					a.Daire = p.tokens[next].fold
+				}
+			}
+		case katKeywords[t.fold]:
+			if kat := p.number(i); len(kat) > 0 {
+				t.used = true
+				a.Kat = kat
+			}
+		case daireKeywords[t.fold]:
+			if daire := p.number(i); len(daire) > 0 {
+				t.used = true
+				a.Daire = daire
+			}
+		case t.fold == "nolu" && i > 0 && isNumber(p.tokens[i-1]) && !p.tokens[i-1].used:
+			p.tokens[i-1].used = true
+			t.used = true
+			a.No = strings.ToUpper(p.tokens[i-1].fold)
+		}
+	}
+
+	for i, t := range p.tokens {
+		if t.used {
+			continue
+		}
+
+		if street, ok := streetKeywords[t.fold]; ok {
+			name := p.name(i, 3)
+			if len(name) == 0 {
+				continue
+			}
+
+			t.used = true
+			if street.cadde {
+				a.Cadde = name + " " + street.suffix
+			} else {
+				a.Sokak = name + " " + street.suffix
+			}
+		}
+
+		if suffix, ok := buildingKeywords[t.fold]; ok {
+			max := 3
+			if suffix == "Blok" {
+				max = 1
+			}
+
+			name := p.name(i, max)
+			if len(name) == 0 {
+				continue
+			}
+
+			t.used = true
+			a.Building = strings.TrimSpace(a.Building + " " + name + " " + suffix)
+		}
+	}
+
+	for _, t := range p.tokens {
+		if t.used {
+			continue
+		}
+
+		if place, ok := ilceByFold[t.fold]; ok && len(a.Ilce) == 0 {
+			t.used = true
+			a.Ilce = place.ilce
+			if len(a.Il) == 0 {
+				a.Il = place.il
+			}
+		} else if il, ok := ilByFold[t.fold]; ok {
+			t.used = true
+			if len(a.Il) == 0 {
+				a.Il = il
+			}
+		} else if fillers[t.fold] {
+			t.used = true
+		}
+	}
+
+	// Merkez sadece il belliyse ilçedir
+	for _, t := range p.tokens {
+		if !t.used && t.fold == "merkez" && len(a.Il) > 0 && len(a.Ilce) == 0 {
+			t.used = true
+			a.Ilce = merkez
+		}
+	}
+
+	a.Confidence = confidence(a, p.tokens)
+
+	return a
+}
+
+func confidence(a *Address, tokens []*token) float64 {
+	score := 0.0
+	for _, c := range []struct {
+		found  bool
+		weight float64
+	}{
+		{len(a.Il) > 0, weightIl},
+		{len(a.Ilce) > 0, weightIlce},
+		{len(a.Mahalle) > 0, weightMahalle},
+		{len(a.Cadde) > 0 || len(a.Sokak) > 0, weightStreet},
+		{len(a.No) > 0, weightNo},
+		{len(a.Kat) > 0 || len(a.Daire) > 0, weightFlat},
+		{len(a.Building) > 0, weightBuilding},
+	} {
+		if c.found {
+			score += c.weight
+		}
+	}
+
+	if score == 0 {
+		return 0
+	}
+
+	used := 0
+	for _, t := range tokens {
+		if t.used {
+			used++
+		}
+	}
+
+	coverage := float64(used) / float64(len(tokens))
+
+	// İlçe başka bir ilin ilçesiyse ikisinden biri yanlış
+	if place, ok := ilceByFold[fold(a.Ilce)]; ok && len(a.Il) > 0 && place.il != a.Il {
+		score *= 0.7
+	}
+
+	return math.Round((score*0.6+coverage*0.4)*100) / 100
+}
+
+// ParseFields parses the open address and the apartment of a resolution together, nil when both are empty.
+func ParseFields(openAddress, apartment string) *Address {
+	text := strings.Trim(strings.TrimSpace(openAddress)+", "+strings.TrimSpace(apartment), ", ")
+	if len(text) == 0 {
+		return nil
+	}
+
+	return Parse(text)
+}
+
+// Key folds a mahalle or street name for comparisons and drops its type, "İnönü Cd." and "inonu caddesi" are both
+// "inonu".
+func Key(name string) string {
+	words := make([]string, 0)
+	for _, t := range tokenize(name) {
+		if _, street := streetKeywords[t.fold]; street || mahalleKeywords[t.fold] {
+			continue
+		}
+
+		words = append(words, t.fold)
+	}
+
+	return strings.Join(words, " ")
+}
+
+var patternLetters = map[rune]string{
+	'i': "[iıİI]", 'u': "[uüUÜ]", 'o': "[oöOÖ]", 'c': "[cçCÇ]", 's': "[sşSŞ]", 'g': "[gğGĞ]", 'a': "[aâAÂ]",
+}
+
+// Pattern is a case and Turkish letter insensitive regular expression of s, "inonu" matches "İnönü".
+func Pattern(s string) string {
+	b := strings.Builder{}
+	for _, r := range fold(s) {
+		if class, ok := patternLetters[r]; ok {
+			b.WriteString(class)
+		} else {
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	return b.String()
+}
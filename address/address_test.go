@@ -0,0 +1,166 @@
+package address
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Address
+	}{
+		{
+			name: "full address",
+			text: "Hatay Antakya Cumhuriyet Mah. İnönü Cad. No: 12 Kat 3 Daire 5",
+			want: Address{Il: "Hatay", Ilce: "Antakya", Mahalle: "Cumhuriyet", Cadde: "İnönü Caddesi", No: "12", Kat: "3", Daire: "5"},
+		},
+		{
+			name: "abbreviations and building",
+			text: "kurtuluş mh. 123 sk. gül apt no:4/7 iskenderun",
+			want: Address{Il: "Hatay", Ilce: "İskenderun", Mahalle: "Kurtuluş", Sokak: "123 Sokak", No: "4", Daire: "7", Building: "Gül Apartmanı"},
+		},
+		{
+			name: "il alias",
+			text: "Maraş Onikişubat Yenişehir Mahallesi",
+			want: Address{Il: "Kahramanmaraş", Ilce: "Onikişubat", Mahalle: "Yenişehir"},
+		},
+		{
+			name: "merkez is the ilçe when the il is known",
+			text: "Malatya merkez, Fırat mahallesi",
+			want: Address{Il: "Malatya", Ilce: "Merkez", Mahalle: "Fırat"},
+		},
+		{
+			name: "merkez alone is not an ilçe",
+			text: "merkez camii yanı",
+			want: Address{},
+		},
+		{
+			name: "nolu",
+			text: "Atatürk Bulvarı 45 nolu bina",
+			want: Address{Cadde: "Atatürk Bulvarı", No: "45"},
+		},
+		{
+			name: "blok takes one word",
+			text: "Ebrar Sitesi A Blok",
+			want: Address{Building: "Ebrar Sitesi A Blok"},
+		},
+		{
+			name: "nothing",
+			text: "enkaz altında ses var",
+			want: Address{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text)
+
+			want := tt.want
+			want.Confidence = got.Confidence
+			want.Version = Version
+			if *got != want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.text, *got, want)
+			}
+
+			if empty := (tt.want == Address{}); empty != (got.Confidence == 0) {
+				t.Errorf("Parse(%q) confidence = %.2f", tt.text, got.Confidence)
+			}
+		})
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		name   string
+		higher string
+		lower  string
+	}{
+		{"more components", "Hatay Antakya Cumhuriyet Mah. İnönü Cad. No: 12", "Cumhuriyet Mah."},
+		{"less unexplained text", "Cumhuriyet Mah. No: 12", "Cumhuriyet Mah. No: 12 caminin arkasındaki yeşil kapı"},
+		{"ilçe of another il", "Hatay Antakya", "Adana Antakya"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			higher, lower := Parse(tt.higher).Confidence, Parse(tt.lower).Confidence
+			if higher <= lower || higher > 1 {
+				t.Errorf("confidence of %q = %.2f, %q = %.2f", tt.higher, higher, tt.lower, lower)
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		openAddress string
+		apartment   string
+		want        *Address
+	}{
+		{"both empty", " ", "", nil},
+		{"apartment only", "", "Gül Apt. Kat 2", &Address{Kat: "2", Building: "Gül Apartmanı"}},
+		{"joined with a comma", "Cumhuriyet Mah.", "Gül Apt.", &Address{Mahalle: "Cumhuriyet", Building: "Gül Apartmanı"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseFields(tt.openAddress, tt.apartment)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("ParseFields(%q, %q) = %+v, want nil", tt.openAddress, tt.apartment, *got)
+				}
+
+				return
+			}
+
+			want := *tt.want
+			want.Confidence = got.Confidence
+			want.Version = Version
+			if *got != want {
+				t.Errorf("ParseFields(%q, %q) = %+v, want %+v", tt.openAddress, tt.apartment, *got, want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"İnönü Cd.", "inonu"},
+		{"inonu caddesi", "inonu"},
+		{"Cumhuriyet Mahallesi", "cumhuriyet"},
+		{"Gazi Osman Paşa Sokak", "gazi osman pasa"},
+	}
+
+	for _, tt := range tests {
+		if got := Key(tt.name); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		s    string
+		text string
+		want bool
+	}{
+		{"inonu", "İnönü Caddesi", true},
+		{"İnönü", "INONU", true},
+		{"cumhuriyet", "Cumhurıyet", true},
+		{"a.b", "a.b", true},
+		{"a.b", "axb", false},
+		{"inonu", "Atatürk", false},
+	}
+
+	// Mongo sorguları desenle birlikte i seçeneğini kullanıyor
+	for _, tt := range tests {
+		if got := regexp.MustCompile("(?i)" + Pattern(tt.s)).MatchString(tt.text); got != tt.want {
+			t.Errorf("Pattern(%q) matches %q = %t, want %t", tt.s, tt.text, got, tt.want)
+		}
+	}
+}
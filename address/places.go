@@ -0,0 +1,68 @@
+package address
+
+// iller are the 81 provinces, ilceler the districts of the provinces hit by the earthquake. Both are matched after
+// folding, the canonical spelling is stored.
+var iller = []string{
+	"Adana", "Adıyaman", "Afyonkarahisar", "Ağrı", "Amasya", "Ankara", "Antalya", "Artvin", "Aydın", "Balıkesir",
+	"Bilecik", "Bingöl", "Bitlis", "Bolu", "Burdur", "Bursa", "Çanakkale", "Çankırı", "Çorum", "Denizli",
+	"Diyarbakır", "Edirne", "Elazığ", "Erzincan", "Erzurum", "Eskişehir", "Gaziantep", "Giresun", "Gümüşhane",
+	"Hakkari", "Hatay", "Isparta", "Mersin", "İstanbul", "İzmir", "Kars", "Kastamonu", "Kayseri", "Kırklareli",
+	"Kırşehir", "Kocaeli", "Konya", "Kütahya", "Malatya", "Manisa", "Kahramanmaraş", "Mardin", "Muğla", "Muş",
+	"Nevşehir", "Niğde", "Ordu", "Rize", "Sakarya", "Samsun", "Siirt", "Sinop", "Sivas", "Tekirdağ", "Tokat",
+	"Trabzon", "Tunceli", "Şanlıurfa", "Uşak", "Van", "Yozgat", "Zonguldak", "Aksaray", "Bayburt", "Karaman",
+	"Kırıkkale", "Batman", "Şırnak", "Bartın", "Ardahan", "Iğdır", "Yalova", "Karabük", "Kilis", "Osmaniye", "Düzce",
+}
+
+// Halk arasında kullanılan kısa adlar
+var ilAliases = map[string]string{
+	"maras":  "Kahramanmaraş",
+	"kmaras": "Kahramanmaraş",
+	"antep":  "Gaziantep",
+	"gantep": "Gaziantep",
+	"urfa":   "Şanlıurfa",
+	"surfa":  "Şanlıurfa",
+	"icel":   "Mersin",
+}
+
+var ilceler = map[string][]string{
+	"Kahramanmaraş": {"Afşin", "Andırın", "Çağlayancerit", "Dulkadiroğlu", "Ekinözü", "Elbistan", "Göksun", "Nurhak", "Onikişubat", "Pazarcık", "Türkoğlu"},
+	"Hatay":         {"Altınözü", "Antakya", "Arsuz", "Belen", "Defne", "Dörtyol", "Erzin", "Hassa", "İskenderun", "Kırıkhan", "Kumlu", "Payas", "Reyhanlı", "Samandağ", "Yayladağı"},
+	"Gaziantep":     {"Araban", "İslahiye", "Karkamış", "Nizip", "Nurdağı", "Oğuzeli", "Şahinbey", "Şehitkamil", "Yavuzeli"},
+	"Malatya":       {"Akçadağ", "Arapgir", "Arguvan", "Battalgazi", "Darende", "Doğanşehir", "Doğanyol", "Hekimhan", "Kale", "Kuluncak", "Pütürge", "Yazıhan", "Yeşilyurt"},
+	"Adıyaman":      {"Besni", "Çelikhan", "Gerger", "Gölbaşı", "Kahta", "Samsat", "Sincik", "Tut"},
+	"Osmaniye":      {"Bahçe", "Düziçi", "Hasanbeyli", "Kadirli", "Sumbas", "Toprakkale"},
+	"Diyarbakır":    {"Bağlar", "Bismil", "Çermik", "Çınar", "Çüngüş", "Dicle", "Eğil", "Ergani", "Hani", "Hazro", "Kayapınar", "Kocaköy", "Kulp", "Lice", "Silvan", "Sur", "Yenişehir"},
+	"Adana":         {"Aladağ", "Ceyhan", "Çukurova", "Feke", "İmamoğlu", "Karaisalı", "Karataş", "Kozan", "Pozantı", "Saimbeyli", "Sarıçam", "Seyhan", "Tufanbeyli", "Yumurtalık", "Yüreğir"},
+	"Şanlıurfa":     {"Akçakale", "Birecik", "Bozova", "Ceylanpınar", "Eyyübiye", "Halfeti", "Haliliye", "Harran", "Hilvan", "Karaköprü", "Siverek", "Suruç", "Viranşehir"},
+	"Kilis":         {"Elbeyli", "Musabeyli", "Polateli"},
+	"Elazığ":        {"Ağın", "Alacakaya", "Arıcak", "Baskil", "Karakoçan", "Keban", "Kovancılar", "Maden", "Palu", "Sivrice"},
+}
+
+// merkez is the central district of every province, it is only an ilçe when the il is known.
+const merkez = "Merkez"
+
+type place struct {
+	il   string
+	ilce string
+}
+
+var (
+	ilByFold   = make(map[string]string)
+	ilceByFold = make(map[string]*place)
+)
+
+func init() {
+	for _, il := range iller {
+		ilByFold[fold(il)] = il
+	}
+
+	for alias, il := range ilAliases {
+		ilByFold[alias] = il
+	}
+
+	for il, names := range ilceler {
+		for _, ilce := range names {
+			ilceByFold[fold(ilce)] = &place{il: il, ilce: ilce}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package backfill
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/address"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+)
+
+// addressesJob parses the open addresses stored before the parser existed, or parsed by an older version of it.
+type addressesJob struct {
+	locations locations.Repository
+}
+
+func NewAddressesJob(locations locations.Repository) Job {
+	return &addressesJob{
+		locations: locations,
+	}
+}
+
+func (j *addressesJob) Name() string {
+	return "addresses"
+}
+
+func (j *addressesJob) Fetch(ctx context.Context, after string, limit int) ([]*Item, error) {
+	cursor, err := objectIDCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	locs, err := j.locations.GetDocumentsWithoutAddress(ctx, cursor, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, 0, len(locs))
+	for _, loc := range locs {
+		items = append(items, &Item{
+			ID:     strconv.Itoa(loc.EntryID),
+			Cursor: loc.ID.Hex(),
+			Value:  loc,
+		})
+	}
+
+	return items, nil
+}
+
+func (j *addressesJob) Count(ctx context.Context, after string) (int64, error) {
+	cursor, err := objectIDCursor(after)
+	if err != nil {
+		return 0, err
+	}
+
+	return j.locations.CountDocumentsWithoutAddress(ctx, cursor)
+}
+
+func (j *addressesJob) Process(ctx context.Context, item *Item) error {
+	loc := item.Value.(*locations.LocationDB)
+
+	return j.locations.WithProject(loc.ProjectID).SetParsedAddress(ctx, loc.EntryID, address.ParseFields(loc.OpenAddress, loc.Apartment))
+}
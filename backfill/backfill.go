@@ -0,0 +1,213 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/jobs"
+	"github.com/sirupsen/logrus"
+)
+
+// Item is a single unit of work. Cursor must grow in the order Fetch returns items.
+type Item struct {
+	ID     string
+	Cursor string
+	Value  interface{}
+}
+
+type Job interface {
+	Name() string
+	// Fetch returns at most limit items that come after the given cursor. An empty cursor means from the start.
+	Fetch(ctx context.Context, after string, limit int) ([]*Item, error)
+	Process(ctx context.Context, item *Item) error
+}
+
+// Counter is implemented by jobs that can tell how many items are left, used for progress reporting.
+type Counter interface {
+	Count(ctx context.Context, after string) (int64, error)
+}
+
+type Options struct {
+	Concurrency   int
+	RatePerSecond float64
+	BatchSize     int
+	MaxAttempts   int
+	RetryDelay    time.Duration
+}
+
+type Runner interface {
+	Run(ctx context.Context, job Job) error
+}
+
+type runner struct {
+	jobs jobs.Repository
+	opts Options
+}
+
+func NewRunner(jobs jobs.Repository, opts Options) Runner {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+
+	return &runner{
+		jobs: jobs,
+		opts: opts,
+	}
+}
+
+func (r *runner) Run(ctx context.Context, job Job) error {
+	checkpoint, err := r.jobs.GetCheckpoint(ctx, job.Name())
+	if err != nil {
+		return err
+	}
+
+	if checkpoint.Done {
+		logrus.Infof("[%s] already complete, reset the checkpoint to run it again", job.Name())
+
+		return nil
+	}
+
+	total := int64(-1)
+	if counter, ok := job.(Counter); ok {
+		if total, err = counter.Count(ctx, checkpoint.Cursor); err != nil {
+			return err
+		}
+	}
+
+	logrus.Infof("[%s] starting from cursor %q (%d processed, %d failed so far)", job.Name(), checkpoint.Cursor, checkpoint.Processed, checkpoint.Failed)
+
+	limiter := newLimiter(r.opts.RatePerSecond)
+	defer limiter.stop()
+
+	started := time.Now()
+	processed := 0
+
+	for {
+		items, err := job.Fetch(ctx, checkpoint.Cursor, r.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			break
+		}
+
+		failed := r.processBatch(ctx, job, limiter, items)
+		if err := ctx.Err(); err != nil {
+			// Yarım kalan batch'i checkpoint'e yazmıyoruz, bir sonraki çalıştırmada baştan işlenecek
+			return err
+		}
+
+		processed += len(items)
+		checkpoint.Cursor = items[len(items)-1].Cursor
+		checkpoint.Processed += len(items) - failed
+		checkpoint.Failed += failed
+
+		if err := r.jobs.SaveCheckpoint(ctx, checkpoint); err != nil {
+			return err
+		}
+
+		r.reportProgress(job, checkpoint, processed, total, started)
+	}
+
+	checkpoint.Done = true
+	if err := r.jobs.SaveCheckpoint(ctx, checkpoint); err != nil {
+		return err
+	}
+
+	logrus.Infof("[%s] complete: %d processed, %d failed in %s", job.Name(), checkpoint.Processed, checkpoint.Failed, time.Since(started).Round(time.Second))
+
+	return nil
+}
+
+func (r *runner) processBatch(ctx context.Context, job Job, limiter *limiter, items []*Item) int {
+	wg := &sync.WaitGroup{}
+	sem := make(chan struct{}, r.opts.Concurrency)
+
+	mu := &sync.Mutex{}
+	failed := 0
+
+	for _, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+
+			return failed
+		}
+
+		wg.Add(1)
+
+		go func(item *Item) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := r.processItem(ctx, job, limiter, item); err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(item)
+	}
+
+	wg.Wait()
+
+	return failed
+}
+
+func (r *runner) processItem(ctx context.Context, job Job, limiter *limiter, item *Item) error {
+	var err error
+
+	for attempt := 1; attempt <= r.opts.MaxAttempts; attempt++ {
+		if err = limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		if err = job.Process(ctx, item); err == nil {
+			return nil
+		}
+
+		logrus.Warnf("[%s] item %s failed (attempt %d/%d): %s", job.Name(), item.ID, attempt, r.opts.MaxAttempts, err)
+
+		if attempt < r.opts.MaxAttempts {
+			select {
+			case <-time.After(r.opts.RetryDelay * time.Duration(attempt)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	if dlErr := r.jobs.AddDeadLetter(ctx, &jobs.DeadLetter{
+		Job:      job.Name(),
+		ItemID:   item.ID,
+		Error:    err.Error(),
+		Attempts: r.opts.MaxAttempts,
+	}); dlErr != nil {
+		logrus.Errorf("[%s] couldn't dead-letter item %s: %s", job.Name(), item.ID, dlErr)
+	}
+
+	return err
+}
+
+func (r *runner) reportProgress(job Job, checkpoint *jobs.Checkpoint, processed int, total int64, started time.Time) {
+	rate := float64(processed) / time.Since(started).Seconds()
+
+	progress := fmt.Sprintf("%d", processed)
+	if total >= 0 {
+		progress = fmt.Sprintf("%d/%d", processed, total)
+	}
+
+	logrus.Infof("[%s] %s items this run (%.1f/s), %d processed, %d failed in total", job.Name(), progress, rate, checkpoint.Processed, checkpoint.Failed)
+}
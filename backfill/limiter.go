@@ -0,0 +1,40 @@
+package backfill
+
+import (
+	"context"
+	"time"
+)
+
+// limiter lets through at most one call per tick. A zero rate disables limiting.
+type limiter struct {
+	ticker *time.Ticker
+}
+
+func newLimiter(ratePerSecond float64) *limiter {
+	if ratePerSecond <= 0 {
+		return &limiter{}
+	}
+
+	return &limiter{
+		ticker: time.NewTicker(time.Duration(float64(time.Second) / ratePerSecond)),
+	}
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	if l.ticker == nil {
+		return ctx.Err()
+	}
+
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
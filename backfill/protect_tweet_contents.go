@@ -0,0 +1,79 @@
+package backfill
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/extract"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+)
+
+// protectTweetContentsJob redacts and encrypts the tweet contents stored in plain text before the pii package.
+type protectTweetContentsJob struct {
+	locations locations.Repository
+	protector pii.Protector
+}
+
+func NewProtectTweetContentsJob(locations locations.Repository, protector pii.Protector) Job {
+	return &protectTweetContentsJob{
+		locations: locations,
+		protector: protector,
+	}
+}
+
+func (j *protectTweetContentsJob) Name() string {
+	return "protect-tweet-contents"
+}
+
+func (j *protectTweetContentsJob) Fetch(ctx context.Context, after string, limit int) ([]*Item, error) {
+	cursor, err := objectIDCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	locs, err := j.locations.GetDocumentsWithPlainTweetContents(ctx, cursor, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, 0, len(locs))
+	for _, loc := range locs {
+		items = append(items, &Item{
+			ID:     strconv.Itoa(loc.EntryID),
+			Cursor: loc.ID.Hex(),
+			Value:  loc,
+		})
+	}
+
+	return items, nil
+}
+
+func (j *protectTweetContentsJob) Count(ctx context.Context, after string) (int64, error) {
+	cursor, err := objectIDCursor(after)
+	if err != nil {
+		return 0, err
+	}
+
+	return j.locations.CountDocumentsWithPlainTweetContents(ctx, cursor)
+}
+
+func (j *protectTweetContentsJob) Process(ctx context.Context, item *Item) error {
+	loc := item.Value.(*locations.LocationDB)
+	// Entry id sadece proje içinde tekil
+	repo := j.locations.WithProject(loc.ProjectID)
+
+	// Sinyaller redaksiyondan önce çıkarılmalı, yoksa telefonlar kaybolur
+	if loc.Signals == nil {
+		if err := repo.SetSignals(ctx, loc.EntryID, extract.Extract(loc.TweetContents)); err != nil {
+			return err
+		}
+	}
+
+	protected, err := j.protector.Protect(loc.TweetContents)
+	if err != nil {
+		return err
+	}
+
+	return repo.SetTweetContents(ctx, loc.EntryID, protected)
+}
@@ -0,0 +1,76 @@
+package backfill
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/extract"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+)
+
+// signalsJob extracts the urgency, needs and contacts of documents stored before extraction existed.
+type signalsJob struct {
+	locations locations.Repository
+	protector pii.Protector
+}
+
+func NewSignalsJob(locations locations.Repository, protector pii.Protector) Job {
+	return &signalsJob{
+		locations: locations,
+		protector: protector,
+	}
+}
+
+func (j *signalsJob) Name() string {
+	return "signals"
+}
+
+func (j *signalsJob) Fetch(ctx context.Context, after string, limit int) ([]*Item, error) {
+	cursor, err := objectIDCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	locs, err := j.locations.GetDocumentsWithoutSignals(ctx, cursor, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, 0, len(locs))
+	for _, loc := range locs {
+		items = append(items, &Item{
+			ID:     strconv.Itoa(loc.EntryID),
+			Cursor: loc.ID.Hex(),
+			Value:  loc,
+		})
+	}
+
+	return items, nil
+}
+
+func (j *signalsJob) Count(ctx context.Context, after string) (int64, error) {
+	cursor, err := objectIDCursor(after)
+	if err != nil {
+		return 0, err
+	}
+
+	return j.locations.CountDocumentsWithoutSignals(ctx, cursor)
+}
+
+func (j *signalsJob) Process(ctx context.Context, item *Item) error {
+	loc := item.Value.(*locations.LocationDB)
+
+	// Telefonlar redakte edilmiş metinde kalmıyor, şifreli metin varsa ondan çıkarıyoruz
+	text := loc.TweetContents
+	if len(loc.TweetContentsEncrypted) > 0 {
+		raw, err := j.protector.Reveal(loc.TweetContentsEncrypted)
+		if err != nil {
+			return err
+		}
+
+		text = raw
+	}
+
+	return j.locations.WithProject(loc.ProjectID).SetSignals(ctx, loc.EntryID, extract.Extract(text))
+}
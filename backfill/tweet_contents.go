@@ -0,0 +1,134 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/extract"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// tweetContentsJob hydrates tweet_contents of old documents from the feed of their project.
+type tweetContentsJob struct {
+	locations locations.Repository
+	projects  projects.Repository
+	feeds     tools.Feeds
+	protector pii.Protector
+
+	mu     sync.Mutex
+	loaded map[string]*projects.Project
+}
+
+func NewTweetContentsJob(locations locations.Repository, projectRepository projects.Repository, feeds tools.Feeds, protector pii.Protector) Job {
+	return &tweetContentsJob{
+		locations: locations,
+		projects:  projectRepository,
+		feeds:     feeds,
+		protector: protector,
+		loaded:    make(map[string]*projects.Project),
+	}
+}
+
+func (j *tweetContentsJob) Name() string {
+	return "tweet-contents"
+}
+
+func (j *tweetContentsJob) Fetch(ctx context.Context, after string, limit int) ([]*Item, error) {
+	cursor, err := objectIDCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	locs, err := j.locations.GetDocumentsWithNoTweetContents(ctx, cursor, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, 0, len(locs))
+	for _, loc := range locs {
+		items = append(items, &Item{
+			ID:     strconv.Itoa(loc.EntryID),
+			Cursor: loc.ID.Hex(),
+			Value:  loc,
+		})
+	}
+
+	return items, nil
+}
+
+func (j *tweetContentsJob) Count(ctx context.Context, after string) (int64, error) {
+	cursor, err := objectIDCursor(after)
+	if err != nil {
+		return 0, err
+	}
+
+	return j.locations.CountDocumentsWithNoTweetContents(ctx, cursor)
+}
+
+func (j *tweetContentsJob) Process(ctx context.Context, item *Item) error {
+	loc := item.Value.(*locations.LocationDB)
+	// Entry id sadece proje içinde tekil
+	repo := j.locations.WithProject(loc.ProjectID)
+
+	project, err := j.project(ctx, loc.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	resp, err := j.feeds.For(project).GetSingleLocation(ctx, loc.EntryID)
+	if err != nil {
+		return err
+	}
+
+	protected, err := j.protector.Protect(resp.FullText)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.SetTweetContents(ctx, loc.EntryID, protected); err != nil {
+		return err
+	}
+
+	return repo.SetSignals(ctx, loc.EntryID, extract.Extract(resp.FullText))
+}
+
+// project loads a project once per run, documents without a project belong to the default one.
+func (j *tweetContentsJob) project(ctx context.Context, id string) (*projects.Project, error) {
+	if len(id) == 0 {
+		id = projects.DefaultID
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if project, ok := j.loaded[id]; ok {
+		return project, nil
+	}
+
+	project, err := j.projects.GetProject(ctx, id)
+	if errors.Is(err, projects.ErrNotFound) && id == projects.DefaultID {
+		// Uygulama hiç başlatılmadıysa varsayılan proje yazılmamıştır, config'deki feed kullanılır
+		project, err = &projects.Project{ID: projects.DefaultID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	j.loaded[id] = project
+
+	return project, nil
+}
+
+func objectIDCursor(cursor string) (primitive.ObjectID, error) {
+	if len(cursor) == 0 {
+		return primitive.NilObjectID, nil
+	}
+
+	return primitive.ObjectIDFromHex(cursor)
+}
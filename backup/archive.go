@@ -0,0 +1,173 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// archiveWriter gzips one extended JSON document per line and hashes the compressed bytes as they are written.
+type archiveWriter struct {
+	f         *os.File
+	gz        *gzip.Writer
+	hash      hash.Hash
+	size      *counter
+	documents int64
+	closed    bool
+}
+
+type counter struct {
+	n int64
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+
+	return len(p), nil
+}
+
+func createArchive(path string) (*archiveWriter, error) {
+	// Yedekler kişisel veri içeriyor, sadece sahibi okuyabilsin
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &archiveWriter{
+		f:    f,
+		hash: sha256.New(),
+		size: &counter{},
+	}
+	w.gz = gzip.NewWriter(io.MultiWriter(f, w.hash, w.size))
+
+	return w, nil
+}
+
+// Write keeps the BSON types, canonical extended JSON tells int32, int64 and double apart.
+func (w *archiveWriter) Write(doc bson.Raw) error {
+	line, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.gz.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	w.documents++
+
+	return nil
+}
+
+func (w *archiveWriter) Close() error {
+	w.closed = true
+
+	if err := w.gz.Close(); err != nil {
+		w.f.Close()
+
+		return err
+	}
+
+	if err := w.f.Sync(); err != nil {
+		w.f.Close()
+
+		return err
+	}
+
+	return w.f.Close()
+}
+
+// Abort removes the file if it wasn't closed, it is a no-op after Close.
+func (w *archiveWriter) Abort() {
+	if w.closed {
+		return
+	}
+
+	w.closed = true
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
+
+func (w *archiveWriter) Sum() string {
+	return hex.EncodeToString(w.hash.Sum(nil))
+}
+
+// verifyChecksum reads the whole file, a restore must not start from a corrupted archive.
+func verifyChecksum(path string, file *CollectionFile) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+
+	if n != file.Bytes {
+		return fmt.Errorf("%s is %d bytes, the manifest says %d", file.File, n, file.Bytes)
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != file.SHA256 {
+		return fmt.Errorf("%s has checksum %s, the manifest says %s", file.File, sum, file.SHA256)
+	}
+
+	return nil
+}
+
+// readArchive calls fn with every document of the archive in the order they were dumped.
+func readArchive(path string, fn func(doc bson.Raw) error) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+
+	// bufio.Scanner satır uzunluğunu sınırlıyor, büyük dokümanlar için Reader kullanıyoruz
+	r := bufio.NewReaderSize(gz, 1<<20)
+
+	var count int64
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if len(data) > 0 && !(len(data) == 1 && data[0] == '\n') {
+			doc := bson.D{}
+			if err := bson.UnmarshalExtJSON(data, true, &doc); err != nil {
+				return count, fmt.Errorf("line %d: %w", line, err)
+			}
+
+			raw, err := bson.Marshal(doc)
+			if err != nil {
+				return count, fmt.Errorf("line %d: %w", line, err)
+			}
+
+			if err := fn(raw); err != nil {
+				return count, err
+			}
+
+			count++
+		}
+
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package backup writes snapshots of collections as gzipped NDJSON files in MongoDB extended JSON, with a manifest
+// holding the document counts and SHA-256 checksums. Snapshots can be restored selectively and verified against the
+// live database.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ManifestVersion is increased when the layout of a snapshot changes.
+const ManifestVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	// Latest selects the newest snapshot in the directory.
+	Latest = "latest"
+	// TimeFormat is the UTC time in the snapshot names, they sort by time.
+	TimeFormat = "20060102T150405Z"
+)
+
+// DefaultCollections are dumped when no collection is given.
+var DefaultCollections = []string{"locations", "users"}
+
+var ErrNotFound = errors.New("snapshot not found")
+
+type Manifest struct {
+	Version     int               `json:"version"`
+	Name        string            `json:"name"`
+	Database    string            `json:"database"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  time.Time         `json:"finished_at"`
+	Collections []*CollectionFile `json:"collections"`
+}
+
+// CollectionFile is a single collection of a snapshot. Collections are streamed one after the other, so the snapshot
+// is only consistent within a collection.
+type CollectionFile struct {
+	Name       string    `json:"name"`
+	File       string    `json:"file"`
+	Documents  int64     `json:"documents"`
+	Bytes      int64     `json:"bytes"`
+	SHA256     string    `json:"sha256"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+func (m *Manifest) Collection(name string) *CollectionFile {
+	for _, c := range m.Collections {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+type Service interface {
+	// Dump writes the collections to <dir>/<prefix>-<time>, the directory only appears once every file is complete.
+	Dump(ctx context.Context, dir, prefix string, collections []string) (*Manifest, error)
+	// List returns the manifests of the snapshots in dir, the oldest first.
+	List(dir string) ([]*Manifest, error)
+	Restore(ctx context.Context, dir, snapshot string, opts *RestoreOptions) (*RestoreReport, error)
+	// Verify checks the checksums of the snapshot and diffs its documents against the live collections.
+	Verify(ctx context.Context, dir, snapshot string, collections []string) (*VerifyReport, error)
+}
+
+type service struct {
+	mongo    sources.MongoClient
+	database string
+}
+
+func NewService(mongo sources.MongoClient, database string) Service {
+	return &service{
+		mongo:    mongo,
+		database: database,
+	}
+}
+
+func (s *service) Dump(ctx context.Context, dir, prefix string, collections []string) (*Manifest, error) {
+	if len(collections) == 0 {
+		collections = DefaultCollections
+	}
+
+	manifest := &Manifest{
+		Version:     ManifestVersion,
+		Name:        fmt.Sprintf("%s-%s", prefix, time.Now().UTC().Format(TimeFormat)),
+		Database:    s.database,
+		StartedAt:   time.Now(),
+		Collections: make([]*CollectionFile, 0, len(collections)),
+	}
+
+	// Yarım kalan yedek "latest" olarak seçilmesin diye önce gizli bir dizine yazıyoruz
+	partial := filepath.Join(dir, "."+manifest.Name+".partial")
+	if err := os.MkdirAll(partial, 0700); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(partial)
+
+	for _, collection := range collections {
+		file, err := s.dumpCollection(ctx, partial, collection)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", collection, err)
+		}
+
+		logrus.Infof("Dumped %d documents of %s (%d bytes)", file.Documents, collection, file.Bytes)
+
+		manifest.Collections = append(manifest.Collections, file)
+	}
+
+	manifest.FinishedAt = time.Now()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(partial, manifestFile), data, 0600); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(partial, filepath.Join(dir, manifest.Name)); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (s *service) dumpCollection(ctx context.Context, dir, collection string) (*CollectionFile, error) {
+	file := &CollectionFile{
+		Name:      collection,
+		File:      collection + ".ndjson.gz",
+		StartedAt: time.Now(),
+	}
+
+	w, err := createArchive(filepath.Join(dir, file.File))
+	if err != nil {
+		return nil, err
+	}
+	defer w.Abort()
+
+	cur, err := s.mongo.Find(ctx, collection, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		if err := w.Write(cur.Current); err != nil {
+			return nil, err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	file.Documents = w.documents
+	file.Bytes = w.size.n
+	file.SHA256 = w.Sum()
+	file.FinishedAt = time.Now()
+
+	return file, nil
+}
+
+func (s *service) List(dir string) ([]*Manifest, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := make([]*Manifest, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		manifest, err := readManifest(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		manifests = append(manifests, manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].StartedAt.Before(manifests[j].StartedAt) })
+
+	return manifests, nil
+}
+
+// open returns the path and the manifest of a snapshot, snapshot is a name in dir or Latest.
+func (s *service) open(dir, snapshot string) (string, *Manifest, error) {
+	if snapshot == Latest {
+		manifests, err := s.List(dir)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(manifests) == 0 {
+			return "", nil, ErrNotFound
+		}
+
+		snapshot = manifests[len(manifests)-1].Name
+	}
+
+	path := filepath.Join(dir, snapshot)
+
+	manifest, err := readManifest(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil, ErrNotFound
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if manifest.Version > ManifestVersion {
+		return "", nil, fmt.Errorf("snapshot %s has manifest version %d, this tool reads up to %d", manifest.Name, manifest.Version, ManifestVersion)
+	}
+
+	return path, manifest, nil
+}
+
+// selectFiles picks the given collections of the manifest, every collection when none is given.
+func selectFiles(manifest *Manifest, collections []string) ([]*CollectionFile, error) {
+	if len(collections) == 0 {
+		return manifest.Collections, nil
+	}
+
+	files := make([]*CollectionFile, 0, len(collections))
+	for _, name := range collections {
+		file := manifest.Collection(name)
+		if file == nil {
+			return nil, fmt.Errorf("snapshot %s has no %s collection", manifest.Name, name)
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+func readManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(path, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
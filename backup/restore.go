@@ -0,0 +1,418 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+type RestoreOptions struct {
+	// Collections restores every collection of the snapshot when empty.
+	Collections []string
+	// EntryIDs only restores the documents with one of the entry ids, documents without entry_id are left out.
+	EntryIDs []int
+	// Since and Until limit the restore to the documents whose TimeField is in [Since, Until), zero values are open.
+	Since     time.Time
+	Until     time.Time
+	TimeField string
+	// Overwrite replaces the live documents that differ, otherwise only missing documents are inserted.
+	Overwrite bool
+	DryRun    bool
+}
+
+func (o *RestoreOptions) match(doc bson.Raw) bool {
+	if len(o.EntryIDs) > 0 {
+		id, ok := doc.Lookup("entry_id").AsInt64OK()
+		if !ok || !containsInt(o.EntryIDs, int(id)) {
+			return false
+		}
+	}
+
+	if !o.Since.IsZero() || !o.Until.IsZero() {
+		t, ok := doc.Lookup(o.TimeField).TimeOK()
+		if !ok {
+			return false
+		}
+		if !o.Since.IsZero() && t.Before(o.Since) {
+			return false
+		}
+		if !o.Until.IsZero() && !t.Before(o.Until) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type RestoreCount struct {
+	Matched   int64 `json:"matched"`
+	Inserted  int64 `json:"inserted"`
+	Replaced  int64 `json:"replaced"`
+	Unchanged int64 `json:"unchanged"`
+	// Skipped differ from the live document and were kept because Overwrite wasn't set.
+	Skipped int64 `json:"skipped"`
+	// Repurged were replaced and had the personal fields purged since the snapshot (see locations.Purged) removed again.
+	Repurged int64 `json:"repurged"`
+	Failed   int64 `json:"failed"`
+}
+
+type RestoreReport struct {
+	Snapshot    string                   `json:"snapshot"`
+	DryRun      bool                     `json:"dry_run"`
+	Overwrite   bool                     `json:"overwrite"`
+	Collections map[string]*RestoreCount `json:"collections"`
+}
+
+func (s *service) Restore(ctx context.Context, dir, snapshot string, opts *RestoreOptions) (*RestoreReport, error) {
+	path, manifest, err := s.open(dir, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := selectFiles(manifest, opts.Collections)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bozuk bir yedeğin yarısını yazmamak için önce bütün dosyaları kontrol ediyoruz
+	for _, file := range files {
+		if err := verifyChecksum(filepath.Join(path, file.File), file); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &RestoreReport{
+		Snapshot:    manifest.Name,
+		DryRun:      opts.DryRun,
+		Overwrite:   opts.Overwrite,
+		Collections: make(map[string]*RestoreCount),
+	}
+
+	for _, file := range files {
+		count := &RestoreCount{}
+		report.Collections[file.Name] = count
+
+		if _, err := readArchive(filepath.Join(path, file.File), func(doc bson.Raw) error {
+			if !opts.match(doc) {
+				return nil
+			}
+
+			count.Matched++
+
+			if err := s.restoreDocument(ctx, file.Name, doc, opts, count); err != nil {
+				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+					return err
+				}
+
+				logrus.Errorf("%s %s: %s", file.Name, doc.Lookup("_id"), err)
+				count.Failed++
+			}
+
+			return nil
+		}); err != nil {
+			return report, fmt.Errorf("%s: %w", file.Name, err)
+		}
+
+		logrus.Infof("Restored %s: %+v", file.Name, *count)
+	}
+
+	return report, nil
+}
+
+func (s *service) restoreDocument(ctx context.Context, collection string, doc bson.Raw, opts *RestoreOptions, count *RestoreCount) error {
+	doc, err := withProject(collection, doc)
+	if err != nil {
+		return err
+	}
+
+	filter, _, err := keyOf(collection, doc)
+	if err != nil {
+		return err
+	}
+
+	live, err := s.mongo.FindOne(ctx, collection, filter).DecodeBytes()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		count.Inserted++
+		if opts.DryRun {
+			return nil
+		}
+
+		return s.mongo.InsertOne(ctx, collection, doc)
+	}
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(live, doc) {
+		count.Unchanged++
+
+		return nil
+	}
+
+	if !opts.Overwrite {
+		count.Skipped++
+
+		return nil
+	}
+
+	count.Replaced++
+
+	// Retention ya da silme talebiyle temizlenen alanlar yedekten geri gelmesin
+	purged := purgedFields(live)
+	if len(purged) > 0 {
+		count.Repurged++
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+
+	// ResolveLocation sil-ekle yaptığı için canlıdaki _id yedektekinden farklı olabilir, _id değiştirilemiyor
+	replacement := bson.D{}
+	elements, err := doc.Elements()
+	if err != nil {
+		return err
+	}
+	for _, element := range elements {
+		if element.Key() != "_id" {
+			replacement = append(replacement, bson.E{Key: element.Key(), Value: element.Value()})
+		}
+	}
+
+	if err := s.mongo.ReplaceOne(ctx, collection, filter, replacement); err != nil {
+		return err
+	}
+
+	if len(purged) == 0 {
+		return nil
+	}
+
+	update, err := locations.PurgeUpdate(purged)
+	if err != nil {
+		return err
+	}
+
+	return s.mongo.UpdateOne(ctx, collection, filter, update)
+}
+
+// purgedFields returns the purged list of a live location, the other collections have none.
+func purgedFields(live bson.Raw) []string {
+	values, ok := live.Lookup("purged").ArrayOK()
+	if !ok {
+		return nil
+	}
+
+	elements, err := values.Values()
+	if err != nil {
+		return nil
+	}
+
+	fields := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if field, ok := element.StringValueOK(); ok {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// Diff compares a collection of a snapshot with the live one by the keys of keyOf.
+type Diff struct {
+	Archived  int64 `json:"archived"`
+	Live      int64 `json:"live"`
+	Unchanged int64 `json:"unchanged"`
+	// Missing are in the snapshot but not in the live collection, Extra the other way around.
+	Missing []string `json:"missing"`
+	Changed []string `json:"changed"`
+	Extra   []string `json:"extra"`
+}
+
+type VerifyReport struct {
+	Snapshot    string           `json:"snapshot"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Collections map[string]*Diff `json:"collections"`
+}
+
+func (s *service) Verify(ctx context.Context, dir, snapshot string, collections []string) (*VerifyReport, error) {
+	path, manifest, err := s.open(dir, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := selectFiles(manifest, collections)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{
+		Snapshot:    manifest.Name,
+		CreatedAt:   manifest.StartedAt,
+		Collections: make(map[string]*Diff),
+	}
+
+	for _, file := range files {
+		if err := verifyChecksum(filepath.Join(path, file.File), file); err != nil {
+			return nil, err
+		}
+
+		diff, err := s.diff(ctx, filepath.Join(path, file.File), file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+
+		report.Collections[file.Name] = diff
+	}
+
+	return report, nil
+}
+
+func (s *service) diff(ctx context.Context, path string, file *CollectionFile) (*Diff, error) {
+	diff := &Diff{
+		Missing: make([]string, 0),
+		Changed: make([]string, 0),
+		Extra:   make([]string, 0),
+	}
+
+	archived := make(map[string]bool)
+
+	n, err := readArchive(path, func(doc bson.Raw) error {
+		doc, err := withProject(file.Name, doc)
+		if err != nil {
+			return err
+		}
+
+		filter, key, err := keyOf(file.Name, doc)
+		if err != nil {
+			return err
+		}
+
+		archived[key] = true
+
+		live, err := s.mongo.FindOne(ctx, file.Name, filter).DecodeBytes()
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			diff.Missing = append(diff.Missing, key)
+		case err != nil:
+			return err
+		case bytes.Equal(live, doc):
+			diff.Unchanged++
+		default:
+			diff.Changed = append(diff.Changed, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if n != file.Documents {
+		return nil, fmt.Errorf("%s has %d documents, the manifest says %d", file.File, n, file.Documents)
+	}
+	diff.Archived = n
+
+	cur, err := s.mongo.Find(ctx, file.Name, bson.D{}, options.Find().SetProjection(projectionOf(file.Name)))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		diff.Live++
+
+		_, key, err := keyOf(file.Name, cur.Current)
+		if err != nil {
+			return nil, err
+		}
+
+		if !archived[key] {
+			diff.Extra = append(diff.Extra, key)
+		}
+	}
+
+	return diff, cur.Err()
+}
+
+// keyOf returns the filter that finds the live version of a document and a printable key of it. Locations are
+// matched by their entry, a resolution deleted and inserted again gets a new _id.
+func keyOf(collection string, doc bson.Raw) (bson.D, string, error) {
+	if collection != "locations" {
+		id, err := doc.LookupErr("_id")
+		if err != nil {
+			return nil, "", fmt.Errorf("document without _id")
+		}
+
+		return bson.D{{Key: "_id", Value: id}}, id.String(), nil
+	}
+
+	entryID, ok := doc.Lookup("entry_id").AsInt64OK()
+	if !ok {
+		return nil, "", fmt.Errorf("location without entry_id: %s", doc.Lookup("_id"))
+	}
+
+	// Projelerden önceki yedeklerde project_id yok, migration bunları varsayılan projeye taşıdı
+	project, ok := doc.Lookup("project_id").StringValueOK()
+	if !ok {
+		project = projects.DefaultID
+	}
+
+	return bson.D{
+		{Key: "project_id", Value: project},
+		{Key: "entry_id", Value: entryID},
+	}, fmt.Sprintf("%s/%d", project, entryID), nil
+}
+
+// withProject adds the default project to the locations of the snapshots taken before projects, at the end of the
+// document like migration 16 did. Without it restored documents would be invisible to every project.
+func withProject(collection string, doc bson.Raw) (bson.Raw, error) {
+	if collection != "locations" {
+		return doc, nil
+	}
+	if _, err := doc.LookupErr("project_id"); err == nil {
+		return doc, nil
+	}
+
+	idx, scoped := bsoncore.AppendDocumentStart(nil)
+	scoped = append(scoped, doc[4:len(doc)-1]...)
+	scoped = bsoncore.AppendStringElement(scoped, "project_id", projects.DefaultID)
+
+	scoped, err := bsoncore.AppendDocumentEnd(scoped, idx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scoped, nil
+}
+
+func projectionOf(collection string) bson.D {
+	if collection != "locations" {
+		return bson.D{{Key: "_id", Value: 1}}
+	}
+
+	return bson.D{
+		{Key: "project_id", Value: 1},
+		{Key: "entry_id", Value: 1},
+	}
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
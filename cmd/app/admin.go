@@ -2,36 +2,114 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/address"
+	"github.com/YusufOzmen01/veri-kontrol-backend/coords"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/metrics"
+	"github.com/YusufOzmen01/veri-kontrol-backend/extract"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+	"github.com/YusufOzmen01/veri-kontrol-backend/queue"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/audit"
 	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/ratelimits"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/skips"
 	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	"github.com/YusufOzmen01/veri-kontrol-backend/util"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
 	"github.com/gofiber/fiber/v2"
-	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Admin interface {
 	GetLocationEntries(c *fiber.Ctx) error
 	GetSingleEntry(c *fiber.Ctx) error
 	UpdateEntry(c *fiber.Ctx) error
+	GetRateLimitHits(c *fiber.Ctx) error
+	GetStats(c *fiber.Ctx) error
+	GetQueueWeights(c *fiber.Ctx) error
+	SetQueueWeights(c *fiber.Ctx) error
+	PreviewQueue(c *fiber.Ctx) error
+	GetTweetContents(c *fiber.Ctx) error
+	GetAuditLog(c *fiber.Ctx) error
 }
 
 type admin struct {
-	locations locations.Repository
-	cache     sources.Cache
+	locations  locations.Repository
+	rateLimits ratelimits.Repository
+	skips      skips.Repository
+	audits     audit.Repository
+	scheduler  queue.Scheduler
+	feeds      tools.Feeds
+	categories CategoryRegistry
+	protector  pii.Protector
+	normalizer coords.Normalizer
+	geocoding  Geocoding
 }
 
-func NewAdmin(locations locations.Repository, cache sources.Cache) Admin {
+func NewAdmin(locations locations.Repository, rateLimits ratelimits.Repository, skips skips.Repository, audits audit.Repository, scheduler queue.Scheduler, feeds tools.Feeds, categories CategoryRegistry, protector pii.Protector, normalizer coords.Normalizer, geocoding Geocoding) Admin {
 	return &admin{
-		locations: locations,
-		cache:     cache,
+		locations:  locations,
+		rateLimits: rateLimits,
+		skips:      skips,
+		audits:     audits,
+		scheduler:  scheduler,
+		feeds:      feeds,
+		categories: categories,
+		protector:  protector,
+		normalizer: normalizer,
+		geocoding:  geocoding,
 	}
 }
 
+// locationsOf, skipsOf and feedOf return the data of the project of the request.
+func (a *admin) locationsOf(c *fiber.Ctx) locations.Repository {
+	return a.locations.WithProject(currentProject(c).ID)
+}
+
+func (a *admin) skipsOf(c *fiber.Ctx) skips.Repository {
+	return a.skips.WithProject(currentProject(c).ID)
+}
+
+func (a *admin) feedOf(c *fiber.Ctx) tools.Feed {
+	return a.feeds.For(currentProject(c))
+}
+
+// GetLocationEntries lists the resolutions, ?urgency= (minimum, name or level), ?need= (comma separated, all must
+// match), ?has_phone=true and ?min_people= filter them by the extracted signals, ?il=, ?ilce=, ?mahalle= and
+// ?street= by the parsed address and ?category= by the category id.
 func (a *admin) GetLocationEntries(c *fiber.Ctx) error {
-	entries, err := a.locations.GetLocations(c.Context())
+	filter := &locations.Filter{
+		HasPhone:  c.Query("has_phone") == "true",
+		MinPeople: c.QueryInt("min_people"),
+		Il:        c.Query("il"),
+		Ilce:      c.Query("ilce"),
+		Mahalle:   c.Query("mahalle"),
+		Street:    c.Query("street"),
+		Category:  c.Query("category"),
+	}
+
+	if s := c.Query("urgency"); len(s) > 0 {
+		urgency, ok := extract.ParseUrgency(s)
+		if !ok {
+			return c.Status(400).SendString("Invalid urgency.")
+		}
+
+		filter.MinUrgency = urgency
+	}
+
+	for _, need := range strings.Split(c.Query("need"), ",") {
+		if need = strings.TrimSpace(need); len(need) > 0 {
+			filter.Needs = append(filter.Needs, need)
+		}
+	}
+
+	entries, err := a.locationsOf(c).FindLocations(c.UserContext(), filter)
 	if err != nil {
 		return c.SendString(err.Error())
 	}
@@ -41,8 +119,9 @@ func (a *admin) GetLocationEntries(c *fiber.Ctx) error {
 
 func (a *admin) GetSingleEntry(c *fiber.Ctx) error {
 	entryID, _ := strconv.ParseInt(c.Params("entry_id"), 10, 32)
+	logging.SetEntry(c, int(entryID))
 
-	entries, err := a.locations.GetLocations(c.Context())
+	entries, err := a.locationsOf(c).GetLocations(c.UserContext())
 	if err != nil {
 		return c.SendString(err.Error())
 	}
@@ -63,27 +142,62 @@ func (a *admin) UpdateEntry(c *fiber.Ctx) error {
 		return c.SendString(err.Error())
 	}
 
-	locs, err := tools.GetAllLocations(c.Context(), a.cache)
+	logging.SetEntry(c, body.ID)
+
+	locs, err := a.feedOf(c).GetAllLocations(c.UserContext())
 	if err != nil {
-		logrus.Errorln(err)
+		logging.For(c).Errorln(err)
 
 		return c.SendString(err.Error())
 	}
 
 	originalLocation := ""
-	location := make([]float64, 0)
+	original := make([]float64, 0)
 
 	for _, loc := range locs {
 		if loc.EntryID == body.ID {
-			originalLocation = fmt.Sprintf("https://www.google.com/maps/?q=%f,%f&ll=%f,%f&z=21", loc.Loc[0], loc.Loc[1], loc.Loc[0], loc.Loc[1])
-			location = loc.Loc
+			originalLocation = util.MapLink(loc.Loc)
+			original = loc.Loc
 		}
 	}
 
-	if err := a.locations.ResolveLocation(c.Context(), &locations.LocationDB{
+	// Feed'den silinmiş bir kayıt boş konumla kaydedilmesin, bunları reconcile raporluyor
+	if len(original) != 2 {
+		errs := make(validation.Errors, 0)
+		errs.Add("id", "entry %d not found in the feed", body.ID)
+
+		return sendValidationErrors(c, errs)
+	}
+
+	project := currentProject(c)
+
+	registry, err := a.categories.Load(c.UserContext())
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.SendString(err.Error())
+	}
+
+	category, err := resolveCategory(registry, project, body.Category, body.LocationType)
+	if err != nil {
+		return sendValidationErrors(c, err)
+	}
+
+	repo := a.locationsOf(c)
+
+	existing, err := repo.GetLocation(c.UserContext(), body.ID)
+	if err != nil && !errors.Is(err, locations.ErrNotFound) {
+		logging.For(c).Errorln(err)
+
+		return c.SendString(err.Error())
+	}
+
+	update := &locations.LocationDB{
+		ID:               primitive.NewObjectIDFromTimestamp(time.Now()),
 		EntryID:          body.ID,
-		Type:             body.LocationType,
-		Location:         location,
+		Type:             registry.LegacyType(category),
+		Category:         category.ID,
+		Location:         []float64{original[0], original[1]},
 		Corrected:        body.Reason == "Hata Yok",
 		Verified:         true,
 		OriginalAddress:  originalLocation,
@@ -91,11 +205,328 @@ func (a *admin) UpdateEntry(c *fiber.Ctx) error {
 		Reason:           body.Reason,
 		OpenAddress:      body.OpenAddress,
 		Apartment:        body.Apartment,
+		ParsedAddress:    address.ParseFields(body.OpenAddress, body.Apartment),
+		Source:           locations.SourceAdmin,
+	}
+
+	// Moderatör yeni konum vermediyse gönüllünün düzelttiği konum korunur, /resolve'daki gibi yazılan adres sadece ilk
+	// çözümde sözlükten aranıyor
+	input := &coords.Input{Lat: body.Lat, Lng: body.Lng, Text: body.NewAddress}
+	if existing != nil && input.Empty() && strings.ToLower(body.Reason) != "hata yok" {
+		update.Location = existing.Location
+		update.Provenance = existing.Provenance
+		update.Geocode = existing.Geocode
+		update.CorrectedAddress = existing.CorrectedAddress
+	} else {
+		corrected, provenance, geocoded, err := correctedLocation(a.normalizer, a.geocoding, body, original)
+		if err != nil {
+			if errs, ok := err.(validation.Errors); ok {
+				return sendValidationErrors(c, errs)
+			}
+
+			logging.For(c).Errorln(err)
+
+			return c.SendString(err.Error())
+		}
+
+		if corrected != nil {
+			update.Location = corrected
+			update.Provenance = provenance
+			update.Geocode = geocoded
+		}
+	}
+
+	// ResolveLocation sil-ekle yapıyor, moderatörün değiştirmediği alanları eski kayıttan taşıyoruz
+	if existing != nil {
+		update.ID = existing.ID
+		update.Sender = existing.Sender
+		update.TweetContents = existing.TweetContents
+		update.TweetContentsEncrypted = existing.TweetContentsEncrypted
+		update.TweetContentsHash = existing.TweetContentsHash
+		update.DuplicateOf = existing.DuplicateOf
+		update.Signals = existing.Signals
+		update.Purged = existing.Purged
+		update.OrphanedAt = existing.OrphanedAt
+	}
+
+	// Silinmiş kişisel veriler zorunlu olsa da tekrar istenmez
+	required := make([]string, 0)
+	for _, field := range registry.RequiredFields(category) {
+		if !containsString(update.Purged, field) {
+			required = append(required, field)
+		}
+	}
+
+	if err := validation.Resolve(&validation.Resolution{
+		Category:       category.ID,
+		RequiredFields: required,
+		Area:           project.Area(),
+		Location:       update.Location,
+		Original:       original,
+		OpenAddress:    update.OpenAddress,
+		Apartment:      update.Apartment,
+		TweetContents:  update.TweetContents,
 	}); err != nil {
-		logrus.Errorln(err)
+		return sendValidationErrors(c, err)
+	}
+
+	if err := repo.ResolveLocation(c.UserContext(), update); err != nil {
+		logging.For(c).Errorln(err)
 
 		return c.SendString(err.Error())
 	}
 
+	metrics.ObserveResolution(category.ID, body.Reason)
+
 	return c.SendString("")
 }
+
+// GetRateLimitHits lists the rejected requests since the given RFC3339 time, the last 24 hours by default.
+func (a *admin) GetRateLimitHits(c *fiber.Ctx) error {
+	since := time.Now().Add(-24 * time.Hour)
+
+	if s := c.Query("since"); len(s) > 0 {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return c.Status(400).SendString("Invalid since, expected RFC3339.")
+		}
+
+		since = t
+	}
+
+	hits, err := a.rateLimits.GetHits(c.UserContext(), since)
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(hits)
+}
+
+type UserStats struct {
+	Resolutions int64   `json:"resolutions"`
+	Skips       int64   `json:"skips"`
+	SkipRate    float64 `json:"skip_rate"`
+}
+
+type Stats struct {
+	Resolutions   int64                 `json:"resolutions"`
+	Unverified    int64                 `json:"unverified"`
+	Skips         int64                 `json:"skips"`
+	SkipsByReason map[string]int64      `json:"skips_by_reason"`
+	SkipRate      float64               `json:"skip_rate"`
+	Users         map[string]*UserStats `json:"users"`
+}
+
+// skipRate is the share of skips among the decisions, a resolution or a skip.
+func skipRate(resolutions, skips int64) float64 {
+	if resolutions+skips == 0 {
+		return 0
+	}
+
+	return float64(skips) / float64(resolutions+skips)
+}
+
+func (a *admin) GetStats(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	resolved, err := a.locationsOf(c).GetResolvedIDs(ctx)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	unverified, err := a.locationsOf(c).CountUnverified(ctx)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	bySender, err := a.locationsOf(c).CountBySender(ctx)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	skipStats, err := a.skipsOf(c).GetStats(ctx)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	stats := &Stats{
+		Resolutions:   int64(len(resolved)),
+		Unverified:    unverified,
+		Skips:         skipStats.Total,
+		SkipsByReason: skipStats.ByReason,
+		SkipRate:      skipRate(int64(len(resolved)), skipStats.Total),
+		Users:         make(map[string]*UserStats),
+	}
+
+	user := func(id string) *UserStats {
+		if _, ok := stats.Users[id]; !ok {
+			stats.Users[id] = &UserStats{}
+		}
+
+		return stats.Users[id]
+	}
+
+	for id, count := range bySender {
+		user(id).Resolutions = count
+	}
+	for id, count := range skipStats.ByUser {
+		user(id).Skips = count
+	}
+	for _, u := range stats.Users {
+		u.SkipRate = skipRate(u.Resolutions, u.Skips)
+	}
+
+	return c.JSON(stats)
+}
+
+func (a *admin) GetQueueWeights(c *fiber.Ctx) error {
+	weights, err := a.scheduler.Weights(c.UserContext())
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(weights)
+}
+
+// SetQueueWeights applies to every replica from the next /get-location on.
+func (a *admin) SetQueueWeights(c *fiber.Ctx) error {
+	weights := &config.Weights{}
+	if err := json.Unmarshal(c.Body(), weights); err != nil {
+		return c.Status(400).SendString(err.Error())
+	}
+
+	userID, _ := c.Locals(logging.LocalUserID).(string)
+
+	if err := a.scheduler.SetWeights(c.UserContext(), weights, userID); err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	logging.For(c).Infof("queue weights changed to %+v", *weights)
+
+	return c.JSON(weights)
+}
+
+// PreviewQueue shows the best ranked unresolved entries with their factors, to see the effect of the weights.
+func (a *admin) PreviewQueue(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	feed, err := a.feedOf(c).GetAllLocations(ctx)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	resolved, err := a.locationsOf(c).GetResolvedIDs(ctx)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	skipCounts, err := a.skipsOf(c).GetSkipCounts(ctx)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	unresolved := make([]*locations.Location, 0, len(feed))
+	for _, loc := range feed {
+		if !resolved[loc.EntryID] {
+			unresolved = append(unresolved, loc)
+		}
+	}
+
+	candidates, err := a.scheduler.WithProject(currentProject(c)).Rank(ctx, unresolved, skipCounts)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if limit := c.QueryInt("limit", 20); limit >= 0 && limit < len(candidates) {
+		candidates = candidates[:limit]
+	}
+
+	return c.JSON(candidates)
+}
+
+// GetTweetContents decrypts the raw tweet of a resolution, the read is audit-logged.
+func (a *admin) GetTweetContents(c *fiber.Ctx) error {
+	entryID, err := strconv.Atoi(c.Params("entry_id"))
+	if err != nil {
+		return c.Status(400).SendString("Invalid entry id.")
+	}
+
+	logging.SetEntry(c, entryID)
+
+	entry, err := a.locationsOf(c).GetLocation(c.UserContext(), entryID)
+	if errors.Is(err, locations.ErrNotFound) {
+		return c.Status(404).SendString("Entry not found.")
+	}
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	// protect-tweet-contents backfill'i çalışmadan önce kaydedilenler hâlâ düz metin
+	tweetContents := entry.TweetContents
+	if len(entry.TweetContentsEncrypted) > 0 {
+		if tweetContents, err = a.protector.Reveal(entry.TweetContentsEncrypted); err != nil {
+			logging.For(c).Errorln(err)
+
+			return c.Status(500).SendString(err.Error())
+		}
+	}
+
+	if err := recordRead(c, a.audits, currentUser(c), audit.ActionReadTweetContents, currentProject(c).ID, entryID); err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(struct {
+		EntryID       int    `json:"entry_id"`
+		TweetContents string `json:"tweet_contents"`
+	}{
+		EntryID:       entryID,
+		TweetContents: tweetContents,
+	})
+}
+
+// GetAuditLog lists the reads of raw tweet text, newest first, filtered by ?user_id=, ?project_id=, ?entry_id= and
+// ?since=. An entry id is only unique together with ?project_id=.
+func (a *admin) GetAuditLog(c *fiber.Ctx) error {
+	filter := &audit.Filter{
+		UserID:    c.Query("user_id"),
+		ProjectID: c.Query("project_id"),
+		EntryID:   c.QueryInt("entry_id"),
+		Limit:     int64(c.QueryInt("limit", 100)),
+	}
+
+	if s := c.Query("since"); len(s) > 0 {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return c.Status(400).SendString("Invalid since, expected RFC3339.")
+		}
+
+		filter.Since = t
+	}
+
+	records, err := a.audits.GetRecords(c.UserContext(), filter)
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(records)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	categoriesRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/categories"
+	projectsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+const categoriesCacheKey = "categories"
+
+// categoryTTL is how long a replica may keep accepting a category after it was archived on another replica.
+const categoryTTL = time.Minute
+
+// CategoryRegistry loads every category once per categoryTTL, /resolve looks them up on every request.
+type CategoryRegistry interface {
+	Load(ctx context.Context) (*categoriesRepository.Registry, error)
+	Invalidate()
+}
+
+type categoryRegistry struct {
+	repo  categoriesRepository.Repository
+	cache sources.Cache
+}
+
+func NewCategoryRegistry(repo categoriesRepository.Repository, cache sources.Cache) CategoryRegistry {
+	return &categoryRegistry{
+		repo:  repo,
+		cache: cache,
+	}
+}
+
+func (r *categoryRegistry) Load(ctx context.Context) (*categoriesRepository.Registry, error) {
+	if cached, ok := r.cache.Get(categoriesCacheKey); ok {
+		return cached.(*categoriesRepository.Registry), nil
+	}
+
+	categories, err := r.repo.GetCategories(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := categoriesRepository.NewRegistry(categories)
+	r.cache.SetWithTTL(categoriesCacheKey, registry, 1, categoryTTL)
+
+	return registry, nil
+}
+
+func (r *categoryRegistry) Invalidate() {
+	r.cache.Del(categoriesCacheKey)
+}
+
+// resolveCategory finds the category of a resolution. category wins over the legacy integer type, which is only
+// used by the clients that don't know about categories yet.
+func resolveCategory(registry *categoriesRepository.Registry, project *projectsRepository.Project, id string, legacyType int) (*categoriesRepository.Category, error) {
+	errs := make(validation.Errors, 0)
+
+	var category *categoriesRepository.Category
+	switch {
+	case len(id) > 0:
+		if category = registry.Get(id); category == nil {
+			errs.Add("category", "unknown category %q", id)
+		}
+	case legacyType > 0:
+		if category = registry.Legacy(legacyType); category == nil {
+			errs.Add("type", "unknown type %d", legacyType)
+		}
+	default:
+		errs.Add("category", "is required")
+	}
+
+	if category != nil && category.Archived() {
+		errs.Add("category", "category %q is archived", category.ID)
+	}
+	if category != nil && !project.AllowsCategory(category) {
+		errs.Add("category", "category %q is not used in project %s", category.ID, project.ID)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return category, nil
+}
+
+// CategoryView is a category as the frontend shows it, Name is in the requested language and RequiredFields include
+// the ones of the parent.
+type CategoryView struct {
+	*categoriesRepository.Category
+	Name           string   `json:"name"`
+	RequiredFields []string `json:"required_fields"`
+}
+
+type CategoryBody struct {
+	ID             string            `json:"_id"`
+	Parent         string            `json:"parent"`
+	LegacyType     int               `json:"legacy_type"`
+	Names          map[string]string `json:"names"`
+	Color          string            `json:"color"`
+	RequiredFields []string          `json:"required_fields"`
+	Order          int               `json:"order"`
+}
+
+func (b *CategoryBody) category() *categoriesRepository.Category {
+	category := &categoriesRepository.Category{
+		ID:             b.ID,
+		Parent:         b.Parent,
+		LegacyType:     b.LegacyType,
+		Names:          b.Names,
+		Color:          b.Color,
+		RequiredFields: b.RequiredFields,
+		Order:          b.Order,
+	}
+	if category.Names == nil {
+		category.Names = make(map[string]string)
+	}
+	if category.RequiredFields == nil {
+		category.RequiredFields = make([]string, 0)
+	}
+
+	return category
+}
+
+type Categories interface {
+	// GetCategories is public, it lists the active categories of the project of the request.
+	GetCategories(c *fiber.Ctx) error
+	ListCategories(c *fiber.Ctx) error
+	CreateCategory(c *fiber.Ctx) error
+	UpdateCategory(c *fiber.Ctx) error
+	ArchiveCategory(c *fiber.Ctx) error
+}
+
+type categoriesAdmin struct {
+	repo     categoriesRepository.Repository
+	registry CategoryRegistry
+}
+
+func NewCategories(repo categoriesRepository.Repository, registry CategoryRegistry) Categories {
+	return &categoriesAdmin{
+		repo:     repo,
+		registry: registry,
+	}
+}
+
+// GetCategories names the categories in ?lang=, Turkish by default.
+func (a *categoriesAdmin) GetCategories(c *fiber.Ctx) error {
+	registry, err := a.registry.Load(c.UserContext())
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	project := currentProject(c)
+	lang := c.Query("lang", categoriesRepository.DefaultLanguage)
+
+	views := make([]*CategoryView, 0)
+	for _, category := range registry.Active() {
+		if !project.AllowsCategory(category) {
+			continue
+		}
+
+		views = append(views, &CategoryView{
+			Category:       category,
+			Name:           category.Name(lang),
+			RequiredFields: registry.RequiredFields(category),
+		})
+	}
+
+	return c.JSON(views)
+}
+
+// ListCategories lists the active categories as they are stored, ?archived=true includes the archived ones.
+func (a *categoriesAdmin) ListCategories(c *fiber.Ctx) error {
+	categories, err := a.repo.GetCategories(c.UserContext(), c.Query("archived") == "true")
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(categories)
+}
+
+func (a *categoriesAdmin) CreateCategory(c *fiber.Ctx) error {
+	body := &CategoryBody{}
+	if err := json.Unmarshal(c.Body(), body); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	category := body.category()
+	if err := a.validate(c.UserContext(), category); err != nil {
+		var errs validation.Errors
+		if errors.As(err, &errs) {
+			return sendValidationErrors(c, errs)
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if err := a.repo.CreateCategory(c.UserContext(), category); err != nil {
+		if errors.Is(err, categoriesRepository.ErrExists) {
+			return c.Status(fiber.StatusConflict).SendString("Category already exists.")
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	a.registry.Invalidate()
+
+	logging.For(c).Infof("category %s created", category.ID)
+
+	return c.Status(fiber.StatusCreated).JSON(category)
+}
+
+// UpdateCategory replaces everything but the id and the legacy type of :category_id.
+func (a *categoriesAdmin) UpdateCategory(c *fiber.Ctx) error {
+	body := &CategoryBody{}
+	if err := json.Unmarshal(c.Body(), body); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	existing, err := a.repo.GetCategory(c.UserContext(), c.Params("category_id"))
+	if errors.Is(err, categoriesRepository.ErrNotFound) {
+		return c.Status(404).SendString("Category not found.")
+	}
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	category := body.category()
+	category.ID = existing.ID
+	category.LegacyType = existing.LegacyType
+
+	if err := a.validate(c.UserContext(), category); err != nil {
+		var errs validation.Errors
+		if errors.As(err, &errs) {
+			return sendValidationErrors(c, errs)
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if err := a.repo.UpdateCategory(c.UserContext(), category); err != nil {
+		if errors.Is(err, categoriesRepository.ErrNotFound) {
+			return c.Status(404).SendString("Category not found.")
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	a.registry.Invalidate()
+
+	category.Status = existing.Status
+	category.CreatedAt = existing.CreatedAt
+
+	return c.JSON(category)
+}
+
+func (a *categoriesAdmin) ArchiveCategory(c *fiber.Ctx) error {
+	id := c.Params("category_id")
+
+	if err := a.repo.ArchiveCategory(c.UserContext(), id); err != nil {
+		if errors.Is(err, categoriesRepository.ErrNotFound) {
+			return c.Status(404).SendString("Category not found.")
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	// Diğer replikalar kategoriyi en geç categoryTTL sonra arşivlenmiş görür
+	a.registry.Invalidate()
+
+	logging.For(c).Infof("category %s archived", id)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// validate returns validation.Errors when the category itself or its place in the registry is invalid.
+func (a *categoriesAdmin) validate(ctx context.Context, category *categoriesRepository.Category) error {
+	if err := category.Validate(); err != nil {
+		return err
+	}
+
+	registry, err := a.registry.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	return registry.Validate(category)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/events"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	locationsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	projectsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/sirupsen/logrus"
+)
+
+// locationEvent tags the event with the project of the location and the region of that project it is in.
+func locationEvent(project *projectsRepository.Project, location *locationsRepository.LocationDB) *events.Event {
+	eventType := events.TypeResolution
+	switch location.Source {
+	case locationsRepository.SourceAdmin:
+		eventType = events.TypeAdminUpdate
+	case locationsRepository.SourceReconcile:
+		eventType = events.TypeMoved
+	}
+
+	return &events.Event{
+		Type:      eventType,
+		Project:   project.ID,
+		EntryID:   location.EntryID,
+		EntryType: location.Type,
+		Category:  location.Category,
+		Region:    project.RegionOf(location.Location),
+		Time:      location.UpdatedAt,
+		Data:      location,
+	}
+}
+
+// watchLocations publishes the writes of every replica to the bus. It uses a change stream when it can and falls back
+// to polling updated_at on standalone servers or when the stream breaks.
+func watchLocations(cfg *config.Config, locations locationsRepository.Repository, projects projectsRepository.Repository, cache sources.Cache, bus events.Bus) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		since := time.Now()
+
+		publish := func(location *locationsRepository.LocationDB) {
+			if location.UpdatedAt.After(since) {
+				since = location.UpdatedAt
+			}
+
+			projectID := location.ProjectID
+			if len(projectID) == 0 {
+				projectID = projectsRepository.DefaultID
+			}
+
+			project, err := loadProject(ctx, projects, cache, projectID)
+			if err != nil {
+				logrus.Errorf("couldn't load project %s of entry %d: %s", projectID, location.EntryID, err)
+
+				return
+			}
+
+			bus.Publish(locationEvent(project, location))
+		}
+
+		if cfg.Events.ChangeStreams {
+			err := locations.Watch(ctx, publish)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logrus.Warnf("change stream on locations stopped, polling every %s: %s", cfg.Events.PollInterval, err)
+		}
+
+		ticker := time.NewTicker(cfg.Events.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			updated, err := locations.GetUpdatedSince(ctx, since)
+			if err != nil {
+				logrus.Errorf("couldn't poll locations: %s", err)
+
+				continue
+			}
+
+			for _, location := range updated {
+				publish(location)
+			}
+		}
+	}
+}
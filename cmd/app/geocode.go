@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/geocode"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Geocoding interface {
+	Forward(c *fiber.Ctx) error
+	Reverse(c *fiber.Ctx) error
+	// Locate returns the best match of a typed address if it is good enough to be used without a URL.
+	Locate(text string) *geocode.Match
+}
+
+type geocoding struct {
+	cfg       config.Geocoding
+	gazetteer geocode.Gazetteer
+}
+
+// NewGeocoding works without a gazetteer, the endpoints answer 503 and Locate finds nothing.
+func NewGeocoding(cfg config.Geocoding, gazetteer geocode.Gazetteer) Geocoding {
+	return &geocoding{
+		cfg:       cfg,
+		gazetteer: gazetteer,
+	}
+}
+
+// Forward geocodes ?q= and returns at most ?limit= matches, best first.
+func (g *geocoding) Forward(c *fiber.Ctx) error {
+	if g.gazetteer == nil {
+		return c.Status(fiber.StatusServiceUnavailable).SendString("Geocoding is not configured.")
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) == 0 {
+		errs := make(validation.Errors, 0)
+		errs.Add("q", "is required")
+
+		return sendValidationErrors(c, errs)
+	}
+
+	limit := c.QueryInt("limit", g.cfg.MaxResults)
+	if limit < 1 || limit > g.cfg.MaxResults {
+		limit = g.cfg.MaxResults
+	}
+
+	return c.JSON(g.gazetteer.Forward(query, limit))
+}
+
+// Reverse returns the nearest mahalle of ?lat=&lng=, 404 when there is none within max_distance_km.
+func (g *geocoding) Reverse(c *fiber.Ctx) error {
+	if g.gazetteer == nil {
+		return c.Status(fiber.StatusServiceUnavailable).SendString("Geocoding is not configured.")
+	}
+
+	errs := make(validation.Errors, 0)
+
+	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+	if err != nil {
+		errs.Add("lat", "invalid latitude %q", c.Query("lat"))
+	}
+	lng, err := strconv.ParseFloat(c.Query("lng"), 64)
+	if err != nil {
+		errs.Add("lng", "invalid longitude %q", c.Query("lng"))
+	}
+	if len(errs) == 0 {
+		// Sözlük sadece Türkiye'yi kapsıyor
+		validation.Coordinates(&errs, "location", []float64{lat, lng}, validation.Area{validation.Turkey})
+	}
+	if len(errs) > 0 {
+		return sendValidationErrors(c, errs)
+	}
+
+	match := g.gazetteer.Reverse(lat, lng, g.cfg.MaxDistanceKm)
+	if match == nil {
+		return c.Status(fiber.StatusNotFound).SendString("No mahalle found nearby.")
+	}
+
+	return c.JSON(match)
+}
+
+func (g *geocoding) Locate(text string) *geocode.Match {
+	if g.gazetteer == nil || len(strings.TrimSpace(text)) == 0 {
+		return nil
+	}
+
+	matches := g.gazetteer.Forward(text, 1)
+	if len(matches) == 0 || matches[0].Score < g.cfg.MinScore {
+		return nil
+	}
+
+	return matches[0]
+}
+
+// addressText joins the non-empty parts of a typed address for the gazetteer, empty when nothing was typed.
+func addressText(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); len(part) > 0 {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+
+	return strings.Join(nonEmpty, ", ")
+}
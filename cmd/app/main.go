@@ -3,138 +3,366 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"math/rand"
-	"os"
-	"os/signal"
-	"regexp"
-	"strconv"
+	"errors"
 	"strings"
-	"syscall"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
-	"github.com/Netflix/go-env"
+	"github.com/YusufOzmen01/veri-kontrol-backend/address"
+	"github.com/YusufOzmen01/veri-kontrol-backend/coords"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/events"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/lifecycle"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/metrics"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/ratelimit"
 	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/tracing"
+	"github.com/YusufOzmen01/veri-kontrol-backend/discord"
+	"github.com/YusufOzmen01/veri-kontrol-backend/extract"
+	"github.com/YusufOzmen01/veri-kontrol-backend/geocode"
 	"github.com/YusufOzmen01/veri-kontrol-backend/handler"
+	"github.com/YusufOzmen01/veri-kontrol-backend/migrations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+	"github.com/YusufOzmen01/veri-kontrol-backend/queue"
+	"github.com/YusufOzmen01/veri-kontrol-backend/reconcile"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/audit"
+	categoriesRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/categories"
 	locationsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/notifications"
+	projectsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	queueRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/queue"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/ratelimits"
+	skipsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/skips"
 	usersRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/users"
+	webhooksRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/webhooks"
+	"github.com/YusufOzmen01/veri-kontrol-backend/retention"
 	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
 	"github.com/YusufOzmen01/veri-kontrol-backend/util"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/YusufOzmen01/veri-kontrol-backend/webhooks"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/monitor"
 	"github.com/sirupsen/logrus"
 )
 
-type Environment struct {
-	MongoUri string `env:"mongo_uri"`
+type ResolveBody struct {
+	ID int `json:"id"`
+	// Category is an id from /categories, LocationType is the legacy integer type and only used without a category.
+	Category     string `json:"category"`
+	LocationType int    `json:"type"`
+	NewAddress   string `json:"new_address"`
+	// Lat and Lng can be given instead of NewAddress, which also takes a plus code or any supported map URL.
+	Lat         *float64 `json:"lat,omitempty"`
+	Lng         *float64 `json:"lng,omitempty"`
+	OpenAddress string   `json:"open_address"`
+	Apartment   string   `json:"apartment"`
+	Reason      string   `json:"reason"`
 }
 
-var cities = map[int][]float64{
-	1:  {36.852702785393014, 36.87286376953126, 36.535570922786015, 35.88409423828126},
-	2:  {36.2104851748389, 36.81861877441407, 35.84286468375614, 35.82984924316407},
-	3:  {36.495937096205274, 36.649870522206335, 36.064120488812605, 35.4740187605459},
-	4:  {36.50903585150776, 36.402143998719424, 36.47976138594277, 36.31474829364722},
-	5:  {36.64234742932176, 36.3232450328562, 36.53629731173617, 36.029282092441115},
-	6:  {36.116001873480265, 36.06470054394251, 36.0627178139989, 35.91771907373497},
-	7:  {38.53348725642158, 38.78062516773912, 37.32756763881127, 35.45481415037825},
-	8:  {37.35461473302187, 38.0755896764663, 36.85431769725969, 36.67725839531126},
-	9:  {39.065058845523424, 40.013647871307754, 37.86798402826048, 36.687836853946884},
-	10: {38.160827052916495, 39.33362355320935, 37.44250898099215, 37.35608449070936},
+type SkipBody struct {
+	ID     int    `json:"id"`
+	Reason string `json:"reason"`
+	Note   string `json:"note"`
 }
 
-type ResolveBody struct {
-	ID            int    `json:"id"`
-	LocationType  int    `json:"type"`
-	NewAddress    string `json:"new_address"`
-	OpenAddress   string `json:"open_address"`
-	Apartment     string `json:"apartment"`
-	Reason        string `json:"reason"`
-	TweetContents string `json:"tweet_contents"`
+func sendValidationErrors(c *fiber.Ctx, err error) error {
+	errs, ok := err.(validation.Errors)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusBadRequest).JSON(struct {
+		Errors validation.Errors `json:"errors"`
+	}{
+		Errors: errs,
+	})
+}
+
+// correctedLocation reads the location given with new_address or lat and lng, without them it geocodes the typed
+// address. It returns nil when the body has neither or confirms the feed location, validation errors are
+// validation.Errors.
+func correctedLocation(normalizer coords.Normalizer, geocoding Geocoding, body *ResolveBody, original []float64) ([]float64, *coords.Provenance, *geocode.Match, error) {
+	if strings.ToLower(body.Reason) == "hata yok" {
+		return nil, nil, nil, nil
+	}
+
+	input := &coords.Input{Lat: body.Lat, Lng: body.Lng, Text: body.NewAddress}
+	if !input.Empty() {
+		result, err := normalizer.Normalize(input, original)
+		if err != nil {
+			var inputErr *coords.InputError
+			if errors.As(err, &inputErr) {
+				errs := make(validation.Errors, 0)
+				errs.Add(inputErr.Field, "%s", inputErr.Message)
+
+				return nil, nil, nil, errs
+			}
+
+			return nil, nil, nil, err
+		}
+
+		return result.Location, result.Provenance, nil, nil
+	}
+
+	// URL verilmediyse yazılan adresi sözlükten bulmaya çalışıyoruz, adres de yoksa orijinal konum kalır
+	text := addressText(body.OpenAddress, body.Apartment)
+	if len(text) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	geocoded := geocoding.Locate(text)
+	if geocoded == nil {
+		errs := make(validation.Errors, 0)
+		errs.Add("new_address", "is required when the address can't be geocoded")
+
+		return nil, nil, nil, errs
+	}
+
+	return []float64{geocoded.Lat, geocoded.Lng}, &coords.Provenance{Kind: coords.KindGeocode, Input: text}, geocoded, nil
 }
 
 func main() {
-	app := fiber.New()
-	ctx := context.Background()
-	cache := sources.NewCache(1<<30, 1e7, 64)
+	cfg := config.MustLoad()
+	logging.Setup(cfg.Log)
+	logrus.Infof("Effective config:\n%s", cfg.Redacted())
 
-	rand.Seed(time.Now().UnixMilli())
+	lc := lifecycle.New(cfg.HTTP.ShutdownTimeout)
+	ctx := lc.Context()
 
-	var environment Environment
-	if _, err := env.UnmarshalFromEnviron(&environment); err != nil {
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
 		panic(err)
 	}
 
-	mongoClient := sources.NewMongoClient(ctx, environment.MongoUri, "database")
+	// İlk kaydedilen en son kapatılır, diğer bileşenlerin span'leri de gönderilebilsin
+	lc.Register(&lifecycle.Hook{
+		Name: "tracing",
+		Stop: shutdownTracing,
+	})
+
+	app := fiber.New()
+	cache := sources.NewCache(cfg.Cache.MaxCost, cfg.Cache.NumCounters, cfg.Cache.BufferItems)
+	feeds := tools.NewFeeds(cfg.Feed, cache)
+	feed := feeds.For(defaultProject(cfg))
+
+	protector, err := pii.NewProtector(cfg.PII.EncryptionKey)
+	if err != nil {
+		logrus.Fatalf("Invalid pii.encryption_key: %s", err)
+	}
+
+	mongoClient := metrics.InstrumentMongo(sources.NewMongoClient(ctx, cfg.Mongo.URI, cfg.Mongo.Database, cfg.Mongo.MaxPoolSize))
+	metrics.RegisterCache(cache)
+
+	lc.Register(&lifecycle.Hook{
+		Name: "mongo",
+		Stop: mongoClient.Disconnect,
+	})
+
+	if cfg.Mongo.MigrateOnStartup {
+		if err := migrations.Run(ctx, mongoClient); err != nil {
+			// Eksik migration'larla proje sorguları eski kayıtları göremez
+			logrus.Fatalf("Couldn't apply migrations: %s", err)
+		}
+	}
+
+	projectRepository := projectsRepository.NewRepository(mongoClient)
+	categoryRepository := categoriesRepository.NewRepository(mongoClient)
+	if err := projectRepository.EnsureProject(ctx, defaultProject(cfg)); err != nil {
+		logrus.Fatalf("Couldn't save the default project: %s", err)
+	}
+
 	locationRepository := locationsRepository.NewRepository(mongoClient)
 	userRepository := usersRepository.NewRepository(mongoClient)
+	rateLimitRepository := ratelimits.NewRepository(mongoClient)
 
-	admin := NewAdmin(locationRepository, cache)
+	skipRepository := skipsRepository.NewRepository(mongoClient)
 
-	processedIDs := make([]int, 0)
+	queueRepo := queueRepository.NewRepository(mongoClient)
+	scheduler := queue.NewScheduler(cfg, queueRepo)
 
-	logrus.Infoln("Pulling entries")
-	locs, err := locationRepository.GetLocations(ctx)
-	if err != nil {
-		logrus.Errorf("Couldn't get all locations: %s", err)
-	}
+	auditRepository := audit.NewRepository(mongoClient)
+	claimRepository := notifications.NewRepository(mongoClient)
+
+	retentionService := retention.NewService(cfg.Retention, locationRepository, userRepository, auditRepository, claimRepository, protector)
+	privacy := NewPrivacy(retentionService)
 
-	for _, loc := range locs {
-		processedIDs = append(processedIDs, loc.EntryID)
+	reconcileService := reconcile.NewService(cfg.Reconcile, projectRepository, locationRepository, feeds, claimRepository)
+	reconciliation := NewReconciliation(reconcileService)
+
+	var gazetteer geocode.Gazetteer
+	if len(cfg.Geocoding.Gazetteer) > 0 {
+		gazetteer, err = geocode.Load(cfg.Geocoding.Gazetteer)
+		if err != nil {
+			logrus.Fatalf("Couldn't load the gazetteer: %s", err)
+		}
+
+		logrus.Infof("Loaded %d gazetteer places", gazetteer.Len())
+	}
+	geocoding := NewGeocoding(cfg.Geocoding, gazetteer)
+	normalizer := coords.NewNormalizer(util.GatherLongUrlFromShortUrl)
+
+	categoryRegistry := NewCategoryRegistry(categoryRepository, cache)
+	admin := NewAdmin(locationRepository, rateLimitRepository, skipRepository, auditRepository, scheduler, feeds, categoryRegistry, protector, normalizer, geocoding)
+	categoryAdmin := NewCategories(categoryRepository, categoryRegistry)
+	projectAdmin := NewProjects(projectRepository, userRepository, categoryRegistry, cache)
+
+	var rateLimitStore ratelimit.Store
+	if cfg.RateLimit.Store == "mongo" {
+		rateLimitStore = ratelimit.NewMongoStore(mongoClient)
+	} else {
+		rateLimitStore = ratelimit.NewMemoryStore()
 	}
 
+	webhookRepository := webhooksRepository.NewRepository(mongoClient)
+	webhookAdmin := NewWebhooks(webhookRepository, categoryRegistry)
+
+	bus := events.NewBus(cfg.Events.BufferSize)
+	eventStream := handler.NewEvents(bus, cfg.Events.Heartbeat, func(c *fiber.Ctx) string { return currentProject(c).ID })
+
+	limiter := ratelimit.NewLimiter(cfg.RateLimit, rateLimitStore, rateLimitIdentity(userRepository), recordRateLimitHit(rateLimitRepository))
+
+	critical := cfg.Health.Critical
+	health := handler.NewHealth(cfg.Health.Timeout,
+		&handler.Check{Name: "mongo", Critical: critical.Contains("mongo"), Check: handler.MongoCheck(mongoClient)},
+		&handler.Check{Name: "feed", Critical: critical.Contains("feed"), Check: handler.FeedCheck(feed, cfg.Health.FeedMaxAge)},
+		&handler.Check{Name: "cache", Critical: critical.Contains("cache"), Check: handler.CacheCheck(cache)},
+	)
+
 	logrus.Infoln("Startup complete")
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 	}))
+	app.Use(tracing.Middleware)
+	app.Use(logging.Middleware)
+	app.Use(metrics.Middleware)
 	app.Get("/healthcheck", handler.Healtcheck)
+	app.Get("/livez", health.Livez)
+	app.Get("/readyz", health.Readyz)
+	app.Get("/metrics", metrics.Handler)
 
-	adminG := app.Group("/admin", func(c *fiber.Ctx) error {
-		authKey := c.Get("Auth-Key")
+	withProject := projectMiddleware(projectRepository, cache)
 
-		user, err := userRepository.GetUser(ctx, authKey)
-		if err != nil {
-			return c.Status(401).SendString("User not found.")
-		}
+	// Proje bazlı admin route'ları hem /admin altında varsayılan proje için hem de /projects/:project_id/admin altında var
+	projectAdminRoutes := func(router fiber.Router) {
+		entriesG := router.Group("/entries")
 
-		if user.PermLevel < usersRepository.PermModerator {
-			return c.Status(401).SendString("You are not allowed to access here.")
-		}
+		entriesG.Get("", admin.GetLocationEntries)
+		entriesG.Get("/:entry_id", admin.GetSingleEntry)
+		entriesG.Post("/:entry_id", admin.UpdateEntry)
+		entriesG.Get("/:entry_id/tweet-contents", admin.GetTweetContents)
 
-		return c.Next()
-	})
+		router.Get("/stats", admin.GetStats)
+		router.Get("/queue/preview", admin.PreviewQueue)
+		router.Get("/reconcile", reconciliation.GetReport)
+		router.Get("/events", eventStream.Stream)
+	}
+
+	adminG := app.Group("/admin", withProject, adminMiddleware(userRepository))
 
-	entriesG := adminG.Group("/entries")
+	projectAdminRoutes(adminG)
 
-	entriesG.Get("", admin.GetLocationEntries)
-	entriesG.Get("/:entry_id", admin.GetSingleEntry)
-	entriesG.Post("/:entry_id", admin.UpdateEntry)
+	// Aşağıdakiler bütün projelerin ya da global verinin üzerinde çalışıyor, projedeki rol yetmez
+	adminG.Get("/rate-limits", globalModerator, admin.GetRateLimitHits)
+	adminG.Get("/audit", globalModerator, admin.GetAuditLog)
+	adminG.Post("/retention/purge", globalModerator, privacy.Purge)
+
+	subjectsG := adminG.Group("/subjects", globalModerator)
+
+	subjectsG.Get("", privacy.LookupSubject)
+	subjectsG.Get("/export", privacy.ExportSubject)
+	subjectsG.Post("/erase", privacy.EraseSubject)
+
+	queueG := adminG.Group("/queue/weights", globalModerator)
+
+	queueG.Get("", admin.GetQueueWeights)
+	queueG.Put("", admin.SetQueueWeights)
+
+	webhooksG := adminG.Group("/webhooks", globalModerator)
+
+	webhooksG.Get("", webhookAdmin.GetEndpoints)
+	webhooksG.Post("", webhookAdmin.CreateEndpoint)
+	webhooksG.Delete("/:id", webhookAdmin.DeactivateEndpoint)
+	webhooksG.Get("/:id/deliveries", webhookAdmin.GetDeliveries)
+	webhooksG.Post("/:id/replay", webhookAdmin.ReplayFailed)
+	webhooksG.Post("/deliveries/:delivery_id/replay", webhookAdmin.ReplayDelivery)
+
+	projectsG := adminG.Group("/projects", globalModerator)
+
+	projectsG.Get("", projectAdmin.GetProjects)
+	projectsG.Post("", projectAdmin.CreateProject)
+	projectsG.Get("/:project_id", projectAdmin.GetProject)
+	projectsG.Post("/:project_id/archive", projectAdmin.ArchiveProject)
+	projectsG.Put("/:project_id/members/:user_id", projectAdmin.SetMember)
+
+	categoriesG := adminG.Group("/categories", globalModerator)
+
+	categoriesG.Get("", categoryAdmin.ListCategories)
+	categoriesG.Post("", categoryAdmin.CreateCategory)
+	categoriesG.Put("/:category_id", categoryAdmin.UpdateCategory)
+	categoriesG.Post("/:category_id/archive", categoryAdmin.ArchiveCategory)
 
 	app.Get("/monitor", monitor.New())
 
-	app.Get("/get-location", func(c *fiber.Ctx) error {
-		locations, err := tools.GetAllLocations(ctx, cache)
+	if cfg.Discord.Enabled {
+		discordClient := discord.NewClient()
+		if cfg.Discord.Mock {
+			discordClient = discord.NewMockClient()
+		}
+
+		notifier := discord.NewNotifier(cfg, discordClient, locationRepository, feed, claimRepository)
+		lc.Go("discord", notifier.Run)
+
+		app.Post("/discord/interactions", discord.NewCommands(cfg.Discord, userRepository).Interactions)
+	}
+
+	app.Get("/geocode", limiter.Middleware("geocode"), geocoding.Forward)
+	app.Get("/reverse-geocode", limiter.Middleware("geocode"), geocoding.Reverse)
+
+	getLocation := func(c *fiber.Ctx) error {
+		project := currentProject(c)
+		if project.Archived() {
+			return c.Status(fiber.StatusGone).SendString("Project is archived.")
+		}
+
+		feed := feeds.For(project)
+		locationRepository := locationRepository.WithProject(project.ID)
+		skipRepository := skipRepository.WithProject(project.ID)
+		scheduler := scheduler.WithProject(project)
+
+		locations, err := feed.GetAllLocations(c.UserContext())
 		if err != nil {
-			logrus.Errorln(err)
+			logging.For(c).Errorln(err)
 
 			return c.SendString(err.Error())
 		}
 
-		for _, id := range processedIDs {
-			for i, loc := range locations {
-				if id == loc.EntryID {
-					locations = append(locations[:i], locations[i+1:]...)
+		resolved, err := locationRepository.GetResolvedIDs(c.UserContext())
+		if err != nil {
+			logging.For(c).Errorln(err)
 
-					continue
-				}
+			return c.SendString(err.Error())
+		}
+
+		unresolved := make([]*locationsRepository.Location, 0, len(locations))
+		for _, loc := range locations {
+			if !resolved[loc.EntryID] {
+				unresolved = append(unresolved, loc)
 			}
 		}
+		locations = unresolved
 
 		cityID := c.QueryInt("city_id")
 		if cityID > 0 {
-			box := cities[cityID]
+			box := project.Region(cityID)
+			if box == nil {
+				errs := make(validation.Errors, 0)
+				errs.Add("city_id", "unknown region %d", cityID)
+
+				return sendValidationErrors(c, errs)
+			}
 
 			filteredLocations := make([]*locationsRepository.Location, 0)
 
@@ -160,6 +388,53 @@ func main() {
 			locations = filteredLocations
 		}
 
+		// Çok atlanan girdiler sadece moderatörlere, atlanan girdiler de atlayan kişiye bir daha gösterilmez
+		expertPool := c.Query("pool") == "expert"
+
+		holder := "ip:" + clientIP(c)
+		skipped := make(map[int]bool)
+		var user *usersRepository.User
+		if authKey := c.Get("Auth-Key"); len(authKey) > 0 {
+			user, err = userRepository.GetUser(c.UserContext(), authKey)
+			if err != nil {
+				return c.Status(401).SendString("User not found.")
+			}
+
+			logging.SetUser(c, user.ID.Hex())
+			holder = "user:" + user.ID.Hex()
+
+			if skipped, err = skipRepository.GetSkippedByUser(c.UserContext(), user.ID.Hex()); err != nil {
+				logging.For(c).Errorln(err)
+
+				return c.SendString(err.Error())
+			}
+
+			if expertPool && user.PermIn(project.ID) < usersRepository.PermModerator {
+				return c.Status(403).SendString("The expert pool is only for moderators.")
+			}
+		} else if expertPool {
+			return c.Status(401).SendString("User not found.")
+		}
+
+		skipCounts, err := skipRepository.GetSkipCounts(c.UserContext())
+		if err != nil {
+			logging.For(c).Errorln(err)
+
+			return c.SendString(err.Error())
+		}
+
+		filteredLocations := make([]*locationsRepository.Location, 0, len(locations))
+		for _, loc := range locations {
+			if skipped[loc.EntryID] || (skipCounts[loc.EntryID] >= cfg.Skips.ExpertThreshold) != expertPool {
+				continue
+			}
+
+			filteredLocations = append(filteredLocations, loc)
+		}
+		locations = filteredLocations
+
+		metrics.QueueBacklog.Set(float64(len(locations)))
+
 		if len(locations) == 0 {
 			return c.JSON(struct {
 				Count    int                           `json:"count"`
@@ -170,148 +445,201 @@ func main() {
 			})
 		}
 
-		var selected *locationsRepository.Location
+		candidates, err := scheduler.Rank(c.UserContext(), locations, skipCounts)
+		if err != nil {
+			logging.For(c).Errorln(err)
+
+			return c.SendString(err.Error())
+		}
+
 		fullText := ""
-		processed := make([]int, 0)
-
-		for {
-			randIndex := rand.Intn(len(locations))
-
-			if len(processed) == len(locations) {
-				return c.JSON(struct {
-					Count    int                           `json:"count"`
-					Location *locationsRepository.Location `json:"location"`
-				}{
-					Count:    0,
-					Location: nil,
-				})
+
+		// Aynı tweet daha önce çözülmüşse girdi atlanır
+		next, leaseUntil, err := scheduler.Next(c.UserContext(), candidates, holder, func(loc *locationsRepository.Location) (bool, error) {
+			singleData, err := feed.GetSingleLocation(c.UserContext(), loc.EntryID)
+			if err != nil {
+				return false, err
 			}
 
-			for _, i := range processed {
-				if randIndex == i {
-					continue
-				}
+			if err := scheduler.ObserveText(c.UserContext(), loc.EntryID, singleData.FullText); err != nil {
+				logging.For(c).Errorln(err)
 			}
 
-			processed = append(processed, randIndex)
+			exists, err := locationRepository.IsDuplicate(c.UserContext(), protector.Hash(singleData.FullText), singleData.FullText)
+			if err != nil || exists {
+				return false, err
+			}
 
-			s := locations[randIndex]
+			fullText = singleData.FullText
 
-			singleData, err := tools.GetSingleLocation(ctx, s.EntryID, cache)
-			if err != nil {
-				logrus.Errorln(err)
+			return true, nil
+		})
+		if err != nil {
+			logging.For(c).Errorln(err)
 
-				return c.SendString(err.Error())
-			}
+			return c.SendString(err.Error())
+		}
 
-			exists, err := locationRepository.IsDuplicate(c.Context(), singleData.FullText)
-			if err != nil {
-				logrus.Errorln(err)
+		if next == nil {
+			return c.JSON(struct {
+				Count    int                           `json:"count"`
+				Location *locationsRepository.Location `json:"location"`
+			}{
+				Count:    0,
+				Location: nil,
+			})
+		}
 
-				return c.SendString(err.Error())
-			}
+		selected := next.Location
 
-			if !exists {
-				selected = s
-				fullText = singleData.FullText
+		logging.SetEntry(c, selected.EntryID)
 
-				break
+		// Tam metin sadece moderatörlere, her okuma audit log'a yazılır
+		signals := extract.Extract(fullText)
+		if user != nil && user.PermIn(project.ID) >= usersRepository.PermModerator {
+			if err := recordRead(c, auditRepository, user, audit.ActionReadOriginalMessage, project.ID, selected.EntryID); err != nil {
+				logging.For(c).Errorln(err)
+
+				return c.Status(500).SendString(err.Error())
 			}
-		}
 
-		selected.OriginalMessage = fullText
-		selected.OriginalLocation = fmt.Sprintf("https://www.google.com/maps/?q=%f,%f&ll=%f,%f&z=21", selected.Loc[0], selected.Loc[1], selected.Loc[0], selected.Loc[1])
+			selected.OriginalMessage = fullText
+		} else {
+			selected.OriginalMessage = pii.Redact(fullText)
+			signals = signals.Masked()
+		}
+		selected.OriginalLocation = util.MapLink(selected.Loc)
 
 		return c.JSON(struct {
-			Count    int                           `json:"count"`
-			Location *locationsRepository.Location `json:"location"`
+			Count          int                           `json:"count"`
+			Location       *locationsRepository.Location `json:"location"`
+			Signals        *extract.Signals              `json:"signals"`
+			Score          float64                       `json:"score"`
+			LeaseExpiresAt time.Time                     `json:"lease_expires_at"`
 		}{
-			Count:    len(locations),
-			Location: selected,
+			Count:          len(locations),
+			Location:       selected,
+			Signals:        signals,
+			Score:          next.Score,
+			LeaseExpiresAt: leaseUntil,
 		})
-	})
+	}
+
+	resolve := func(c *fiber.Ctx) error {
+		project := currentProject(c)
+		feed := feeds.For(project)
+		locationRepository := locationRepository.WithProject(project.ID)
+		scheduler := scheduler.WithProject(project)
 
-	app.Post("/resolve", func(c *fiber.Ctx) error {
 		body := &ResolveBody{}
 
 		if err := json.Unmarshal(c.Body(), body); err != nil {
-			logrus.Errorln(err)
+			logging.For(c).Errorln(err)
 
 			return c.SendString(err.Error())
 		}
 
-		match, err := regexp.MatchString("^https://www.google.com/maps/.*", body.NewAddress)
+		logging.SetEntry(c, body.ID)
+
+		resolved, err := locationRepository.IsResolved(c.UserContext(), body.ID)
 		if err != nil {
-			logrus.Error(err)
-			return err
-		}
-		if !match {
-			return c.SendString("Invalid Google Maps URL!")
-		}
+			logging.For(c).Errorln(err)
 
-		for _, id := range processedIDs {
-			if body.ID == id {
-				return c.SendString("this location is already checked")
-			}
+			return c.SendString(err.Error())
+		}
+		if resolved {
+			return c.SendString("this location is already checked")
 		}
 
-		locations, err := tools.GetAllLocations(ctx, cache)
+		locations, err := feed.GetAllLocations(c.UserContext())
 		if err != nil {
-			logrus.Errorln(err)
+			logging.For(c).Errorln(err)
 
 			return c.SendString(err.Error())
 		}
 
 		originalLocation := ""
-		location := make([]float64, 2)
+		original := make([]float64, 0)
 
 		for _, loc := range locations {
 			if loc.EntryID == body.ID {
-				originalLocation = fmt.Sprintf("https://www.google.com/maps/?q=%f,%f&ll=%f,%f&z=21", loc.Loc[0], loc.Loc[1], loc.Loc[0], loc.Loc[1])
-				location = loc.Loc
+				originalLocation = util.MapLink(loc.Loc)
+				original = loc.Loc
 			}
 		}
 
+		if len(original) != 2 {
+			errs := make(validation.Errors, 0)
+			errs.Add("id", "entry %d not found", body.ID)
+
+			return sendValidationErrors(c, errs)
+		}
+
+		// Gönüllüye maskeli metin gidiyor, şifrelenecek ve hash'lenecek ham metni kaynaktan alıyoruz
+		single, err := feed.GetSingleLocation(c.UserContext(), body.ID)
+		if err != nil {
+			logging.For(c).Errorln(err)
+
+			return c.SendString(err.Error())
+		}
+		tweetContents := single.FullText
+
+		// Cache'deki konumu değiştirmemek için kopyalıyoruz
+		location := []float64{original[0], original[1]}
+
 		var sender *usersRepository.User
 
 		authKey := c.Get("Auth-Key")
-		userData, err := userRepository.GetUser(c.Context(), authKey)
+		userData, err := userRepository.GetUser(c.UserContext(), authKey)
 		if err == nil {
 			sender = userData
+			logging.SetUser(c, userData.ID.Hex())
 		}
 
-		if strings.ToLower(body.Reason) != "hata yok" && len(body.NewAddress) > 0 {
-			longUrl, err := util.GatherLongUrlFromShortUrl(body.NewAddress)
-			if err != nil {
-				logrus.Errorln(err)
-				return c.SendString(err.Error())
-			}
-			locUrl := util.URLtoLatLng(longUrl)
-			if locUrl == nil {
-				return c.SendString("Not a valid url")
+		corrected, provenance, geocoded, err := correctedLocation(normalizer, geocoding, body, original)
+		if err != nil {
+			if errs, ok := err.(validation.Errors); ok {
+				return sendValidationErrors(c, errs)
 			}
 
-			latVal, err := strconv.ParseFloat(locUrl["lat"], 64)
-			if err != nil {
-				logrus.Error(err)
+			logging.For(c).Errorln(err)
 
-				c.SendString(err.Error())
+			return c.SendString(err.Error())
+		}
+		if corrected != nil {
+			location = corrected
+		}
 
-			}
-			lngVal, err := strconv.ParseFloat(locUrl["lng"], 64)
-			if err != nil {
-				logrus.Error(err)
+		registry, err := categoryRegistry.Load(c.UserContext())
+		if err != nil {
+			logging.For(c).Errorln(err)
 
-				c.SendString(err.Error())
-			}
-			location[0] = latVal
-			location[1] = lngVal
+			return c.SendString(err.Error())
+		}
+
+		category, err := resolveCategory(registry, project, body.Category, body.LocationType)
+		if err != nil {
+			return sendValidationErrors(c, err)
+		}
+
+		if err := validation.Resolve(&validation.Resolution{
+			Category:       category.ID,
+			RequiredFields: registry.RequiredFields(category),
+			Area:           project.Area(),
+			Location:       location,
+			Original:       original,
+			OpenAddress:    body.OpenAddress,
+			Apartment:      body.Apartment,
+			TweetContents:  tweetContents,
+		}); err != nil {
+			return sendValidationErrors(c, err)
 		}
 
-		if err := locationRepository.ResolveLocation(ctx, &locationsRepository.LocationDB{
+		resolution := &locationsRepository.LocationDB{
 			ID:               primitive.NewObjectIDFromTimestamp(time.Now()),
 			EntryID:          body.ID,
-			Type:             body.LocationType,
+			Type:             registry.LegacyType(category),
+			Category:         category.ID,
 			Location:         location,
 			Corrected:        body.Reason == "Hata Yok",
 			OriginalAddress:  originalLocation,
@@ -320,29 +648,159 @@ func main() {
 			Sender:           sender,
 			OpenAddress:      body.OpenAddress,
 			Apartment:        body.Apartment,
-			TweetContents:    body.TweetContents,
-		}); err != nil {
-			logrus.Errorln(err)
+			ParsedAddress:    address.ParseFields(body.OpenAddress, body.Apartment),
+			Geocode:          geocoded,
+			Provenance:       provenance,
+			Source:           locationsRepository.SourceResolve,
+		}
+
+		if len(tweetContents) > 0 {
+			protected, err := protector.Protect(tweetContents)
+			if err != nil {
+				logging.For(c).Errorln(err)
+
+				return c.Status(500).SendString(err.Error())
+			}
+
+			resolution.TweetContents = protected.Redacted
+			resolution.TweetContentsEncrypted = protected.Encrypted
+			resolution.TweetContentsHash = protected.Hash
+			resolution.Signals = extract.Extract(tweetContents)
+		}
+
+		if err := locationRepository.ResolveLocation(c.UserContext(), resolution); err != nil {
+			logging.For(c).Errorln(err)
 
 			return c.SendString(err.Error())
 		}
 
-		processedIDs = append(processedIDs, body.ID)
+		if err := scheduler.Release(c.UserContext(), body.ID); err != nil {
+			logging.For(c).Errorln(err)
+		}
+
+		metrics.ObserveResolution(category.ID, body.Reason)
 
 		return c.SendString("Successfully added!")
+	}
+
+	skip := func(c *fiber.Ctx) error {
+		project := currentProject(c)
+		locationRepository := locationRepository.WithProject(project.ID)
+		skipRepository := skipRepository.WithProject(project.ID)
+		scheduler := scheduler.WithProject(project)
+
+		body := &SkipBody{}
+
+		if err := json.Unmarshal(c.Body(), body); err != nil {
+			return c.Status(400).SendString(err.Error())
+		}
+
+		logging.SetEntry(c, body.ID)
+
+		user, err := userRepository.GetUser(c.UserContext(), c.Get("Auth-Key"))
+		if err != nil {
+			return c.Status(401).SendString("User not found.")
+		}
+
+		logging.SetUser(c, user.ID.Hex())
+
+		errs := make(validation.Errors, 0)
+		if !skipsRepository.IsReason(body.Reason) {
+			errs.Add("reason", "must be one of %v", skipsRepository.Reasons)
+		}
+		validation.Length(&errs, "note", body.Note, validation.MaxNoteLength)
+		if len(errs) > 0 {
+			return sendValidationErrors(c, errs)
+		}
+
+		resolved, err := locationRepository.IsResolved(c.UserContext(), body.ID)
+		if err != nil {
+			logging.For(c).Errorln(err)
+
+			return c.SendString(err.Error())
+		}
+		if resolved {
+			return c.SendString("this location is already checked")
+		}
+
+		if err := skipRepository.AddSkip(c.UserContext(), &skipsRepository.Skip{
+			EntryID: body.ID,
+			UserID:  user.ID.Hex(),
+			Reason:  body.Reason,
+			Note:    body.Note,
+		}); err != nil {
+			logging.For(c).Errorln(err)
+
+			return c.SendString(err.Error())
+		}
+
+		if err := scheduler.Release(c.UserContext(), body.ID); err != nil {
+			logging.For(c).Errorln(err)
+		}
+
+		metrics.SkipsTotal.WithLabelValues(body.Reason).Inc()
+
+		return c.SendString("Skipped.")
+	}
+
+	publicRoutes := func(router fiber.Router, middleware ...fiber.Handler) {
+		handlers := func(route ...fiber.Handler) []fiber.Handler {
+			return append(append([]fiber.Handler{}, middleware...), route...)
+		}
+
+		router.Get("/get-location", handlers(limiter.Middleware("get-location"), getLocation)...)
+		router.Post("/resolve", handlers(limiter.Middleware("resolve"), resolve)...)
+		router.Post("/skip", handlers(limiter.Middleware("skip"), skip)...)
+		router.Get("/categories", handlers(categoryAdmin.GetCategories)...)
+	}
+
+	// Proje verilmeyen eski route'lar varsayılan projeye gider
+	publicRoutes(app, withProject)
+
+	projectG := app.Group("/projects/:project_id", withProject)
+
+	publicRoutes(projectG)
+	projectAdminRoutes(projectG.Group("/admin", adminMiddleware(userRepository)))
+
+	// Kapanırken önce SSE akışları kesilir, istekler bitirilir, sonra root context iptal edilip worker'lar beklenir, en son Mongo kapatılır
+	lc.Register(lc.WorkersHook())
+	lc.Register(&lifecycle.Hook{
+		Name: "http",
+		Stop: func(ctx context.Context) error {
+			timeout := cfg.HTTP.ShutdownTimeout
+			if deadline, ok := ctx.Deadline(); ok {
+				timeout = time.Until(deadline)
+			}
+
+			return app.ShutdownWithTimeout(timeout)
+		},
 	})
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, syscall.SIGINT)
-	signal.Notify(c, syscall.SIGTERM)
+	lc.Register(&lifecycle.Hook{
+		Name: "events",
+		Stop: eventStream.Stop,
+	})
 
-	go func() {
-		_ = <-c
-		fmt.Println("application gracefully shutting down..")
-		_ = app.Shutdown()
-	}()
+	lc.Go("events", watchLocations(cfg, locationRepository, projectRepository, cache, bus))
+	lc.Go("queue-sweep", scheduler.Sweep(bus))
+	lc.Go("queue-ingest", queue.NewIngester(cfg.Queue, queueRepo, projectRepository, locationRepository, feeds, claimRepository).Run)
+	if cfg.Retention.Enabled {
+		lc.Go("retention", retentionService.Run)
+	}
+	if cfg.Reconcile.Enabled {
+		lc.Go("reconcile", reconcileService.Run(bus))
+	}
+	if cfg.Webhooks.Enabled {
+		dispatcher := webhooks.NewDispatcher(cfg.Webhooks, webhookRepository, categoryRegistry)
+
+		lc.Go("webhooks-outbox", dispatcher.Follow(locationRepository))
+		lc.Go("webhooks", dispatcher.Work)
+	}
+	lc.Go("http", func(ctx context.Context) error {
+		return app.Listen(cfg.HTTP.Listen)
+	})
 
-	if err := app.Listen(":80"); err != nil {
-		panic(fmt.Sprintf("app error: %s", err.Error()))
+	if err := lc.Run(); err != nil {
+		logrus.Fatalf("app error: %s", err)
 	}
 }
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/tracing"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/audit"
+	usersRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+// localUser holds the user authenticated by the admin middleware.
+const localUser = "user"
+
+func currentUser(c *fiber.Ctx) *usersRepository.User {
+	user, _ := c.Locals(localUser).(*usersRepository.User)
+
+	return user
+}
+
+// recordRead audit-logs a read of raw tweet text, the text must not be returned when this fails.
+func recordRead(c *fiber.Ctx, audits audit.Repository, user *usersRepository.User, action, projectID string, entryID int) error {
+	return audits.Record(c.UserContext(), &audit.Record{
+		Action:    action,
+		UserID:    user.ID.Hex(),
+		ProjectID: projectID,
+		EntryID:   entryID,
+		IP:        clientIP(c),
+		RequestID: tracing.RequestID(c),
+	})
+}
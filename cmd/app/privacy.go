@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/tracing"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/audit"
+	"github.com/YusufOzmen01/veri-kontrol-backend/retention"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+type EraseBody struct {
+	retention.Subject
+	Mode   string `json:"mode"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type Privacy interface {
+	LookupSubject(c *fiber.Ctx) error
+	ExportSubject(c *fiber.Ctx) error
+	EraseSubject(c *fiber.Ctx) error
+	Purge(c *fiber.Ctx) error
+}
+
+type privacyAdmin struct {
+	service retention.Service
+}
+
+func NewPrivacy(service retention.Service) Privacy {
+	return &privacyAdmin{
+		service: service,
+	}
+}
+
+func actor(c *fiber.Ctx) *audit.Record {
+	return &audit.Record{
+		UserID:    currentUser(c).ID.Hex(),
+		IP:        clientIP(c),
+		RequestID: tracing.RequestID(c),
+	}
+}
+
+func querySubject(c *fiber.Ctx) (*retention.Subject, error) {
+	subject := &retention.Subject{
+		Phone: c.Query("phone"),
+		Name:  c.Query("name"),
+	}
+
+	return subject, subject.Validate()
+}
+
+// LookupSubject lists the records mentioning ?phone= or ?name= with the tweets redacted.
+func (p *privacyAdmin) LookupSubject(c *fiber.Ctx) error {
+	subject, err := querySubject(c)
+	if err != nil {
+		return sendValidationErrors(c, err)
+	}
+
+	export, err := p.service.Lookup(c.UserContext(), subject, actor(c))
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(export)
+}
+
+// ExportSubject returns the same records with the raw tweets as a file to hand over to the subject.
+func (p *privacyAdmin) ExportSubject(c *fiber.Ctx) error {
+	subject, err := querySubject(c)
+	if err != nil {
+		return sendValidationErrors(c, err)
+	}
+
+	export, err := p.service.Export(c.UserContext(), subject, actor(c))
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Attachment(fmt.Sprintf("subject-export-%s.json", export.ExportedAt.Format("20060102-150405")))
+
+	return c.JSON(export)
+}
+
+func (p *privacyAdmin) EraseSubject(c *fiber.Ctx) error {
+	body := &EraseBody{Mode: retention.ModeAnonymize}
+	if err := json.Unmarshal(c.Body(), body); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if err := body.Subject.Validate(); err != nil {
+		return sendValidationErrors(c, err)
+	}
+
+	erasure, err := p.service.Erase(c.UserContext(), &body.Subject, body.Mode, body.DryRun, actor(c))
+	if err != nil {
+		if _, ok := err.(validation.Errors); ok {
+			return sendValidationErrors(c, err)
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(erasure)
+}
+
+// Purge applies the retention policies now, ?dry_run=true only counts.
+func (p *privacyAdmin) Purge(c *fiber.Ctx) error {
+	report, err := p.service.Purge(c.UserContext(), c.Query("dry_run") == "true")
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(report)
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	projectsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	usersRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/users"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// localProject holds the project of the request, the default project on the routes without /projects/:project_id.
+const localProject = "project"
+
+// projectTTL is how long a replica may keep serving a project after it was archived on another replica.
+const projectTTL = time.Minute
+
+func currentProject(c *fiber.Ctx) *projectsRepository.Project {
+	project, _ := c.Locals(localProject).(*projectsRepository.Project)
+
+	return project
+}
+
+func projectCacheKey(id string) string {
+	return "project_" + id
+}
+
+// defaultProject carries the feed and the cities of the config, they are written to the default project on startup.
+func defaultProject(cfg *config.Config) *projectsRepository.Project {
+	project := &projectsRepository.Project{
+		ID:   projectsRepository.DefaultID,
+		Name: projectsRepository.DefaultID,
+		Feed: projectsRepository.Feed{
+			AreasURL:  cfg.Feed.AreasURL,
+			SingleURL: cfg.Feed.SingleURL,
+		},
+		Regions: make([]projectsRepository.Region, 0, len(cfg.Cities)),
+		// Varsayılan proje her kategoriyi kabul ediyor
+		Categories: make([]string, 0),
+	}
+
+	for id, box := range cfg.Cities {
+		project.Regions = append(project.Regions, projectsRepository.Region{ID: id, Box: box})
+	}
+	sort.Slice(project.Regions, func(i, j int) bool { return project.Regions[i].ID < project.Regions[j].ID })
+
+	return project
+}
+
+// loadProject keeps the project in the cache for projectTTL, it is looked up on every request.
+func loadProject(ctx context.Context, repo projectsRepository.Repository, cache sources.Cache, id string) (*projectsRepository.Project, error) {
+	if cached, ok := cache.Get(projectCacheKey(id)); ok {
+		return cached.(*projectsRepository.Project), nil
+	}
+
+	project, err := repo.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.SetWithTTL(projectCacheKey(id), project, 1, projectTTL)
+
+	return project, nil
+}
+
+// projectMiddleware loads the project of :project_id, archived projects only accept reads.
+func projectMiddleware(repo projectsRepository.Repository, cache sources.Cache) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		project, err := loadProject(c.UserContext(), repo, cache, c.Params("project_id", projectsRepository.DefaultID))
+		if errors.Is(err, projectsRepository.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString("Project not found.")
+		}
+		if err != nil {
+			logging.For(c).Errorln(err)
+
+			return c.Status(500).SendString(err.Error())
+		}
+
+		if project.Archived() && c.Method() != fiber.MethodGet {
+			return c.Status(fiber.StatusGone).SendString("Project is archived.")
+		}
+
+		c.Locals(localProject, project)
+
+		return c.Next()
+	}
+}
+
+// eventsPath is the SSE route of the admin routes, the only one taking ?auth_key=.
+const eventsPath = "/admin/events"
+
+// adminMiddleware lets the moderators of the project of the request through.
+func adminMiddleware(users usersRepository.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authKey := c.Get("Auth-Key")
+		if len(authKey) == 0 && c.Method() == fiber.MethodGet && strings.HasSuffix(c.Path(), eventsPath) {
+			// EventSource header gönderemiyor, diğer route'larda anahtar proxy ve erişim loglarına düşmesin
+			authKey = c.Query("auth_key")
+		}
+
+		user, err := users.GetUser(c.UserContext(), authKey)
+		if err != nil {
+			return c.Status(401).SendString("User not found.")
+		}
+
+		logging.SetUser(c, user.ID.Hex())
+
+		if user.PermIn(currentProject(c).ID) < usersRepository.PermModerator {
+			return c.Status(401).SendString("You are not allowed to access here.")
+		}
+
+		c.Locals(localUser, user)
+
+		return c.Next()
+	}
+}
+
+// globalModerator guards the routes that act on every project or on global data, a role in one project isn't enough.
+func globalModerator(c *fiber.Ctx) error {
+	if currentUser(c).PermLevel < usersRepository.PermModerator {
+		return c.Status(403).SendString("Only global moderators can access here.")
+	}
+
+	return c.Next()
+}
+
+type ProjectBody struct {
+	ID         string                      `json:"_id"`
+	Name       string                      `json:"name"`
+	Feed       projectsRepository.Feed     `json:"feed"`
+	Regions    []projectsRepository.Region `json:"regions"`
+	Categories []string                    `json:"categories"`
+}
+
+type MemberBody struct {
+	PermLevel int `json:"perm_level"`
+}
+
+type Projects interface {
+	GetProjects(c *fiber.Ctx) error
+	GetProject(c *fiber.Ctx) error
+	CreateProject(c *fiber.Ctx) error
+	ArchiveProject(c *fiber.Ctx) error
+	SetMember(c *fiber.Ctx) error
+}
+
+type projectsAdmin struct {
+	repo       projectsRepository.Repository
+	users      usersRepository.Repository
+	categories CategoryRegistry
+	cache      sources.Cache
+}
+
+func NewProjects(repo projectsRepository.Repository, users usersRepository.Repository, categories CategoryRegistry, cache sources.Cache) Projects {
+	return &projectsAdmin{
+		repo:       repo,
+		users:      users,
+		categories: categories,
+		cache:      cache,
+	}
+}
+
+// GetProjects lists the active projects, ?archived=true includes the archived ones.
+func (p *projectsAdmin) GetProjects(c *fiber.Ctx) error {
+	projects, err := p.repo.GetProjects(c.UserContext(), c.Query("archived") == "true")
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(projects)
+}
+
+func (p *projectsAdmin) GetProject(c *fiber.Ctx) error {
+	project, err := p.repo.GetProject(c.UserContext(), c.Params("project_id"))
+	if errors.Is(err, projectsRepository.ErrNotFound) {
+		return c.Status(404).SendString("Project not found.")
+	}
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(project)
+}
+
+func (p *projectsAdmin) CreateProject(c *fiber.Ctx) error {
+	body := &ProjectBody{}
+	if err := json.Unmarshal(c.Body(), body); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	project := &projectsRepository.Project{
+		ID:         body.ID,
+		Name:       body.Name,
+		Feed:       body.Feed,
+		Regions:    body.Regions,
+		Categories: body.Categories,
+		CreatedBy:  currentUser(c).ID.Hex(),
+	}
+	if project.Regions == nil {
+		project.Regions = make([]projectsRepository.Region, 0)
+	}
+	if project.Categories == nil {
+		project.Categories = make([]string, 0)
+	}
+
+	if err := project.Validate(); err != nil {
+		return sendValidationErrors(c, err)
+	}
+
+	registry, err := p.categories.Load(c.UserContext())
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	errs := make(validation.Errors, 0)
+	for _, id := range project.Categories {
+		if category := registry.Get(id); category == nil || category.Archived() {
+			errs.Add("categories", "unknown category %q", id)
+		}
+	}
+	if len(errs) > 0 {
+		return sendValidationErrors(c, errs)
+	}
+
+	if err := p.repo.CreateProject(c.UserContext(), project); err != nil {
+		if errors.Is(err, projectsRepository.ErrExists) {
+			return c.Status(fiber.StatusConflict).SendString("Project already exists.")
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	logging.For(c).Infof("project %s created", project.ID)
+
+	return c.Status(fiber.StatusCreated).JSON(project)
+}
+
+func (p *projectsAdmin) ArchiveProject(c *fiber.Ctx) error {
+	id := c.Params("project_id")
+	if id == projectsRepository.DefaultID {
+		return c.Status(400).SendString("The default project can't be archived.")
+	}
+
+	if err := p.repo.ArchiveProject(c.UserContext(), id, currentUser(c).ID.Hex()); err != nil {
+		if errors.Is(err, projectsRepository.ErrNotFound) {
+			return c.Status(404).SendString("Project not found.")
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	// Diğer replikalar projeyi en geç projectTTL sonra arşivlenmiş görür
+	p.cache.Del(projectCacheKey(id))
+
+	logging.For(c).Infof("project %s archived", id)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SetMember sets the permission level of :user_id in the project, 0 removes the project role.
+func (p *projectsAdmin) SetMember(c *fiber.Ctx) error {
+	body := &MemberBody{}
+	if err := json.Unmarshal(c.Body(), body); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	errs := make(validation.Errors, 0)
+
+	userID, err := primitive.ObjectIDFromHex(c.Params("user_id"))
+	if err != nil {
+		errs.Add("user_id", "invalid user id")
+	}
+	if body.PermLevel < 0 || body.PermLevel > usersRepository.PermModerator {
+		errs.Add("perm_level", "must be between 0 and %d", usersRepository.PermModerator)
+	}
+	if len(errs) > 0 {
+		return sendValidationErrors(c, errs)
+	}
+
+	projectID := c.Params("project_id")
+	if _, err := p.repo.GetProject(c.UserContext(), projectID); err != nil {
+		if errors.Is(err, projectsRepository.ErrNotFound) {
+			return c.Status(404).SendString("Project not found.")
+		}
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if err := p.users.SetProjectRole(c.UserContext(), userID, projectID, body.PermLevel); err != nil {
+		if errors.Is(err, usersRepository.ErrNotFound) {
+			return c.Status(404).SendString("User not found.")
+		}
+
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
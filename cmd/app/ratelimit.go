@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/metrics"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/ratelimit"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/ratelimits"
+	usersRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+// clientIP takes the address appended by the load balancer, the first X-Forwarded-For entries can be forged.
+func clientIP(c *fiber.Ctx) string {
+	if ips := c.IPs(); len(ips) > 0 {
+		return ips[len(ips)-1]
+	}
+
+	return c.IP()
+}
+
+// rateLimitIdentity limits callers with a valid Auth-Key per user and everyone else per ip.
+func rateLimitIdentity(users usersRepository.Repository) func(c *fiber.Ctx) *ratelimit.Identity {
+	return func(c *fiber.Ctx) *ratelimit.Identity {
+		if authKey := c.Get("Auth-Key"); len(authKey) > 0 {
+			if user, err := users.GetUser(c.UserContext(), authKey); err == nil {
+				logging.SetUser(c, user.ID.Hex())
+
+				return &ratelimit.Identity{
+					Key:    "user:" + strconv.FormatUint(uint64(user.AuthKeyHash), 10),
+					UserID: user.ID.Hex(),
+				}
+			}
+		}
+
+		return &ratelimit.Identity{Key: "ip:" + clientIP(c), Anonymous: true}
+	}
+}
+
+func recordRateLimitHit(hits ratelimits.Repository) func(c *fiber.Ctx, route string, identity *ratelimit.Identity) {
+	return func(c *fiber.Ctx, route string, identity *ratelimit.Identity) {
+		metrics.RateLimitHits.WithLabelValues(route, strconv.FormatBool(identity.Anonymous)).Inc()
+
+		if err := hits.RecordHit(c.UserContext(), &ratelimits.Hit{
+			Route:  route,
+			Key:    identity.Key,
+			UserID: identity.UserID,
+			IP:     clientIP(c),
+		}); err != nil {
+			logging.For(c).Errorf("couldn't record rate limit hit: %s", err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/YusufOzmen01/veri-kontrol-backend/reconcile"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Reconciliation interface {
+	GetReport(c *fiber.Ctx) error
+}
+
+type reconciliationAdmin struct {
+	service reconcile.Service
+}
+
+func NewReconciliation(service reconcile.Service) Reconciliation {
+	return &reconciliationAdmin{
+		service: service,
+	}
+}
+
+// GetReport reconciles the project of the request without fixing anything, fixes are applied by cmd/reconcile -fix
+// or the scheduled run.
+func (r *reconciliationAdmin) GetReport(c *fiber.Ctx) error {
+	report, err := r.service.Reconcile(c.UserContext(), currentProject(c), false)
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(report)
+}
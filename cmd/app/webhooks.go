@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	categoriesRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/categories"
+	webhooksRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/webhooks"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/YusufOzmen01/veri-kontrol-backend/webhooks"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookBody filters the deliveries by Categories, Types are the legacy integer types of old clients and are turned
+// into their categories.
+type WebhookBody struct {
+	URL        string   `json:"url"`
+	Events     []string `json:"events"`
+	Categories []string `json:"categories"`
+	Types      []int    `json:"types"`
+}
+
+type Webhooks interface {
+	CreateEndpoint(c *fiber.Ctx) error
+	GetEndpoints(c *fiber.Ctx) error
+	DeactivateEndpoint(c *fiber.Ctx) error
+	GetDeliveries(c *fiber.Ctx) error
+	ReplayDelivery(c *fiber.Ctx) error
+	ReplayFailed(c *fiber.Ctx) error
+}
+
+type webhookAdmin struct {
+	repo       webhooksRepository.Repository
+	categories CategoryRegistry
+}
+
+func NewWebhooks(repo webhooksRepository.Repository, categories CategoryRegistry) Webhooks {
+	return &webhookAdmin{
+		repo:       repo,
+		categories: categories,
+	}
+}
+
+// validateWebhook adds the categories of the legacy types to body.Categories.
+func validateWebhook(body *WebhookBody, registry *categoriesRepository.Registry) error {
+	errs := validation.Errors{}
+
+	if u, err := url.Parse(body.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		errs.Add("url", "must be an absolute http or https url")
+	}
+
+	if len(body.Events) == 0 {
+		errs.Add("events", "at least one of %v is required", webhooks.Events)
+	}
+	for _, event := range body.Events {
+		known := false
+		for _, e := range webhooks.Events {
+			known = known || e == event
+		}
+
+		if !known {
+			errs.Add("events", "unknown event %q, known events are %v", event, webhooks.Events)
+		}
+	}
+
+	for _, id := range body.Categories {
+		if category := registry.Get(id); category == nil || category.Archived() {
+			errs.Add("categories", "unknown category %q", id)
+		}
+	}
+	for _, t := range body.Types {
+		category := registry.Legacy(t)
+		if category == nil {
+			errs.Add("types", "unknown type %d", t)
+
+			continue
+		}
+
+		body.Categories = append(body.Categories, category.ID)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// CreateEndpoint returns the signing secret of the endpoint, it is not shown again.
+func (w *webhookAdmin) CreateEndpoint(c *fiber.Ctx) error {
+	body := &WebhookBody{}
+	if err := json.Unmarshal(c.Body(), body); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	registry, err := w.categories.Load(c.UserContext())
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	if err := validateWebhook(body, registry); err != nil {
+		return sendValidationErrors(c, err)
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	userID, _ := c.Locals(logging.LocalUserID).(string)
+
+	endpoint := &webhooksRepository.Endpoint{
+		URL:        body.URL,
+		Secret:     secret,
+		Events:     body.Events,
+		Categories: body.Categories,
+		CreatedBy:  userID,
+	}
+	if endpoint.Categories == nil {
+		endpoint.Categories = make([]string, 0)
+	}
+
+	if err := w.repo.CreateEndpoint(c.UserContext(), endpoint); err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(endpoint)
+}
+
+func (w *webhookAdmin) GetEndpoints(c *fiber.Ctx) error {
+	endpoints, err := w.repo.GetEndpoints(c.UserContext(), false)
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	for _, endpoint := range endpoints {
+		endpoint.Secret = ""
+	}
+
+	return c.JSON(endpoints)
+}
+
+func (w *webhookAdmin) DeactivateEndpoint(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid id.")
+	}
+
+	return w.respond(c, w.repo.DeactivateEndpoint(c.UserContext(), id))
+}
+
+// GetDeliveries lists the last deliveries of an endpoint with their attempts, ?status= filters them.
+func (w *webhookAdmin) GetDeliveries(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid id.")
+	}
+
+	deliveries, err := w.repo.GetDeliveries(c.UserContext(), id, c.Query("status"), int64(c.QueryInt("limit", 100)))
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.JSON(deliveries)
+}
+
+func (w *webhookAdmin) ReplayDelivery(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("delivery_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid id.")
+	}
+
+	return w.respond(c, w.repo.Replay(c.UserContext(), id))
+}
+
+// ReplayFailed sends every failed delivery of an endpoint again, after the receiver is fixed.
+func (w *webhookAdmin) ReplayFailed(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid id.")
+	}
+
+	count, err := w.repo.ReplayFailed(c.UserContext(), id)
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.JSON(fiber.Map{"replayed": count})
+}
+
+func (w *webhookAdmin) respond(c *fiber.Ctx, err error) error {
+	if errors.Is(err, webhooksRepository.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		logging.For(c).Errorln(err)
+
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
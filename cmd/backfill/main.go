@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/backfill"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/jobs"
+	locationsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	projectsRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	log "github.com/sirupsen/logrus"
+)
+
+type dependencies struct {
+	locations locationsRepository.Repository
+	projects  projectsRepository.Repository
+	feeds     tools.Feeds
+	protector pii.Protector
+}
+
+var registry = map[string]func(d *dependencies) backfill.Job{
+	"tweet-contents": func(d *dependencies) backfill.Job {
+		return backfill.NewTweetContentsJob(d.locations, d.projects, d.feeds, d.protector)
+	},
+	"signals": func(d *dependencies) backfill.Job {
+		return backfill.NewSignalsJob(d.locations, d.protector)
+	},
+	"protect-tweet-contents": func(d *dependencies) backfill.Job {
+		return backfill.NewProtectTweetContentsJob(d.locations, d.protector)
+	},
+	"addresses": func(d *dependencies) backfill.Job {
+		return backfill.NewAddressesJob(d.locations)
+	},
+}
+
+func main() {
+	jobName := flag.String("job", "", "name of the job to run")
+	concurrency := flag.Int("concurrency", 10, "number of items processed in parallel")
+	rate := flag.Float64("rate", 5, "maximum items per second, 0 for unlimited")
+	batchSize := flag.Int("batch", 100, "number of items fetched and checkpointed at once")
+	maxAttempts := flag.Int("max-attempts", 3, "attempts per item before it is dead-lettered")
+	reset := flag.Bool("reset", false, "forget the checkpoint and start the job from the beginning")
+	deadLetters := flag.Bool("dead-letters", false, "list the dead-lettered items of the job instead of running it")
+	flag.Parse()
+
+	newJob, ok := registry[*jobName]
+	if !ok {
+		names := make([]string, 0, len(registry))
+		for name := range registry {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Printf("unknown job %q, available jobs: %v\n", *jobName, names)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg := config.MustLoad()
+
+	mongoClient := sources.NewMongoClient(ctx, cfg.Mongo.URI, cfg.Mongo.Database, cfg.Mongo.MaxPoolSize)
+	jobRepository := jobs.NewRepository(mongoClient)
+
+	protector, err := pii.NewProtector(cfg.PII.EncryptionKey)
+	if err != nil {
+		log.Fatalf("Invalid pii.encryption_key: %s", err)
+	}
+
+	job := newJob(&dependencies{
+		locations: locationsRepository.NewRepository(mongoClient),
+		projects:  projectsRepository.NewRepository(mongoClient),
+		feeds:     tools.NewFeeds(cfg.Feed, sources.NewCache(cfg.Cache.MaxCost, cfg.Cache.NumCounters, cfg.Cache.BufferItems)),
+		protector: protector,
+	})
+
+	if *deadLetters {
+		letters, err := jobRepository.GetDeadLetters(ctx, job.Name())
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		for _, letter := range letters {
+			fmt.Printf("%s\t%s\t%d\t%s\n", letter.CreatedAt.Format(time.RFC3339), letter.ItemID, letter.Attempts, letter.Error)
+		}
+
+		return
+	}
+
+	if *reset {
+		if err := jobRepository.ResetCheckpoint(ctx, job.Name()); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	runner := backfill.NewRunner(jobRepository, backfill.Options{
+		Concurrency:   *concurrency,
+		RatePerSecond: *rate,
+		BatchSize:     *batchSize,
+		MaxAttempts:   *maxAttempts,
+	})
+
+	if err := runner.Run(ctx, job); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/backup"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	action := flag.String("action", "dump", "dump, list, restore or verify")
+	dir := flag.String("dir", "backups", "directory the snapshots are kept in")
+	prefix := flag.String("prefix", "", "name prefix of a new snapshot, the database name by default")
+	snapshot := flag.String("snapshot", backup.Latest, "snapshot to restore or verify, latest picks the newest one")
+	collections := flag.String("collections", "", "comma separated collections, dump defaults to locations,users and restore and verify to the whole snapshot")
+	entryIDs := flag.String("entry-ids", "", "comma separated entry ids to restore, documents without entry_id are left out")
+	since := flag.String("since", "", "only restore documents whose time field is at or after this RFC3339 time")
+	until := flag.String("until", "", "only restore documents whose time field is before this RFC3339 time")
+	timeField := flag.String("time-field", "updated_at", "field -since and -until are compared with")
+	overwrite := flag.Bool("overwrite", false, "replace live documents that differ from the snapshot, by default only missing ones are inserted")
+	dryRun := flag.Bool("dry-run", false, "only report what restore would change")
+	out := flag.String("out", "", "file the report is written to instead of stdout")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	cfg := config.MustLoad()
+
+	if len(*prefix) == 0 {
+		*prefix = cfg.Mongo.Database
+	}
+
+	mongoClient := sources.NewMongoClient(ctx, cfg.Mongo.URI, cfg.Mongo.Database, cfg.Mongo.MaxPoolSize)
+	service := backup.NewService(mongoClient, cfg.Mongo.Database)
+
+	var result interface{}
+	var err error
+
+	switch *action {
+	case "dump":
+		if err = os.MkdirAll(*dir, 0700); err == nil {
+			result, err = service.Dump(ctx, *dir, *prefix, splitList(*collections))
+		}
+	case "list":
+		result, err = service.List(*dir)
+	case "restore":
+		opts := &backup.RestoreOptions{
+			Collections: splitList(*collections),
+			TimeField:   *timeField,
+			Overwrite:   *overwrite,
+			DryRun:      *dryRun,
+		}
+
+		if opts.EntryIDs, err = parseInts(*entryIDs); err != nil {
+			log.Fatalf("Invalid -entry-ids: %s", err)
+		}
+		if opts.Since, err = parseTime(*since); err != nil {
+			log.Fatalf("Invalid -since: %s", err)
+		}
+		if opts.Until, err = parseTime(*until); err != nil {
+			log.Fatalf("Invalid -until: %s", err)
+		}
+
+		result, err = service.Restore(ctx, *dir, *snapshot, opts)
+	case "verify":
+		result, err = service.Verify(ctx, *dir, *snapshot, splitList(*collections))
+	default:
+		err = fmt.Errorf("unknown action %q", *action)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if len(*out) == 0 {
+		fmt.Println(string(data))
+
+		return
+	}
+
+	if err := os.WriteFile(*out, data, 0600); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func splitList(s string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func parseInts(s string) ([]int, error) {
+	values := make([]int, 0)
+	for _, value := range splitList(s) {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, i)
+	}
+
+	return values, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
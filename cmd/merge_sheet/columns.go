@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	colID          = "id"
+	colOriginal    = "original_address"
+	colCorrected   = "corrected_address"
+	colReason      = "reason"
+	colDuplicates  = "duplicate_ids"
+	colOpenAddress = "open_address"
+	colApartment   = "apartment"
+)
+
+// Sheet'lerde aynı kolon farklı isimlerle yazılmış, normalize edilmiş başlıkları kolonlara eşliyoruz
+var columnAliases = map[string]string{
+	"id":                 colID,
+	"entryid":            colID,
+	"yanlisadres":        colOriginal,
+	"olmasigerekenadres": colCorrected,
+	"hatasebebi":         colReason,
+	"duplicateid":        colDuplicates,
+	"duplicateentryid":   colDuplicates,
+	"acikadres":          colOpenAddress,
+	"apartman":           colApartment,
+	"apartmanadi":        colApartment,
+}
+
+var requiredColumns = []string{colID, colOriginal, colCorrected, colReason}
+
+var turkishFold = strings.NewReplacer(
+	"ı", "i", "İ", "i", "ş", "s", "Ş", "s", "ç", "c", "Ç", "c",
+	"ğ", "g", "Ğ", "g", "ü", "u", "Ü", "u", "ö", "o", "Ö", "o",
+)
+
+func normalizeHeader(header string) string {
+	folded := strings.ToLower(turkishFold.Replace(strings.TrimSpace(header)))
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return -1
+	}, folded)
+}
+
+type columnMap map[string]int
+
+func mapColumns(header []string) (columnMap, error) {
+	columns := make(columnMap)
+
+	for i, h := range header {
+		col, ok := columnAliases[normalizeHeader(h)]
+		if !ok {
+			continue
+		}
+
+		if _, exists := columns[col]; !exists {
+			columns[col] = i
+		}
+	}
+
+	for _, col := range requiredColumns {
+		if _, ok := columns[col]; !ok {
+			return nil, fmt.Errorf("missing required column %q", col)
+		}
+	}
+
+	return columns, nil
+}
+
+func (m columnMap) get(rec []string, col string) string {
+	i, ok := m[col]
+	if !ok || i >= len(rec) {
+		return ""
+	}
+
+	return strings.TrimSpace(rec[i])
+}
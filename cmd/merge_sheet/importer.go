@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/address"
+	"github.com/YusufOzmen01/veri-kontrol-backend/coords"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	OnExistingSkip      = "skip"
+	OnExistingMerge     = "merge"
+	OnExistingOverwrite = "overwrite"
+)
+
+const exampleReason = "örnek veri"
+
+type importer struct {
+	locations  locations.Repository
+	feed       map[int][]float64
+	area       validation.Area
+	dryRun     bool
+	onExisting string
+	normalizer coords.Normalizer
+	imported   map[int]bool
+	report     *Report
+}
+
+func (im *importer) importFile(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = -1
+
+	recs, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	if len(recs) == 0 {
+		return fmt.Errorf("%s is empty", path)
+	}
+
+	columns, err := mapColumns(recs[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i, rec := range recs[1:] {
+		im.importRow(ctx, filepath.Base(path), i+2, columns, rec)
+	}
+
+	return nil
+}
+
+func (im *importer) importRow(ctx context.Context, file string, line int, columns columnMap, rec []string) {
+	idValue := columns.get(rec, colID)
+	if len(idValue) == 0 {
+		// Sheet'lerin alt kısmındaki gönüllü listesi satırları
+		return
+	}
+
+	errs := make(validation.Errors, 0)
+
+	id, err := strconv.Atoi(idValue)
+	if err != nil {
+		errs.Add(colID, "invalid entry id %q", idValue)
+	}
+
+	row := &RowResult{File: file, Line: line, EntryID: id}
+
+	reason := columns.get(rec, colReason)
+	if strings.ToLower(reason) == exampleReason {
+		row.Action = ActionSkip
+		row.Reason = "example row"
+		im.record(row)
+
+		return
+	}
+
+	duplicates := make([]int, 0)
+	for _, value := range strings.Split(columns.get(rec, colDuplicates), ",") {
+		value = strings.TrimSpace(value)
+		if len(value) == 0 {
+			continue
+		}
+
+		duplicateID, err := strconv.Atoi(value)
+		if err != nil {
+			errs.Add(colDuplicates, "invalid entry id %q", value)
+
+			continue
+		}
+
+		if duplicateID != id {
+			duplicates = append(duplicates, duplicateID)
+		}
+	}
+
+	original, ok := im.feed[id]
+	if err == nil && !ok {
+		errs.Add(colID, "entry %d not found in feed", id)
+	}
+
+	correctedAddress := columns.get(rec, colCorrected)
+	openAddress := columns.get(rec, colOpenAddress)
+	apartment := columns.get(rec, colApartment)
+
+	corrected, provenance, err := im.correctedLocation(correctedAddress, original)
+	if err != nil {
+		errs.Add(colCorrected, "%s", err.Error())
+	}
+	if corrected != nil {
+		validation.Coordinates(&errs, colCorrected, corrected, im.area)
+		validation.Shift(&errs, colCorrected, original, corrected, im.area)
+	}
+
+	validation.Length(&errs, colOpenAddress, openAddress, validation.MaxOpenAddressLength)
+	validation.Length(&errs, colApartment, apartment, validation.MaxApartmentLength)
+
+	if len(errs) > 0 {
+		row.Action = ActionInvalid
+		row.Errors = errs
+		im.record(row)
+
+		return
+	}
+
+	data := &locations.LocationDB{
+		EntryID:          id,
+		Corrected:        len(reason) > 0,
+		OriginalAddress:  columns.get(rec, colOriginal),
+		CorrectedAddress: correctedAddress,
+		Reason:           reason,
+		OpenAddress:      openAddress,
+		Apartment:        apartment,
+		Source:           locations.SourceImport,
+	}
+
+	if corrected != nil {
+		data.Location = corrected
+		data.Provenance = provenance
+	} else {
+		data.Location = []float64{original[0], original[1]}
+	}
+
+	im.apply(ctx, row, data)
+
+	for _, duplicateID := range duplicates {
+		duplicateRow := &RowResult{File: file, Line: line, EntryID: duplicateID, DuplicateOf: id}
+
+		duplicate := *data
+		duplicate.EntryID = duplicateID
+		duplicate.DuplicateOf = id
+
+		if corrected == nil {
+			loc, ok := im.feed[duplicateID]
+			if !ok {
+				duplicateRow.Action = ActionInvalid
+				duplicateRow.Errors = validation.Errors{{Field: colDuplicates, Message: fmt.Sprintf("entry %d not found in feed", duplicateID)}}
+				im.record(duplicateRow)
+
+				continue
+			}
+
+			duplicate.Location = []float64{loc[0], loc[1]}
+		}
+
+		im.apply(ctx, duplicateRow, &duplicate)
+	}
+}
+
+// Gönüllülerin "düzeltme yok" yerine yazdıkları, katlanmış ve küçük harfe çevrilmiş halleriyle
+var noCorrection = map[string]bool{
+	"-":          true,
+	"adres yok":  true,
+	"bulunamadi": true,
+	"hata yok":   true,
+}
+
+// correctedLocation returns nil without an error when the address is empty or says there is no correction, every
+// other value has to carry coordinates. Short links only do with -expand-short-links.
+func (im *importer) correctedLocation(address string, original []float64) ([]float64, *coords.Provenance, error) {
+	address = strings.TrimSpace(address)
+	if len(address) == 0 || noCorrection[strings.ToLower(turkishFold.Replace(address))] {
+		return nil, nil, nil
+	}
+
+	result, err := im.normalizer.Normalize(&coords.Input{Text: address, TextField: colCorrected}, original)
+
+	var inputErr *coords.InputError
+	if errors.As(err, &inputErr) {
+		return nil, nil, errors.New(inputErr.Message)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result.Location, result.Provenance, nil
+}
+
+func (im *importer) apply(ctx context.Context, row *RowResult, data *locations.LocationDB) {
+	defer im.record(row)
+
+	if im.imported[data.EntryID] {
+		row.Action = ActionSkip
+		row.Reason = "already imported earlier in this run"
+
+		return
+	}
+	im.imported[data.EntryID] = true
+
+	existing, err := im.locations.GetLocation(ctx, data.EntryID)
+	if err != nil && !errors.Is(err, locations.ErrNotFound) {
+		row.Action = ActionError
+		row.Reason = err.Error()
+
+		return
+	}
+
+	final := data
+	row.Action = ActionCreate
+
+	if existing != nil {
+		switch im.onExisting {
+		case OnExistingSkip:
+			row.Action = ActionSkip
+			row.Reason = "already resolved"
+
+			return
+		case OnExistingMerge:
+			final = mergeLocation(existing, data)
+		case OnExistingOverwrite:
+			final = overwriteLocation(existing, data)
+		}
+
+		row.Action = ActionUpdate
+	} else {
+		final.ID = primitive.NewObjectIDFromTimestamp(time.Now())
+	}
+
+	final.ParsedAddress = address.ParseFields(final.OpenAddress, final.Apartment)
+
+	row.Changes = diffLocations(existing, final)
+	if len(row.Changes) == 0 {
+		row.Action = ActionUnchanged
+
+		return
+	}
+
+	if im.dryRun {
+		return
+	}
+
+	if err := im.locations.ResolveLocation(ctx, final); err != nil {
+		row.Action = ActionError
+		row.Reason = err.Error()
+	}
+}
+
+func (im *importer) record(row *RowResult) {
+	im.report.add(row)
+
+	if im.dryRun || row.Action == ActionInvalid || row.Action == ActionError {
+		fmt.Println(row.String())
+	}
+}
+
+// mergeLocation only fills the fields that are empty in the existing resolution.
+func mergeLocation(existing, data *locations.LocationDB) *locations.LocationDB {
+	merged := *existing
+
+	if len(merged.Location) != 2 {
+		merged.Location = data.Location
+		merged.Provenance = data.Provenance
+	}
+	if len(merged.OriginalAddress) == 0 {
+		merged.OriginalAddress = data.OriginalAddress
+	}
+	if len(merged.CorrectedAddress) == 0 {
+		merged.CorrectedAddress = data.CorrectedAddress
+	}
+	if len(merged.Reason) == 0 {
+		merged.Reason = data.Reason
+		merged.Corrected = data.Corrected
+	}
+	if len(merged.OpenAddress) == 0 {
+		merged.OpenAddress = data.OpenAddress
+	}
+	if len(merged.Apartment) == 0 {
+		merged.Apartment = data.Apartment
+	}
+	if merged.DuplicateOf == 0 {
+		merged.DuplicateOf = data.DuplicateOf
+	}
+	merged.Source = data.Source
+
+	return &merged
+}
+
+// overwriteLocation replaces the fields that exist in the sheet and keeps the rest of the existing resolution.
+func overwriteLocation(existing, data *locations.LocationDB) *locations.LocationDB {
+	overwritten := *existing
+
+	overwritten.Location = data.Location
+	overwritten.Provenance = data.Provenance
+	overwritten.Corrected = data.Corrected
+	overwritten.OriginalAddress = data.OriginalAddress
+	overwritten.CorrectedAddress = data.CorrectedAddress
+	overwritten.Reason = data.Reason
+	overwritten.OpenAddress = data.OpenAddress
+	overwritten.Apartment = data.Apartment
+	overwritten.DuplicateOf = data.DuplicateOf
+	overwritten.Source = data.Source
+
+	return &overwritten
+}
@@ -2,99 +2,103 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
-	"fmt"
-	"github.com/Netflix/go-env"
+	"flag"
+	"path/filepath"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/coords"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
 	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
 	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
 	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	"github.com/YusufOzmen01/veri-kontrol-backend/util"
 	log "github.com/sirupsen/logrus"
-	"os"
-	"strconv"
 )
 
-type Environment struct {
-	MongoUri string `env:"mongo_uri"`
-}
-
 func main() {
-	var environment Environment
-	ctx := context.Background()
-
-	if _, err := env.UnmarshalFromEnviron(&environment); err != nil {
-		panic(err)
+	project := flag.String("project", projects.DefaultID, "project the sheets are imported into")
+	dir := flag.String("dir", "merge_data", "directory containing the exported sheets")
+	dryRun := flag.Bool("dry-run", false, "print what would change without writing to the database")
+	onExisting := flag.String("on-existing", OnExistingSkip, "what to do with already resolved entries: skip, merge or overwrite")
+	reportPath := flag.String("report", "import_report.json", "path of the machine-readable import report")
+	expandShortLinks := flag.Bool("expand-short-links", false, "follow goo.gl links to read the corrected coordinates, the rows with short links are invalid otherwise")
+	flag.Parse()
+
+	switch *onExisting {
+	case OnExistingSkip, OnExistingMerge, OnExistingOverwrite:
+	default:
+		log.Fatalf("invalid -on-existing value %q", *onExisting)
 	}
 
-	mongoClient := sources.NewMongoClient(ctx, environment.MongoUri, "database")
+	cfg := config.MustLoad()
+	ctx := context.Background()
 
-	locationRepository := locations.NewRepository(mongoClient)
+	mongoClient := sources.NewMongoClient(ctx, cfg.Mongo.URI, cfg.Mongo.Database, cfg.Mongo.MaxPoolSize)
 
-	files, err := os.ReadDir("merge_data")
+	p, err := projects.NewRepository(mongoClient).GetProject(ctx, *project)
 	if err != nil {
-		panic(err)
+		log.Fatalf("%s: %s", *project, err)
 	}
 
-	locs, err := tools.GetAllLocations(context.Background(), sources.NewCache(1<<30, 1e7, 64))
+	locationRepository := locations.NewRepository(mongoClient).WithProject(p.ID)
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.csv"))
 	if err != nil {
 		panic(err)
 	}
 
-	for _, file := range files {
-		log.Infof("Starting merging of file %s", file.Name())
-
-		f, err := os.Open(fmt.Sprintf("merge_data/%s", file.Name()))
-		if err != nil {
-			panic(err)
-		}
-
-		csvReader := csv.NewReader(f)
-
-		recs, err := csvReader.ReadAll()
-		if err != nil {
-			panic(err)
-		}
+	upstream := tools.NewFeeds(cfg.Feed, sources.NewCache(cfg.Cache.MaxCost, cfg.Cache.NumCounters, cfg.Cache.BufferItems)).For(p)
 
-		for i, rec := range recs {
-			if i == 0 {
-				continue // Header kısmını geçmek için
-			}
-
-			id, _ := strconv.ParseInt(rec[0], 10, 32)
-
-			location := make([]float64, 0)
+	locs, err := upstream.GetAllLocations(ctx)
+	if err != nil {
+		panic(err)
+	}
 
-			for _, loc := range locs {
-				if loc.EntryID == int(id) {
-					location = loc.Loc
-				}
-			}
+	feed := make(map[int][]float64)
+	for _, loc := range locs {
+		feed[loc.EntryID] = loc.Loc
+	}
 
-			data := &locations.LocationDB{
-				EntryID:          int(id),
-				Corrected:        len(rec[3]) > 0,
-				Location:         location,
-				OriginalAddress:  rec[1],
-				CorrectedAddress: rec[2],
-				Reason:           rec[3],
-			}
+	report := &Report{
+		Project:    p.ID,
+		StartedAt:  time.Now(),
+		DryRun:     *dryRun,
+		OnExisting: *onExisting,
+		Files:      files,
+		Summary:    make(map[string]int),
+		Rows:       make([]*RowResult, 0),
+	}
 
-			if len(rec) > 4 {
-				data.OpenAddress = rec[4]
-			}
+	var expand func(string) (string, error)
+	if *expandShortLinks {
+		expand = util.GatherLongUrlFromShortUrl
+	}
 
-			if len(rec) > 5 {
-				data.Apartment = rec[5]
-			}
+	im := &importer{
+		locations:  locationRepository,
+		feed:       feed,
+		area:       p.Area(),
+		dryRun:     *dryRun,
+		onExisting: *onExisting,
+		normalizer: coords.NewNormalizer(expand),
+		imported:   make(map[int]bool),
+		report:     report,
+	}
 
-			if err := locationRepository.ResolveLocation(ctx, data); err != nil {
-				panic(err)
-			}
+	for _, file := range files {
+		log.Infof("Starting merging of file %s", file)
 
-			log.Infof("%d/%d", i, len(recs)-1)
+		if err := im.importFile(ctx, file); err != nil {
+			log.Errorln(err)
 		}
+	}
 
-		if err := f.Close(); err != nil {
-			panic(err)
-		}
+	report.FinishedAt = time.Now()
+
+	if err := report.write(*reportPath); err != nil {
+		log.Fatalln(err)
 	}
+
+	log.Infof("Import finished %v, report written to %s", report.Summary, *reportPath)
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+)
+
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionSkip      = "skip"
+	ActionInvalid   = "invalid"
+	ActionError     = "error"
+)
+
+type Change struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+type RowResult struct {
+	File        string            `json:"file"`
+	Line        int               `json:"line"`
+	EntryID     int               `json:"entry_id,omitempty"`
+	DuplicateOf int               `json:"duplicate_of,omitempty"`
+	Action      string            `json:"action"`
+	Reason      string            `json:"reason,omitempty"`
+	Errors      validation.Errors `json:"errors,omitempty"`
+	Changes     []Change          `json:"changes,omitempty"`
+}
+
+type Report struct {
+	Project    string         `json:"project"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	DryRun     bool           `json:"dry_run"`
+	OnExisting string         `json:"on_existing"`
+	Files      []string       `json:"files"`
+	Summary    map[string]int `json:"summary"`
+	Rows       []*RowResult   `json:"rows"`
+}
+
+func (r *Report) add(row *RowResult) {
+	r.Rows = append(r.Rows, row)
+	r.Summary[row.Action]++
+}
+
+func (r *Report) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func (r *RowResult) String() string {
+	s := fmt.Sprintf("%s:%d entry %d %s", r.File, r.Line, r.EntryID, r.Action)
+	if r.DuplicateOf > 0 {
+		s += fmt.Sprintf(" (duplicate of %d)", r.DuplicateOf)
+	}
+	if len(r.Reason) > 0 {
+		s += fmt.Sprintf(": %s", r.Reason)
+	}
+	if len(r.Errors) > 0 {
+		s += fmt.Sprintf(": %s", r.Errors.Error())
+	}
+
+	for _, c := range r.Changes {
+		s += fmt.Sprintf("\n    %s: %v -> %v", c.Field, c.Old, c.New)
+	}
+
+	return s
+}
+
+func diffLocations(old, new *locations.LocationDB) []Change {
+	changes := make([]Change, 0)
+
+	compare := func(field string, o, n interface{}) {
+		if fmt.Sprint(o) != fmt.Sprint(n) {
+			changes = append(changes, Change{Field: field, Old: o, New: n})
+		}
+	}
+
+	if old == nil {
+		old = &locations.LocationDB{}
+	}
+
+	compare("location", old.Location, new.Location)
+	compare("corrected", old.Corrected, new.Corrected)
+	compare("original_address", old.OriginalAddress, new.OriginalAddress)
+	compare("corrected_address", old.CorrectedAddress, new.CorrectedAddress)
+	compare("reason", old.Reason, new.Reason)
+	compare("open_address", old.OpenAddress, new.OpenAddress)
+	compare("apartment", old.Apartment, new.Apartment)
+	compare("duplicate_of", old.DuplicateOf, new.DuplicateOf)
+
+	return changes
+}
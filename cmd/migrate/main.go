@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/migrations"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	status := flag.Bool("status", false, "print which migrations are applied instead of running them")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	cfg := config.MustLoad()
+
+	mongoClient := sources.NewMongoClient(ctx, cfg.Mongo.URI, cfg.Mongo.Database, cfg.Mongo.MaxPoolSize)
+
+	if *status {
+		statuses, err := migrations.GetStatus(ctx, mongoClient)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%3d  %-25s  %s\n", s.Version, appliedAt, s.Description)
+		}
+
+		return
+	}
+
+	if err := migrations.Run(ctx, mongoClient); err != nil {
+		log.Fatalln(err)
+	}
+
+	log.Infoln("Migrations complete")
+}
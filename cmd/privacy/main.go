@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/audit"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/notifications"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/users"
+	"github.com/YusufOzmen01/veri-kontrol-backend/retention"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	action := flag.String("action", "lookup", "lookup, export, erase or purge")
+	phone := flag.String("phone", "", "phone number of the data subject")
+	name := flag.String("name", "", "name or Discord id of the data subject")
+	mode := flag.String("mode", retention.ModeAnonymize, "erasure mode, anonymize or delete")
+	dryRun := flag.Bool("dry-run", false, "only report what erase or purge would change")
+	out := flag.String("out", "", "file the output is written to instead of stdout")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	cfg := config.MustLoad()
+
+	mongoClient := sources.NewMongoClient(ctx, cfg.Mongo.URI, cfg.Mongo.Database, cfg.Mongo.MaxPoolSize)
+
+	protector, err := pii.NewProtector(cfg.PII.EncryptionKey)
+	if err != nil {
+		log.Fatalf("Invalid pii.encryption_key: %s", err)
+	}
+
+	service := retention.NewService(cfg.Retention,
+		locations.NewRepository(mongoClient),
+		users.NewRepository(mongoClient),
+		audit.NewRepository(mongoClient),
+		notifications.NewRepository(mongoClient),
+		protector)
+
+	// CLI'dan yapılan işlemler de audit log'a işletim sistemi kullanıcısıyla yazılır
+	actor := &audit.Record{UserID: "cli"}
+	if u, err := user.Current(); err == nil {
+		actor.UserID = "cli:" + u.Username
+	}
+
+	subject := &retention.Subject{Phone: *phone, Name: *name}
+	if *action != "purge" {
+		if err := subject.Validate(); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	var result interface{}
+
+	switch *action {
+	case "lookup":
+		result, err = service.Lookup(ctx, subject, actor)
+	case "export":
+		result, err = service.Export(ctx, subject, actor)
+	case "erase":
+		result, err = service.Erase(ctx, subject, *mode, *dryRun, actor)
+	case "purge":
+		result, err = service.Purge(ctx, *dryRun)
+	default:
+		err = fmt.Errorf("unknown action %q", *action)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if len(*out) == 0 {
+		fmt.Println(string(data))
+
+		return
+	}
+
+	// Dışa aktarılan dosya kişisel veri içeriyor, sadece sahibi okuyabilsin
+	if err := os.WriteFile(*out, data, 0600); err != nil {
+		log.Fatalln(err)
+	}
+}
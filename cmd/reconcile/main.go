@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/reconcile"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/notifications"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	project := flag.String("project", projects.DefaultID, "project to reconcile, all for every active project")
+	fix := flag.Bool("fix", false, "mark orphans and move the uncorrected resolutions of moved entries, otherwise only report")
+	out := flag.String("out", "", "file the report is written to instead of stdout")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	cfg := config.MustLoad()
+
+	mongoClient := sources.NewMongoClient(ctx, cfg.Mongo.URI, cfg.Mongo.Database, cfg.Mongo.MaxPoolSize)
+	projectRepository := projects.NewRepository(mongoClient)
+
+	service := reconcile.NewService(cfg.Reconcile,
+		projectRepository,
+		locations.NewRepository(mongoClient),
+		tools.NewFeeds(cfg.Feed, sources.NewCache(cfg.Cache.MaxCost, cfg.Cache.NumCounters, cfg.Cache.BufferItems)),
+		notifications.NewRepository(mongoClient))
+
+	var targets []*projects.Project
+	if *project == "all" {
+		active, err := projectRepository.GetProjects(ctx, false)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		targets = active
+	} else {
+		p, err := projectRepository.GetProject(ctx, *project)
+		if err != nil {
+			log.Fatalf("%s: %s", *project, err)
+		}
+
+		targets = []*projects.Project{p}
+	}
+
+	reports := make([]*reconcile.Report, 0, len(targets))
+	for _, p := range targets {
+		report, err := service.Reconcile(ctx, p, *fix)
+		if err != nil {
+			log.Fatalf("%s: %s", p.ID, err)
+		}
+
+		log.WithField("summary", report.Summary()).Infof("Reconciled project %s", p.ID)
+
+		reports = append(reports, report)
+	}
+
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if len(*out) == 0 {
+		fmt.Println(string(data))
+
+		return
+	}
+
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		log.Fatalln(err)
+	}
+}
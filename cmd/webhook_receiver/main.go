@@ -0,0 +1,64 @@
+// webhook_receiver is a local endpoint to try webhook deliveries. It verifies the signature and prints the payloads.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/webhooks"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	listen := flag.String("listen", ":8081", "address to listen on")
+	secret := flag.String("secret", "", "secret returned when the endpoint was registered")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum age of the signed timestamp")
+	fail := flag.Int64("fail", 0, "respond 500 to the first n deliveries to try retries")
+	flag.Parse()
+
+	if len(*secret) == 0 {
+		log.Fatalln("-secret is required")
+	}
+
+	var received int64
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := webhooks.Verify(*secret, r.Header.Get(webhooks.HeaderSignature), r.Header.Get(webhooks.HeaderTimestamp), body, *tolerance); err != nil {
+			log.Warnf("rejected delivery %s: %s", r.Header.Get(webhooks.HeaderID), err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+
+			return
+		}
+
+		if n := atomic.AddInt64(&received, 1); n <= *fail {
+			log.Infof("failing delivery %s on purpose (%d/%d)", r.Header.Get(webhooks.HeaderID), n, *fail)
+			http.Error(w, "failing on purpose", http.StatusInternalServerError)
+
+			return
+		}
+
+		pretty := &bytes.Buffer{}
+		if err := json.Indent(pretty, body, "", "  "); err != nil {
+			pretty.Write(body)
+		}
+
+		fmt.Printf("%s %s\n%s\n\n", r.Header.Get(webhooks.HeaderEvent), r.Header.Get(webhooks.HeaderID), pretty)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Infof("Listening on %s", *listen)
+	log.Fatalln(http.ListenAndServe(*listen, nil))
+}
@@ -0,0 +1,253 @@
+// Package coords turns what a volunteer gives as the corrected location, coordinates, a map URL or a plus code, into
+// a latitude and longitude, keeping the input as provenance.
+package coords
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	KindCoordinates = "coordinates"
+	KindURL         = "url"
+	KindPlusCode    = "plus_code"
+	KindGeocode     = "geocode"
+)
+
+// ErrNoCoordinates is wrapped when the text is none of the supported forms or a map URL without a location in it.
+var ErrNoCoordinates = errors.New("no coordinates found")
+
+// InputError is a problem of one of the fields of the Input.
+type InputError struct {
+	Field   string
+	Message string
+	Err     error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+type Input struct {
+	Lat *float64
+	Lng *float64
+	// Text is a map URL, a plus code or "lat, lng", read from TextField.
+	Text      string
+	TextField string
+}
+
+func (in *Input) Empty() bool {
+	return in.Lat == nil && in.Lng == nil && len(strings.TrimSpace(in.Text)) == 0
+}
+
+// Provenance is what the location was given as.
+type Provenance struct {
+	Kind  string `json:"kind" bson:"kind"`
+	Input string `json:"input" bson:"input"`
+	// Expanded is the long URL of a short link.
+	Expanded string `json:"expanded,omitempty" bson:"expanded,omitempty"`
+}
+
+type Result struct {
+	Location   []float64
+	Provenance *Provenance
+}
+
+type Normalizer interface {
+	// Normalize returns the location of the input, reference is used for the short plus codes and may be nil.
+	Normalize(in *Input, reference []float64) (*Result, error)
+}
+
+type normalizer struct {
+	expand func(shortURL string) (string, error)
+}
+
+// NewNormalizer expands short links with expand, they are not followed when it is nil.
+func NewNormalizer(expand func(shortURL string) (string, error)) Normalizer {
+	return &normalizer{
+		expand: expand,
+	}
+}
+
+// Sadece bilinen kısa link servislerini takip ediyoruz, rastgele adreslere istek atmamak için
+var shortLinkHosts = map[string]bool{
+	"goo.gl":          true,
+	"maps.app.goo.gl": true,
+	"g.co":            true,
+	"g.page":          true,
+	"maps.apple":      true,
+}
+
+func (n *normalizer) Normalize(in *Input, reference []float64) (*Result, error) {
+	if in.Lat != nil || in.Lng != nil {
+		if in.Lat == nil || in.Lng == nil {
+			return nil, &InputError{Field: "lat", Message: "lat and lng must be given together"}
+		}
+
+		return &Result{
+			Location:   []float64{*in.Lat, *in.Lng},
+			Provenance: &Provenance{Kind: KindCoordinates, Input: fmt.Sprintf("%v,%v", *in.Lat, *in.Lng)},
+		}, nil
+	}
+
+	text := strings.TrimSpace(in.Text)
+	field := in.TextField
+	if len(field) == 0 {
+		field = "new_address"
+	}
+
+	if loc, ok := parsePair(text); ok {
+		return &Result{Location: loc, Provenance: &Provenance{Kind: KindCoordinates, Input: text}}, nil
+	}
+
+	if strings.HasPrefix(strings.ToLower(text), "http") || strings.HasPrefix(strings.ToLower(text), "geo:") {
+		return n.normalizeURL(text, field)
+	}
+
+	if code := findPlusCode(text); len(code) > 0 {
+		loc, err := decodePlusCode(code, reference)
+		if err != nil {
+			return nil, &InputError{Field: field, Message: err.Error()}
+		}
+
+		return &Result{Location: loc, Provenance: &Provenance{Kind: KindPlusCode, Input: text}}, nil
+	}
+
+	return nil, &InputError{Field: field, Message: "expected coordinates, a map URL or a plus code", Err: ErrNoCoordinates}
+}
+
+func (n *normalizer) normalizeURL(text, field string) (*Result, error) {
+	provenance := &Provenance{Kind: KindURL, Input: text}
+
+	u, err := url.Parse(text)
+	if err != nil {
+		return nil, &InputError{Field: field, Message: fmt.Sprintf("invalid URL: %s", err)}
+	}
+
+	// Kısa linkler açılmadan önce koordinat içermiyor
+	if shortLinkHosts[strings.ToLower(u.Hostname())] {
+		if n.expand == nil {
+			return nil, &InputError{Field: field, Message: "short links are not expanded", Err: ErrNoCoordinates}
+		}
+
+		expanded, err := n.expand(text)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't expand %s: %w", text, err)
+		}
+
+		provenance.Expanded = expanded
+
+		if u, err = url.Parse(expanded); err != nil {
+			return nil, &InputError{Field: field, Message: fmt.Sprintf("invalid expanded URL: %s", err)}
+		}
+	}
+
+	loc, ok := urlLocation(u)
+	if !ok {
+		return nil, &InputError{Field: field, Message: "no coordinates in the map URL", Err: ErrNoCoordinates}
+	}
+
+	return &Result{Location: loc, Provenance: provenance}, nil
+}
+
+var (
+	pairRe        = regexp.MustCompile(`^\s*(-?\d{1,3}(?:\.\d+)?)\s*[,;~ ]\s*(-?\d{1,3}(?:\.\d+)?)\s*$`)
+	googlePinRe   = regexp.MustCompile(`!3d(-?\d+(?:\.\d+)?)!4d(-?\d+(?:\.\d+)?)`)
+	googleViewRe  = regexp.MustCompile(`@(-?\d+(?:\.\d+)?),(-?\d+(?:\.\d+)?)`)
+	osmFragmentRe = regexp.MustCompile(`map=\d+(?:\.\d+)?/(-?\d+(?:\.\d+)?)/(-?\d+(?:\.\d+)?)`)
+)
+
+// parsePair parses "lat,lng", "lat lng" or "lat~lng" with both values in range.
+func parsePair(s string) ([]float64, bool) {
+	m := pairRe.FindStringSubmatch(s)
+	if m == nil {
+		return nil, false
+	}
+
+	return pair(m[1], m[2])
+}
+
+func pair(latValue, lngValue string) ([]float64, bool) {
+	lat, err := strconv.ParseFloat(latValue, 64)
+	if err != nil || math.Abs(lat) > 90 {
+		return nil, false
+	}
+
+	lng, err := strconv.ParseFloat(lngValue, 64)
+	if err != nil || math.Abs(lng) > 180 {
+		return nil, false
+	}
+
+	return []float64{lat, lng}, true
+}
+
+// urlLocation reads the location from Google Maps, Apple Maps, OpenStreetMap, Yandex, Bing, Waze and geo: URLs.
+func urlLocation(u *url.URL) ([]float64, bool) {
+	raw := u.String()
+	host := strings.ToLower(u.Hostname())
+	query := u.Query()
+
+	if u.Scheme == "geo" {
+		// geo:37.57,36.93;u=35 veya geo:0,0?q=37.57,36.93
+		if loc, ok := parsePair(query.Get("q")); ok {
+			return loc, true
+		}
+
+		return parsePair(strings.SplitN(u.Opaque, ";", 2)[0])
+	}
+
+	// Yandex boylamı önce yazıyor
+	if strings.Contains(host, "yandex.") {
+		for _, key := range []string{"pt", "whatshere[point]", "ll"} {
+			if parts := strings.Split(query.Get(key), ","); len(parts) == 2 {
+				if loc, ok := pair(parts[1], parts[0]); ok {
+					return loc, true
+				}
+			}
+		}
+
+		return nil, false
+	}
+
+	// İşaretlenen yer haritanın merkezinden daha doğru, önce o aranır
+	if m := googlePinRe.FindStringSubmatch(raw); m != nil {
+		if loc, ok := pair(m[1], m[2]); ok {
+			return loc, true
+		}
+	}
+
+	if lat, lng := query.Get("mlat"), query.Get("mlon"); len(lat) > 0 && len(lng) > 0 {
+		if loc, ok := pair(lat, lng); ok {
+			return loc, true
+		}
+	}
+
+	for _, key := range []string{"q", "query", "ll", "sll", "daddr", "destination", "center", "cp"} {
+		if loc, ok := parsePair(query.Get(key)); ok {
+			return loc, true
+		}
+	}
+
+	if m := googleViewRe.FindStringSubmatch(u.Path); m != nil {
+		if loc, ok := pair(m[1], m[2]); ok {
+			return loc, true
+		}
+	}
+
+	if m := osmFragmentRe.FindStringSubmatch(u.Fragment); m != nil {
+		if loc, ok := pair(m[1], m[2]); ok {
+			return loc, true
+		}
+	}
+
+	return nil, false
+}
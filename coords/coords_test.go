@@ -0,0 +1,173 @@
+package coords
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func near(a, b []float64) bool {
+	return len(a) == 2 && len(b) == 2 && math.Abs(a[0]-b[0]) < 1e-6 && math.Abs(a[1]-b[1]) < 1e-6
+}
+
+func TestNormalizeText(t *testing.T) {
+	// OLC spesifikasyonundaki örnek ve kısaltılmış halleri
+	olcReference := []float64{51.3708675, -1.217765625}
+
+	tests := []struct {
+		name      string
+		text      string
+		reference []float64
+		want      []float64
+		kind      string
+	}{
+		{"pair with comma", "37.58, 36.93", nil, []float64{37.58, 36.93}, KindCoordinates},
+		{"pair with space", "37.58 36.93", nil, []float64{37.58, 36.93}, KindCoordinates},
+		{"pair with tilde", "37.58~36.93", nil, []float64{37.58, 36.93}, KindCoordinates},
+		{"full plus code", "9C3W9QCJ+2VX", nil, []float64{51.3701125, -1.217765625}, KindPlusCode},
+		{"full plus code lowercase", "9c3w9qcj+2vx", nil, []float64{51.3701125, -1.217765625}, KindPlusCode},
+		{"full plus code without refinement", "9C3W9QCJ+", nil, []float64{51.37125, -1.21875}, KindPlusCode},
+		{"short plus code", "9QCJ+2VX", olcReference, []float64{51.3701125, -1.217765625}, KindPlusCode},
+		{"short plus code with locality", "9QCJ+2VX Newbury", olcReference, []float64{51.3701125, -1.217765625}, KindPlusCode},
+		{"shorter plus code", "CJ+2VX", olcReference, []float64{51.3701125, -1.217765625}, KindPlusCode},
+		{"google pin", "https://www.google.com/maps/place/Antakya/@37.5,36.9,17z/data=!3m1!4b1!4m5!3m4!1s0x0:0x0!8m2!3d37.574!4d36.936", nil, []float64{37.574, 36.936}, KindURL},
+		{"google view", "https://www.google.com/maps/@37.58,36.93,15z", nil, []float64{37.58, 36.93}, KindURL},
+		{"google query", "https://maps.google.com/?q=37.58,36.93", nil, []float64{37.58, 36.93}, KindURL},
+		{"google directions", "https://www.google.com/maps/dir/?api=1&destination=37.58,36.93", nil, []float64{37.58, 36.93}, KindURL},
+		{"apple", "https://maps.apple.com/?q=Pin&ll=37.58,36.93", nil, []float64{37.58, 36.93}, KindURL},
+		{"openstreetmap marker", "https://www.openstreetmap.org/?mlat=37.58&mlon=36.93#map=17/37.50/36.90", nil, []float64{37.58, 36.93}, KindURL},
+		{"openstreetmap fragment", "https://www.openstreetmap.org/#map=17/37.58/36.93", nil, []float64{37.58, 36.93}, KindURL},
+		{"yandex is lng first", "https://yandex.com.tr/harita/?ll=36.93,37.58&z=16", nil, []float64{37.58, 36.93}, KindURL},
+		{"yandex point", "https://yandex.com.tr/harita/?pt=36.93,37.58&ll=36.00,37.00", nil, []float64{37.58, 36.93}, KindURL},
+		{"bing", "https://www.bing.com/maps?cp=37.58~36.93&lvl=16", nil, []float64{37.58, 36.93}, KindURL},
+		{"waze", "https://waze.com/ul?ll=37.58,36.93&navigate=yes", nil, []float64{37.58, 36.93}, KindURL},
+		{"geo", "geo:37.58,36.93;u=35", nil, []float64{37.58, 36.93}, KindURL},
+		{"geo query", "geo:0,0?q=37.58,36.93", nil, []float64{37.58, 36.93}, KindURL},
+	}
+
+	n := NewNormalizer(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := n.Normalize(&Input{Text: tt.text}, tt.reference)
+			if err != nil {
+				t.Fatalf("Normalize(%q) failed: %s", tt.text, err)
+			}
+
+			if !near(result.Location, tt.want) {
+				t.Errorf("Normalize(%q) = %v, want %v", tt.text, result.Location, tt.want)
+			}
+			if result.Provenance.Kind != tt.kind {
+				t.Errorf("Normalize(%q) kind = %s, want %s", tt.text, result.Provenance.Kind, tt.kind)
+			}
+			if result.Provenance.Input != tt.text {
+				t.Errorf("Normalize(%q) input = %q", tt.text, result.Provenance.Input)
+			}
+		})
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	lat := 37.58
+
+	tests := []struct {
+		name          string
+		in            *Input
+		reference     []float64
+		field         string
+		noCoordinates bool
+	}{
+		{"lat without lng", &Input{Lat: &lat}, nil, "lat", false},
+		{"free text", &Input{Text: "Antakya merkez"}, nil, "new_address", true},
+		{"pair out of range", &Input{Text: "137.58, 36.93"}, nil, "new_address", true},
+		{"map URL without location", &Input{Text: "https://www.google.com/maps/search/antakya"}, nil, "new_address", true},
+		{"yandex without location", &Input{Text: "https://yandex.com.tr/harita/?text=antakya"}, nil, "new_address", true},
+		{"short link not expanded", &Input{Text: "https://maps.app.goo.gl/abc123"}, nil, "new_address", true},
+		{"short plus code without reference", &Input{Text: "9QCJ+2VX"}, nil, "new_address", false},
+		{"plus code with odd separator", &Input{Text: "9C3W9QC+2VX"}, nil, "new_address", false},
+		{"plus code with single refinement", &Input{Text: "9C3W9QCJ+2"}, nil, "new_address", false},
+		{"plus code out of range", &Input{Text: "XX3W9QCJ+2VX"}, nil, "new_address", false},
+		{"text field", &Input{Text: "nowhere", TextField: "location"}, nil, "location", true},
+	}
+
+	n := NewNormalizer(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := n.Normalize(tt.in, tt.reference)
+			if err == nil {
+				t.Fatalf("Normalize returned %v, want an error", result.Location)
+			}
+
+			var inputErr *InputError
+			if !errors.As(err, &inputErr) {
+				t.Fatalf("Normalize returned %v, want an InputError", err)
+			}
+			if inputErr.Field != tt.field {
+				t.Errorf("error field = %s, want %s", inputErr.Field, tt.field)
+			}
+			if got := errors.Is(err, ErrNoCoordinates); got != tt.noCoordinates {
+				t.Errorf("errors.Is(err, ErrNoCoordinates) = %v, want %v", got, tt.noCoordinates)
+			}
+		})
+	}
+}
+
+func TestNormalizeShortLink(t *testing.T) {
+	expanded := ""
+	n := NewNormalizer(func(shortURL string) (string, error) {
+		expanded = shortURL
+
+		return "https://www.google.com/maps/place/X/data=!3d37.574!4d36.936", nil
+	})
+
+	result, err := n.Normalize(&Input{Text: "https://maps.app.goo.gl/abc123"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expanded != "https://maps.app.goo.gl/abc123" {
+		t.Errorf("expanded %q", expanded)
+	}
+	if !near(result.Location, []float64{37.574, 36.936}) {
+		t.Errorf("location = %v", result.Location)
+	}
+	if result.Provenance.Expanded != "https://www.google.com/maps/place/X/data=!3d37.574!4d36.936" {
+		t.Errorf("provenance expanded = %q", result.Provenance.Expanded)
+	}
+
+	// Bilinmeyen hostlar kısa link sayılmaz, istek atılmaz
+	expanded = ""
+	if _, err := n.Normalize(&Input{Text: "https://example.com/abc123"}, nil); !errors.Is(err, ErrNoCoordinates) {
+		t.Errorf("unknown host: %v", err)
+	}
+	if len(expanded) > 0 {
+		t.Errorf("unknown host was expanded")
+	}
+}
+
+func TestRecoverPlusCodeNearestArea(t *testing.T) {
+	// 2222+22 8FVC2222+22'nin kısaltması, referans komşu alanda olsa da en yakın alan seçilmeli
+	want := []float64{47.0000625, 8.0000625}
+
+	tests := []struct {
+		name      string
+		reference []float64
+	}{
+		{"same area", []float64{47.5, 8.5}},
+		{"area below", []float64{46.9, 8.5}},
+		{"area on the left", []float64{47.5, 7.9}},
+		{"area below on the left", []float64{46.9, 7.9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := decodePlusCode("2222+22", tt.reference)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !near(loc, want) {
+				t.Errorf("decodePlusCode = %v, want %v", loc, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package coords
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Open Location Code, https://github.com/google/open-location-code/blob/main/docs/specification.md
+const (
+	plusAlphabet     = "23456789CFGHJMPQRVWX"
+	plusSeparator    = 8
+	plusPairLength   = 10
+	plusGridRows     = 5
+	plusGridColumns  = 4
+	plusEncodeLength = 8
+)
+
+var plusCodeRe = regexp.MustCompile(`(?i)(^|\s)([23456789CFGHJMPQRVWX]{2,8}\+[23456789CFGHJMPQRVWX]*)(\s|,|$)`)
+
+// findPlusCode returns the plus code in text, e.g. "8G4Q6XWC+2R" or "6XWC+2R Antakya" whose locality is ignored.
+func findPlusCode(text string) string {
+	m := plusCodeRe.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+
+	return strings.ToUpper(m[2])
+}
+
+// decodePlusCode returns the center of the area of code, short codes are recovered with the nearest area to
+// reference.
+func decodePlusCode(code string, reference []float64) ([]float64, error) {
+	sep := strings.IndexByte(code, '+')
+	if sep%2 != 0 || sep > plusSeparator || len(code)-sep-1 == 1 {
+		return nil, fmt.Errorf("invalid plus code %q", code)
+	}
+
+	if sep < plusSeparator {
+		if len(reference) != 2 {
+			return nil, fmt.Errorf("short plus code %q needs a reference location", code)
+		}
+
+		return recoverPlusCode(code, sep, reference[0], reference[1])
+	}
+
+	// İlk iki karakter enlem 180, boylam 360 dereceyi aşamaz
+	if strings.IndexByte(plusAlphabet, code[0]) >= 180/20 || strings.IndexByte(plusAlphabet, code[1]) >= 360/20 {
+		return nil, fmt.Errorf("invalid plus code %q", code)
+	}
+
+	lat, lng := decodeFull(strings.Replace(code, "+", "", 1))
+
+	return []float64{lat, lng}, nil
+}
+
+func decodeFull(code string) (lat, lng float64) {
+	lat, lng = -90, -180
+	latRes, lngRes := 20.0, 20.0
+
+	for i := 0; i < len(code) && i < plusPairLength; i += 2 {
+		if i > 0 {
+			latRes /= 20
+			lngRes /= 20
+		}
+
+		lat += float64(strings.IndexByte(plusAlphabet, code[i])) * latRes
+		lng += float64(strings.IndexByte(plusAlphabet, code[i+1])) * lngRes
+	}
+
+	for i := plusPairLength; i < len(code); i++ {
+		latRes /= plusGridRows
+		lngRes /= plusGridColumns
+
+		d := strings.IndexByte(plusAlphabet, code[i])
+		lat += float64(d/plusGridColumns) * latRes
+		lng += float64(d%plusGridColumns) * lngRes
+	}
+
+	return lat + latRes/2, lng + lngRes/2
+}
+
+func recoverPlusCode(code string, sep int, refLat, refLng float64) ([]float64, error) {
+	resolution := math.Pow(20, float64(2-(plusSeparator-sep)/2))
+
+	prefix := encodePrefix(refLat, refLng)[:plusSeparator-sep]
+	lat, lng := decodeFull(prefix + strings.Replace(code, "+", "", 1))
+
+	// En yakın alanı seçiyoruz, referans alan sınırına yakınsa komşu alan daha yakın olabilir
+	half := resolution / 2
+	if refLat+half < lat && lat-resolution >= -90 {
+		lat -= resolution
+	} else if refLat-half > lat && lat+resolution <= 90 {
+		lat += resolution
+	}
+	if refLng+half < lng {
+		lng -= resolution
+	} else if refLng-half > lng {
+		lng += resolution
+	}
+
+	return []float64{lat, lng}, nil
+}
+
+func encodePrefix(lat, lng float64) string {
+	lat = math.Min(math.Max(lat+90, 0), 180-1e-10)
+	lng = math.Mod(lng+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+
+	b := strings.Builder{}
+	resolution := 20.0
+	for i := 0; i < plusEncodeLength; i += 2 {
+		latDigit := int(lat / resolution)
+		lngDigit := int(lng / resolution)
+
+		b.WriteByte(plusAlphabet[latDigit])
+		b.WriteByte(plusAlphabet[lngDigit])
+
+		lat -= float64(latDigit) * resolution
+		lng -= float64(lngDigit) * resolution
+		resolution /= 20
+	}
+
+	return b.String()
+}
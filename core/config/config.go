@@ -0,0 +1,419 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Netflix/go-env"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is loaded from defaults, then the YAML file, then the environment. Later sources win.
+type Config struct {
+	Mongo     Mongo             `yaml:"mongo"`
+	HTTP      HTTP              `yaml:"http"`
+	Cache     Cache             `yaml:"cache"`
+	Feed      Feed              `yaml:"feed"`
+	Health    Health            `yaml:"health"`
+	Log       Log               `yaml:"log"`
+	Tracing   Tracing           `yaml:"tracing"`
+	RateLimit RateLimit         `yaml:"rate_limit"`
+	Events    Events            `yaml:"events"`
+	Webhooks  Webhooks          `yaml:"webhooks"`
+	Discord   Discord           `yaml:"discord"`
+	Skips     Skips             `yaml:"skips"`
+	Queue     Queue             `yaml:"queue"`
+	PII       PII               `yaml:"pii"`
+	Retention Retention         `yaml:"retention"`
+	Reconcile Reconcile         `yaml:"reconcile"`
+	Geocoding Geocoding         `yaml:"geocoding"`
+	Cities    map[int][]float64 `yaml:"cities"`
+}
+
+type Mongo struct {
+	URI              string `yaml:"uri" env:"mongo_uri" secret:"url"`
+	Database         string `yaml:"database" env:"mongo_database"`
+	MaxPoolSize      uint64 `yaml:"max_pool_size" env:"mongo_max_pool_size"`
+	MigrateOnStartup bool   `yaml:"migrate_on_startup" env:"migrate_on_startup"`
+}
+
+type HTTP struct {
+	Listen string `yaml:"listen" env:"http_listen"`
+	// ShutdownTimeout bounds the whole shutdown, draining requests, stopping workers and disconnecting from Mongo.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"shutdown_timeout"`
+}
+
+type Cache struct {
+	MaxCost     int64 `yaml:"max_cost" env:"cache_max_cost"`
+	NumCounters int64 `yaml:"num_counters" env:"cache_num_counters"`
+	BufferItems int64 `yaml:"buffer_items" env:"cache_buffer_items"`
+}
+
+type Feed struct {
+	// AreasURL returns every entry in the affected area, SingleURL is formatted with the entry id.
+	AreasURL  string        `yaml:"areas_url" env:"feed_areas_url"`
+	SingleURL string        `yaml:"single_url" env:"feed_single_url"`
+	UserAgent string        `yaml:"user_agent" env:"feed_user_agent"`
+	AreasTTL  time.Duration `yaml:"areas_ttl" env:"feed_areas_ttl"`
+}
+
+type Health struct {
+	Timeout    time.Duration `yaml:"timeout" env:"health_timeout"`
+	FeedMaxAge time.Duration `yaml:"feed_max_age" env:"feed_max_age"`
+	Critical   StringList    `yaml:"critical" env:"ready_critical"`
+}
+
+type Log struct {
+	// Format is json or text
+	Format string `yaml:"format" env:"log_format"`
+	Level  string `yaml:"level" env:"log_level"`
+}
+
+type Tracing struct {
+	// Endpoint is the host:port of an OTLP/HTTP collector, tracing is disabled when it is empty.
+	Endpoint    string  `yaml:"endpoint" env:"tracing_endpoint"`
+	Insecure    bool    `yaml:"insecure" env:"tracing_insecure"`
+	ServiceName string  `yaml:"service_name" env:"tracing_service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"tracing_sample_ratio"`
+}
+
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" env:"rate_limit_enabled"`
+	// Store is memory (per replica) or mongo (shared between replicas)
+	Store  string                `yaml:"store" env:"rate_limit_store"`
+	Routes map[string]RouteLimit `yaml:"routes"`
+}
+
+// RouteLimit has separate buckets for users with a valid Auth-Key and for anonymous callers, keyed by ip.
+type RouteLimit struct {
+	User      Limit `yaml:"user"`
+	Anonymous Limit `yaml:"anonymous"`
+}
+
+// Limit is a token bucket refilled with Rate tokens per second up to Burst tokens.
+type Limit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+type Events struct {
+	// ChangeStreams needs a replica set, the locations collection is polled every PollInterval without it.
+	ChangeStreams bool          `yaml:"change_streams" env:"events_change_streams"`
+	PollInterval  time.Duration `yaml:"poll_interval" env:"events_poll_interval"`
+	// Heartbeat keeps idle SSE connections open behind the load balancer.
+	Heartbeat  time.Duration `yaml:"heartbeat" env:"events_heartbeat"`
+	BufferSize int           `yaml:"buffer_size" env:"events_buffer_size"`
+}
+
+type Webhooks struct {
+	Enabled bool `yaml:"enabled" env:"webhooks_enabled"`
+	// Workers is the number of deliveries sent at the same time by each replica.
+	Workers int `yaml:"workers" env:"webhooks_workers"`
+	// The writes to locations are enqueued and the due deliveries sent every PollInterval.
+	PollInterval time.Duration `yaml:"poll_interval" env:"webhooks_poll_interval"`
+	Timeout      time.Duration `yaml:"timeout" env:"webhooks_timeout"`
+	// A delivery is retried with exponential backoff from BackoffBase up to BackoffMax, then marked failed.
+	MaxAttempts int           `yaml:"max_attempts" env:"webhooks_max_attempts"`
+	BackoffBase time.Duration `yaml:"backoff_base" env:"webhooks_backoff_base"`
+	BackoffMax  time.Duration `yaml:"backoff_max" env:"webhooks_backoff_max"`
+}
+
+type Discord struct {
+	Enabled bool `yaml:"enabled" env:"discord_enabled"`
+	// Mock logs the messages instead of posting them and accepts unsigned interactions, for local testing.
+	Mock bool `yaml:"mock" env:"discord_mock"`
+	// PublicKey of the Discord application, interactions are signed with it.
+	PublicKey string          `yaml:"public_key" env:"discord_public_key"`
+	Webhooks  DiscordWebhooks `yaml:"webhooks"`
+	Interval  time.Duration   `yaml:"interval" env:"discord_interval"`
+	// A milestone is posted every MilestoneStep resolutions.
+	MilestoneStep           int           `yaml:"milestone_step" env:"discord_milestone_step"`
+	ModeratorQueueThreshold int           `yaml:"moderator_queue_threshold" env:"discord_moderator_queue_threshold"`
+	RegionBacklogThreshold  int           `yaml:"region_backlog_threshold" env:"discord_region_backlog_threshold"`
+	AlertCooldown           time.Duration `yaml:"alert_cooldown" env:"discord_alert_cooldown"`
+	// IssuerRoles may issue and rotate submitter keys, ModeratorRoles moderator keys too. Both are Discord role ids.
+	IssuerRoles    StringList `yaml:"issuer_roles" env:"discord_issuer_roles"`
+	ModeratorRoles StringList `yaml:"moderator_roles" env:"discord_moderator_roles"`
+}
+
+// DiscordWebhooks are the channel webhook urls, a notification is not sent when its url is empty.
+type DiscordWebhooks struct {
+	Milestones string `yaml:"milestones" env:"discord_webhook_milestones" secret:"true"`
+	Moderators string `yaml:"moderators" env:"discord_webhook_moderators" secret:"true"`
+	Backlog    string `yaml:"backlog" env:"discord_webhook_backlog" secret:"true"`
+}
+
+type Skips struct {
+	// ExpertThreshold skips move an entry from the volunteers to the moderators, /get-location?pool=expert.
+	ExpertThreshold int `yaml:"expert_threshold" env:"skips_expert_threshold"`
+}
+
+type Queue struct {
+	// An entry served by /get-location is leased to the caller for LeaseDuration, nobody else gets it meanwhile.
+	LeaseDuration time.Duration `yaml:"lease_duration" env:"queue_lease_duration"`
+	SweepInterval time.Duration `yaml:"sweep_interval" env:"queue_sweep_interval"`
+	// MaxCandidates bounds the upstream calls of one request when the best entries are leased or duplicates.
+	MaxCandidates int `yaml:"max_candidates" env:"queue_max_candidates"`
+	// ClusterCell is the size in degrees of the grid used to count nearby entries, 0.001 is about 100m.
+	ClusterCell float64 `yaml:"cluster_cell" env:"queue_cluster_cell"`
+	// The urgency of up to IngestBatch new entries per project is read from their text every IngestInterval.
+	IngestInterval time.Duration `yaml:"ingest_interval" env:"queue_ingest_interval"`
+	IngestBatch    int           `yaml:"ingest_batch" env:"queue_ingest_batch"`
+	// Weights are the defaults, admins can change them at runtime with /admin/queue/weights.
+	Weights Weights `yaml:"weights"`
+}
+
+// Weights multiply the factors of the priority score, every factor is between 0 and 1.
+type Weights struct {
+	Age           float64 `yaml:"age" json:"age"`
+	Urgency       float64 `yaml:"urgency" json:"urgency"`
+	Cluster       float64 `yaml:"cluster" json:"cluster"`
+	RegionBacklog float64 `yaml:"region_backlog" json:"region_backlog"`
+	Skips         float64 `yaml:"skips" json:"skips"`
+}
+
+type PII struct {
+	// EncryptionKey is a base64 encoded 32 byte AES key, the raw tweet contents are stored encrypted with it.
+	EncryptionKey string `yaml:"encryption_key" env:"pii_encryption_key" secret:"true"`
+}
+
+// Retention purges the personal fields of old records, coordinates and types are kept for statistics.
+type Retention struct {
+	Enabled  bool          `yaml:"enabled" env:"retention_enabled"`
+	Interval time.Duration `yaml:"interval" env:"retention_interval"`
+	// Every field is purged this long after the resolution was last written, 0 keeps it forever.
+	TweetContents time.Duration `yaml:"tweet_contents" env:"retention_tweet_contents"`
+	OpenAddress   time.Duration `yaml:"open_address" env:"retention_open_address"`
+	Apartment     time.Duration `yaml:"apartment" env:"retention_apartment"`
+	Sender        time.Duration `yaml:"sender" env:"retention_sender"`
+	// User names and Discord ids are purged this long after the user's last resolution.
+	UserName    time.Duration `yaml:"user_name" env:"retention_user_name"`
+	UserDiscord time.Duration `yaml:"user_discord" env:"retention_user_discord"`
+}
+
+// Reconcile compares the feed of every project with its resolutions.
+type Reconcile struct {
+	Enabled  bool          `yaml:"enabled" env:"reconcile_enabled"`
+	Interval time.Duration `yaml:"interval" env:"reconcile_interval"`
+	// An entry whose feed location moved further than DriftKm from the one it was resolved with is reported.
+	DriftKm float64 `yaml:"drift_km" env:"reconcile_drift_km"`
+	// Fix lets the scheduled run apply the automatic fixes, otherwise it only reports.
+	Fix bool `yaml:"fix" env:"reconcile_fix"`
+}
+
+type Geocoding struct {
+	// Gazetteer is the path of the CSV (optionally gzipped) of mahalle and street centroids, geocoding is off when empty.
+	Gazetteer  string `yaml:"gazetteer" env:"geocoding_gazetteer"`
+	MaxResults int    `yaml:"max_results" env:"geocoding_max_results"`
+	// MinScore is the score a forward match needs to be used as the location of a resolution without a URL.
+	MinScore      float64 `yaml:"min_score" env:"geocoding_min_score"`
+	MaxDistanceKm float64 `yaml:"max_distance_km" env:"geocoding_max_distance_km"`
+}
+
+// StringList is a list in YAML and a comma separated string in the environment.
+type StringList []string
+
+func (l *StringList) UnmarshalEnvironmentValue(data string) error {
+	list := make(StringList, 0)
+	for _, item := range strings.Split(data, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+
+	*l = list
+
+	return nil
+}
+
+func (l StringList) Contains(s string) bool {
+	for _, item := range l {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Default() *Config {
+	return &Config{
+		Mongo: Mongo{
+			Database:         "database",
+			MaxPoolSize:      5,
+			MigrateOnStartup: true,
+		},
+		HTTP: HTTP{
+			Listen:          ":80",
+			ShutdownTimeout: 25 * time.Second,
+		},
+		Cache: Cache{
+			MaxCost:     1 << 30,
+			NumCounters: 1e7,
+			BufferItems: 64,
+		},
+		Feed: Feed{
+			AreasURL:  "https://apigo.afetharita.com/feeds/areas?ne_lat=39.91618777305531&ne_lng=47.85149904303703&sw_lat=36.07272886939253&sw_lng=23.872389299415502",
+			SingleURL: "https://apigo.afetharita.com/feeds/%d",
+			UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
+			AreasTTL:  15 * time.Minute,
+		},
+		Health: Health{
+			Timeout:    5 * time.Second,
+			FeedMaxAge: time.Hour,
+			Critical:   StringList{"mongo"},
+		},
+		Log: Log{
+			Format: "json",
+			Level:  "info",
+		},
+		Tracing: Tracing{
+			ServiceName: "veri-kontrol-backend",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Store:   "memory",
+			Routes: map[string]RouteLimit{
+				"get-location": {
+					User:      Limit{Rate: 1, Burst: 10},
+					Anonymous: Limit{Rate: 0.2, Burst: 5},
+				},
+				"resolve": {
+					User:      Limit{Rate: 0.5, Burst: 10},
+					Anonymous: Limit{Rate: 0.1, Burst: 3},
+				},
+				"skip": {
+					User:      Limit{Rate: 0.5, Burst: 10},
+					Anonymous: Limit{Rate: 0.1, Burst: 3},
+				},
+				"geocode": {
+					User:      Limit{Rate: 2, Burst: 20},
+					Anonymous: Limit{Rate: 0.2, Burst: 5},
+				},
+			},
+		},
+		Events: Events{
+			ChangeStreams: true,
+			PollInterval:  5 * time.Second,
+			Heartbeat:     15 * time.Second,
+			BufferSize:    64,
+		},
+		Webhooks: Webhooks{
+			Enabled:      true,
+			Workers:      2,
+			PollInterval: 2 * time.Second,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			BackoffBase:  10 * time.Second,
+			BackoffMax:   time.Hour,
+		},
+		Discord: Discord{
+			Interval:                time.Minute,
+			MilestoneStep:           1000,
+			ModeratorQueueThreshold: 200,
+			RegionBacklogThreshold:  500,
+			AlertCooldown:           time.Hour,
+		},
+		Skips: Skips{
+			ExpertThreshold: 3,
+		},
+		Queue: Queue{
+			LeaseDuration:  10 * time.Minute,
+			SweepInterval:  30 * time.Second,
+			MaxCandidates:  20,
+			ClusterCell:    0.001,
+			IngestInterval: time.Minute,
+			IngestBatch:    200,
+			Weights: Weights{
+				Age:           1,
+				Urgency:       2,
+				Cluster:       0.5,
+				RegionBacklog: 0.5,
+				Skips:         -0.5,
+			},
+		},
+		Retention: Retention{
+			Interval: time.Hour,
+		},
+		Reconcile: Reconcile{
+			Interval: time.Hour,
+			DriftKm:  0.1,
+		},
+		Geocoding: Geocoding{
+			MaxResults:    5,
+			MinScore:      0.75,
+			MaxDistanceKm: 5,
+		},
+		Cities: map[int][]float64{
+			1:  {36.852702785393014, 36.87286376953126, 36.535570922786015, 35.88409423828126},
+			2:  {36.2104851748389, 36.81861877441407, 35.84286468375614, 35.82984924316407},
+			3:  {36.495937096205274, 36.649870522206335, 36.064120488812605, 35.4740187605459},
+			4:  {36.50903585150776, 36.402143998719424, 36.47976138594277, 36.31474829364722},
+			5:  {36.64234742932176, 36.3232450328562, 36.53629731173617, 36.029282092441115},
+			6:  {36.116001873480265, 36.06470054394251, 36.0627178139989, 35.91771907373497},
+			7:  {38.53348725642158, 38.78062516773912, 37.32756763881127, 35.45481415037825},
+			8:  {37.35461473302187, 38.0755896764663, 36.85431769725969, 36.67725839531126},
+			9:  {39.065058845523424, 40.013647871307754, 37.86798402826048, 36.687836853946884},
+			10: {38.160827052916495, 39.33362355320935, 37.44250898099215, 37.35608449070936},
+		},
+	}
+}
+
+// CityOf returns the smallest id of the city boxes containing location, 0 if there is none.
+func (c *Config) CityOf(location []float64) int {
+	if len(location) != 2 {
+		return 0
+	}
+
+	city := 0
+	for id, box := range c.Cities {
+		if len(box) != 4 || (city > 0 && id > city) {
+			continue
+		}
+
+		if box[0] >= location[0] && box[1] >= location[1] && box[2] <= location[0] && box[3] <= location[1] {
+			city = id
+		}
+	}
+
+	return city
+}
+
+// Load reads the config file at path (skipped if empty), applies the environment overrides and validates the result.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if len(path) > 0 {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("couldn't parse %s: %w", path, err)
+		}
+	}
+
+	if _, err := env.UnmarshalFromEnviron(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// MustLoad loads the config from the path in the config_path environment variable and panics on failure.
+func MustLoad() *Config {
+	cfg, err := Load(os.Getenv("config_path"))
+	if err != nil {
+		panic(fmt.Sprintf("invalid config: %s", err))
+	}
+
+	return cfg
+}
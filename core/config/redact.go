@@ -0,0 +1,49 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Redacted returns the config as YAML with every field tagged `secret` hidden.
+func (c *Config) Redacted() string {
+	cp := *c
+	redact(reflect.ValueOf(&cp).Elem())
+
+	data, err := yaml.Marshal(&cp)
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(data)
+}
+
+func redact(v reflect.Value) {
+	t := v.Type()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+
+		switch field.Kind() {
+		case reflect.Struct:
+			redact(field)
+		case reflect.String:
+			tag, ok := t.Field(i).Tag.Lookup("secret")
+			if !ok || field.Len() == 0 {
+				continue
+			}
+
+			value := redacted
+			// URL'lerde sadece şifreyi gizliyoruz, host bilgisi hata ayıklarken lazım
+			if u, err := url.Parse(field.String()); tag == "url" && err == nil && u.Host != "" {
+				value = u.Redacted()
+			}
+
+			field.SetString(value)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var healthChecks = []string{"mongo", "feed", "cache"}
+
+func (c *Config) Validate() error {
+	problems := make([]string, 0)
+
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(c.Mongo.URI) == 0 {
+		add("mongo.uri is required")
+	}
+	if len(c.Mongo.Database) == 0 {
+		add("mongo.database is required")
+	}
+	if c.Mongo.MaxPoolSize == 0 {
+		add("mongo.max_pool_size must be positive")
+	}
+
+	if len(c.HTTP.Listen) == 0 {
+		add("http.listen is required")
+	}
+	if c.HTTP.ShutdownTimeout <= 0 {
+		add("http.shutdown_timeout must be positive")
+	}
+
+	if c.Cache.MaxCost <= 0 || c.Cache.NumCounters <= 0 || c.Cache.BufferItems <= 0 {
+		add("cache.max_cost, cache.num_counters and cache.buffer_items must be positive")
+	}
+
+	if u, err := url.Parse(c.Feed.AreasURL); err != nil || !strings.HasPrefix(u.Scheme, "http") {
+		add("feed.areas_url must be an http(s) url")
+	}
+	if u, err := url.Parse(fmt.Sprintf(c.Feed.SingleURL, 0)); err != nil || !strings.HasPrefix(u.Scheme, "http") || strings.Count(c.Feed.SingleURL, "%d") != 1 {
+		add("feed.single_url must be an http(s) url containing %%d for the entry id")
+	}
+	if c.Feed.AreasTTL <= 0 {
+		add("feed.areas_ttl must be positive")
+	}
+
+	if c.Health.Timeout <= 0 {
+		add("health.timeout must be positive")
+	}
+	for _, name := range c.Health.Critical {
+		if !StringList(healthChecks).Contains(name) {
+			add("health.critical: unknown check %q, known checks are %v", name, healthChecks)
+		}
+	}
+
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		add("log.format must be json or text")
+	}
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		add("log.level: %s", err)
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be between 0 and 1")
+	}
+	if len(c.Tracing.Endpoint) > 0 && len(c.Tracing.ServiceName) == 0 {
+		add("tracing.service_name is required when tracing is enabled")
+	}
+
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "mongo" {
+		add("rate_limit.store must be memory or mongo")
+	}
+	for route, limits := range c.RateLimit.Routes {
+		for _, limit := range []Limit{limits.User, limits.Anonymous} {
+			if limit.Rate <= 0 || limit.Burst < 1 {
+				add("rate_limit.routes.%s: rate must be positive and burst at least 1", route)
+
+				break
+			}
+		}
+	}
+
+	if c.Events.PollInterval <= 0 || c.Events.Heartbeat <= 0 {
+		add("events.poll_interval and events.heartbeat must be positive")
+	}
+	if c.Events.BufferSize < 1 {
+		add("events.buffer_size must be at least 1")
+	}
+
+	if c.Webhooks.Workers < 1 || c.Webhooks.MaxAttempts < 1 {
+		add("webhooks.workers and webhooks.max_attempts must be at least 1")
+	}
+	if c.Webhooks.PollInterval <= 0 || c.Webhooks.Timeout <= 0 || c.Webhooks.BackoffBase <= 0 {
+		add("webhooks.poll_interval, webhooks.timeout and webhooks.backoff_base must be positive")
+	}
+	if c.Webhooks.BackoffMax < c.Webhooks.BackoffBase {
+		add("webhooks.backoff_max must not be less than webhooks.backoff_base")
+	}
+
+	if c.Discord.Enabled {
+		if !c.Discord.Mock && len(c.Discord.PublicKey) == 0 {
+			add("discord.public_key is required unless discord.mock is set")
+		}
+		if c.Discord.Interval <= 0 || c.Discord.AlertCooldown <= 0 {
+			add("discord.interval and discord.alert_cooldown must be positive")
+		}
+		if c.Discord.MilestoneStep < 1 || c.Discord.ModeratorQueueThreshold < 1 || c.Discord.RegionBacklogThreshold < 1 {
+			add("discord.milestone_step and the discord thresholds must be at least 1")
+		}
+	}
+
+	if c.Skips.ExpertThreshold < 1 {
+		add("skips.expert_threshold must be at least 1")
+	}
+
+	if c.Queue.LeaseDuration <= 0 || c.Queue.SweepInterval <= 0 {
+		add("queue.lease_duration and queue.sweep_interval must be positive")
+	}
+	if c.Queue.MaxCandidates < 1 {
+		add("queue.max_candidates must be at least 1")
+	}
+	if c.Queue.ClusterCell <= 0 {
+		add("queue.cluster_cell must be positive")
+	}
+	if c.Queue.IngestInterval <= 0 || c.Queue.IngestBatch < 1 {
+		add("queue.ingest_interval must be positive and queue.ingest_batch at least 1")
+	}
+
+	if c.Retention.Interval <= 0 {
+		add("retention.interval must be positive")
+	}
+	for name, d := range map[string]time.Duration{
+		"tweet_contents": c.Retention.TweetContents,
+		"open_address":   c.Retention.OpenAddress,
+		"apartment":      c.Retention.Apartment,
+		"sender":         c.Retention.Sender,
+		"user_name":      c.Retention.UserName,
+		"user_discord":   c.Retention.UserDiscord,
+	} {
+		if d < 0 {
+			add("retention.%s must not be negative", name)
+		}
+	}
+
+	if c.Reconcile.Interval <= 0 {
+		add("reconcile.interval must be positive")
+	}
+	if c.Reconcile.DriftKm <= 0 {
+		add("reconcile.drift_km must be positive")
+	}
+
+	if c.Geocoding.MaxResults < 1 {
+		add("geocoding.max_results must be at least 1")
+	}
+	if c.Geocoding.MinScore < 0 || c.Geocoding.MinScore > 1 {
+		add("geocoding.min_score must be between 0 and 1")
+	}
+	if c.Geocoding.MaxDistanceKm <= 0 {
+		add("geocoding.max_distance_km must be positive")
+	}
+
+	for id, box := range c.Cities {
+		// ne_lat, ne_lng, sw_lat, sw_lng
+		if len(box) != 4 {
+			add("cities.%d must have 4 coordinates", id)
+
+			continue
+		}
+
+		if box[0] < box[2] || box[1] < box[3] {
+			add("cities.%d: north east corner must be above and right of the south west corner", id)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Type string
+
+const (
+	TypeResolution   Type = "resolution"
+	TypeAdminUpdate  Type = "admin_update"
+	TypeLeaseExpired Type = "lease_expired"
+	TypeSync         Type = "sync"
+	TypeMoved        Type = "moved" // the reconciliation moved a resolution to the new location of its feed entry
+)
+
+// Event is pushed to the dashboards. Region is the id of the region of the project the entry is in, 0 when it is not
+// about a single entry or outside every region.
+type Event struct {
+	Type      Type        `json:"type"`
+	Project   string      `json:"project,omitempty"`
+	EntryID   int         `json:"entry_id,omitempty"`
+	EntryType int         `json:"entry_type,omitempty"`
+	Category  string      `json:"category,omitempty"`
+	Region    int         `json:"region,omitempty"`
+	Time      time.Time   `json:"time"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// Filter matches every event when it is empty. Region, category and entry type filters don't apply to events without
+// them, a project filter drops the events without a project. EntryTypes are the legacy integer types, the categories
+// added since have none.
+type Filter struct {
+	Types      []Type
+	Projects   []string
+	Regions    []int
+	Categories []string
+	EntryTypes []int
+}
+
+func (f *Filter) Match(e *Event) bool {
+	if len(f.Types) > 0 && !containsType(f.Types, e.Type) {
+		return false
+	}
+
+	if len(f.Projects) > 0 && !containsString(f.Projects, e.Project) {
+		return false
+	}
+
+	if len(f.Regions) > 0 && e.Region > 0 && !containsInt(f.Regions, e.Region) {
+		return false
+	}
+
+	if len(f.Categories) > 0 && len(e.Category) > 0 && !containsString(f.Categories, e.Category) {
+		return false
+	}
+
+	if len(f.EntryTypes) > 0 && e.EntryType > 0 && !containsInt(f.EntryTypes, e.EntryType) {
+		return false
+	}
+
+	return true
+}
+
+func containsType(types []Type, t Type) bool {
+	for _, item := range types {
+		if item == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(strings []string, s string) bool {
+	for _, item := range strings {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInt(ints []int, i int) bool {
+	for _, item := range ints {
+		if item == i {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Bus interface {
+	Publish(e *Event)
+	Subscribe(filter *Filter) *Subscription
+}
+
+type Subscription struct {
+	C <-chan *Event
+
+	c      chan *Event
+	filter *Filter
+	bus    *bus
+	once   sync.Once
+}
+
+// Close stops the delivery and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.remove(s)
+	})
+}
+
+type bus struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	bufferSize  int
+}
+
+// NewBus returns an in-process bus. Slow subscribers lose events instead of blocking the publishers.
+func NewBus(bufferSize int) Bus {
+	return &bus{
+		subscribers: make(map[*Subscription]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+func (b *bus) Publish(e *Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subscribers {
+		if !s.filter.Match(e) {
+			continue
+		}
+
+		select {
+		case s.c <- e:
+		default:
+			logrus.Warnf("dropped %s event of entry %d, subscriber is too slow", e.Type, e.EntryID)
+		}
+	}
+}
+
+func (b *bus) Subscribe(filter *Filter) *Subscription {
+	if filter == nil {
+		filter = &Filter{}
+	}
+
+	c := make(chan *Event, b.bufferSize)
+	s := &Subscription{
+		C:      c,
+		c:      c,
+		filter: filter,
+		bus:    b,
+	}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+func (b *bus) remove(s *Subscription) {
+	b.mu.Lock()
+	delete(b.subscribers, s)
+	b.mu.Unlock()
+
+	close(s.c)
+}
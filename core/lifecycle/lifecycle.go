@@ -0,0 +1,161 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a component with optional start and stop steps. Starts run in registration order, stops in reverse.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+type Manager interface {
+	// Context is the root context of the application, it is cancelled when the workers are stopped.
+	Context() context.Context
+	Register(hook *Hook)
+	// Go runs fn in the background. A failing worker shuts the whole application down.
+	Go(name string, fn func(ctx context.Context) error)
+	// WorkersHook cancels the root context and waits for every worker started with Go.
+	WorkersHook() *Hook
+	// Run starts the hooks and blocks until SIGINT/SIGTERM or a worker failure, then shuts everything down.
+	Run() error
+}
+
+type manager struct {
+	ctx             context.Context
+	cancel          context.CancelFunc
+	shutdownTimeout time.Duration
+
+	hooks   []*Hook
+	workers sync.WaitGroup
+	failed  chan error
+}
+
+func New(shutdownTimeout time.Duration) Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &manager{
+		ctx:             ctx,
+		cancel:          cancel,
+		shutdownTimeout: shutdownTimeout,
+		hooks:           make([]*Hook, 0),
+		failed:          make(chan error, 1),
+	}
+}
+
+func (m *manager) Context() context.Context {
+	return m.ctx
+}
+
+func (m *manager) Register(hook *Hook) {
+	m.hooks = append(m.hooks, hook)
+}
+
+func (m *manager) Go(name string, fn func(ctx context.Context) error) {
+	m.workers.Add(1)
+
+	go func() {
+		defer m.workers.Done()
+
+		if err := fn(m.ctx); err != nil && !errors.Is(err, context.Canceled) {
+			logrus.Errorf("%s stopped: %s", name, err)
+
+			select {
+			case m.failed <- fmt.Errorf("%s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+func (m *manager) WorkersHook() *Hook {
+	return &Hook{
+		Name: "workers",
+		Stop: func(ctx context.Context) error {
+			m.cancel()
+
+			done := make(chan struct{})
+			go func() {
+				m.workers.Wait()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("workers didn't stop in time: %w", ctx.Err())
+			}
+		},
+	}
+}
+
+func (m *manager) Run() error {
+	started := make([]*Hook, 0, len(m.hooks))
+
+	var runErr error
+
+	for _, hook := range m.hooks {
+		if hook.Start != nil {
+			if err := hook.Start(m.ctx); err != nil {
+				runErr = fmt.Errorf("couldn't start %s: %w", hook.Name, err)
+
+				break
+			}
+		}
+
+		started = append(started, hook)
+	}
+
+	if runErr == nil {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		select {
+		case sig := <-signals:
+			logrus.Infof("Received %s, application gracefully shutting down..", sig)
+		case runErr = <-m.failed:
+			logrus.Errorf("Shutting down because of a failure: %s", runErr)
+		}
+	}
+
+	m.shutdown(started)
+
+	return runErr
+}
+
+func (m *manager) shutdown(started []*Hook) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	for i := len(started) - 1; i >= 0; i-- {
+		hook := started[i]
+		if hook.Stop == nil {
+			continue
+		}
+
+		stopStarted := time.Now()
+		if err := hook.Stop(ctx); err != nil {
+			logrus.Errorf("Couldn't stop %s cleanly: %s", hook.Name, err)
+
+			continue
+		}
+
+		logrus.Infof("Stopped %s in %s", hook.Name, time.Since(stopStarted).Round(time.Millisecond))
+	}
+
+	// WorkersHook kaydedilmemiş olsa bile root context iptal edilmeli
+	m.cancel()
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/tracing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	LocalUserID  = "user_id"
+	LocalEntryID = "entry_id"
+)
+
+func Setup(cfg config.Log) {
+	if cfg.Format == "json" {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err == nil {
+		logrus.SetLevel(level)
+	}
+}
+
+// For returns a logger carrying the request, trace, user and entry of the request.
+func For(c *fiber.Ctx) *logrus.Entry {
+	fields := logrus.Fields{
+		"request_id": tracing.RequestID(c),
+		"route":      c.Route().Path,
+	}
+
+	if traceID := tracing.TraceID(c.UserContext()); len(traceID) > 0 {
+		fields["trace_id"] = traceID
+	}
+	if userID := c.Locals(LocalUserID); userID != nil {
+		fields["user_id"] = userID
+	}
+	if entryID := c.Locals(LocalEntryID); entryID != nil {
+		fields["entry_id"] = entryID
+	}
+
+	return logrus.WithFields(fields)
+}
+
+// Middleware writes an access log line for every request. It must run after tracing.Middleware.
+func Middleware(c *fiber.Ctx) error {
+	started := time.Now()
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+
+	entry := For(c).WithFields(logrus.Fields{
+		"method":     c.Method(),
+		"path":       c.Path(),
+		"status":     status,
+		"latency_ms": float64(time.Since(started).Microseconds()) / 1000,
+		"ip":         c.IP(),
+	})
+
+	switch {
+	case err != nil:
+		entry.WithError(err).Error("request failed")
+	case status >= fiber.StatusInternalServerError:
+		entry.Error("request")
+	default:
+		entry.Info("request")
+	}
+
+	return err
+}
+
+// SetUser and SetEntry attach the user and entry of the request to every log line written through For.
+func SetUser(c *fiber.Ctx, userID string) {
+	c.Locals(LocalUserID, userID)
+}
+
+func SetEntry(c *fiber.Ctx, entryID int) {
+	c.Locals(LocalEntryID, entryID)
+}
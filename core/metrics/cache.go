@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheHitsDesc    = prometheus.NewDesc(namespace+"_cache_hits_total", "Number of cache hits.", nil, nil)
+	cacheMissesDesc  = prometheus.NewDesc(namespace+"_cache_misses_total", "Number of cache misses.", nil, nil)
+	cacheRatioDesc   = prometheus.NewDesc(namespace+"_cache_hit_ratio", "Ratio of cache hits to all cache lookups.", nil, nil)
+	cacheAddedDesc   = prometheus.NewDesc(namespace+"_cache_keys_added_total", "Number of keys added to the cache.", nil, nil)
+	cacheEvictedDesc = prometheus.NewDesc(namespace+"_cache_keys_evicted_total", "Number of keys evicted from the cache.", nil, nil)
+)
+
+type cacheCollector struct {
+	cache sources.Cache
+}
+
+// RegisterCache exposes the ristretto metrics of the cache.
+func RegisterCache(cache sources.Cache) {
+	prometheus.MustRegister(&cacheCollector{cache: cache})
+}
+
+func (cc *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheRatioDesc
+	ch <- cacheAddedDesc
+	ch <- cacheEvictedDesc
+}
+
+func (cc *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	m := cc.cache.Metrics()
+	if m == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(m.Hits()))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(m.Misses()))
+	ch <- prometheus.MustNewConstMetric(cacheRatioDesc, prometheus.GaugeValue, m.Ratio())
+	ch <- prometheus.MustNewConstMetric(cacheAddedDesc, prometheus.CounterValue, float64(m.KeysAdded()))
+	ch <- prometheus.MustNewConstMetric(cacheEvictedDesc, prometheus.CounterValue, float64(m.KeysEvicted()))
+}
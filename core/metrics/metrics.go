@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/expfmt"
+)
+
+const namespace = "veri_kontrol"
+
+var (
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	ResolutionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resolutions_total",
+		Help:      "Number of stored resolutions by category and reason.",
+	}, []string{"category", "reason"})
+
+	SkipsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "skips_total",
+		Help:      "Number of skipped entries by reason.",
+	}, []string{"reason"})
+
+	QueueBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_backlog",
+		Help:      "Number of feed entries that are not resolved yet.",
+	})
+
+	UpstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of requests to the upstream feed.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	UpstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Number of failed requests to the upstream feed.",
+	}, []string{"endpoint"})
+
+	RateLimitHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_hits_total",
+		Help:      "Number of requests rejected by the rate limiter.",
+	}, []string{"route", "anonymous"})
+
+	MongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "Latency of MongoDB operations.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "collection"})
+
+	MongoErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongo_errors_total",
+		Help:      "Number of failed MongoDB operations.",
+	}, []string{"operation", "collection"})
+)
+
+// Middleware records the count and latency of every request under its route pattern.
+func Middleware(c *fiber.Ctx) error {
+	started := time.Now()
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+
+	labels := prometheus.Labels{
+		"method": c.Method(),
+		"route":  c.Route().Path,
+		"status": strconv.Itoa(status),
+	}
+
+	RequestsTotal.With(labels).Inc()
+	RequestDuration.With(labels).Observe(time.Since(started).Seconds())
+
+	return err
+}
+
+// Handler serves every registered metric in the Prometheus text format.
+func Handler(c *fiber.Ctx) error {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, string(expfmt.FmtText))
+
+	enc := expfmt.NewEncoder(c, expfmt.FmtText)
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ObserveResolution counts a resolution. Reasons are free text, so they are bucketed to keep the label cardinality low.
+func ObserveResolution(category string, reason string) {
+	bucket := "corrected"
+
+	switch strings.ToLower(strings.TrimSpace(reason)) {
+	case "":
+		bucket = "none"
+	case "hata yok":
+		bucket = "no_error"
+	}
+
+	ResolutionsTotal.WithLabelValues(category, bucket).Inc()
+}
+
+// ObserveUpstream records the latency of an upstream call and counts it as an error if err isn't nil.
+func ObserveUpstream(endpoint string, started time.Time, err error) {
+	UpstreamDuration.WithLabelValues(endpoint).Observe(time.Since(started).Seconds())
+
+	if err != nil {
+		UpstreamErrors.WithLabelValues(endpoint).Inc()
+	}
+}
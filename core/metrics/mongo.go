@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// instrumentedMongo times every call of the wrapped client.
+type instrumentedMongo struct {
+	next sources.MongoClient
+}
+
+func InstrumentMongo(next sources.MongoClient) sources.MongoClient {
+	return &instrumentedMongo{next: next}
+}
+
+func observeMongo(operation, table string, started time.Time, err error) {
+	MongoDuration.WithLabelValues(operation, table).Observe(time.Since(started).Seconds())
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		MongoErrors.WithLabelValues(operation, table).Inc()
+	}
+}
+
+func (im *instrumentedMongo) Aggregate(ctx context.Context, table string, pipeline interface{}, opts ...*options.AggregateOptions) (cur *mongo.Cursor, err error) {
+	defer func(started time.Time) { observeMongo("aggregate", table, started, err) }(time.Now())
+
+	return im.next.Aggregate(ctx, table, pipeline, opts...)
+}
+
+func (im *instrumentedMongo) UpsertOne(ctx context.Context, table string, filter interface{}, update interface{}) (err error) {
+	defer func(started time.Time) { observeMongo("upsert_one", table, started, err) }(time.Now())
+
+	return im.next.UpsertOne(ctx, table, filter, update)
+}
+
+func (im *instrumentedMongo) UpsertMany(ctx context.Context, table string, filter interface{}, update interface{}) (err error) {
+	defer func(started time.Time) { observeMongo("upsert_many", table, started, err) }(time.Now())
+
+	return im.next.UpsertMany(ctx, table, filter, update)
+}
+
+func (im *instrumentedMongo) InsertOne(ctx context.Context, table string, document interface{}, opts ...*options.InsertOneOptions) (err error) {
+	defer func(started time.Time) { observeMongo("insert_one", table, started, err) }(time.Now())
+
+	return im.next.InsertOne(ctx, table, document, opts...)
+}
+
+func (im *instrumentedMongo) InsertMany(ctx context.Context, table string, documents []interface{}, opts ...*options.InsertManyOptions) (err error) {
+	defer func(started time.Time) { observeMongo("insert_many", table, started, err) }(time.Now())
+
+	return im.next.InsertMany(ctx, table, documents, opts...)
+}
+
+func (im *instrumentedMongo) Find(ctx context.Context, table string, filter interface{}, opts ...*options.FindOptions) (cur *mongo.Cursor, err error) {
+	defer func(started time.Time) { observeMongo("find", table, started, err) }(time.Now())
+
+	return im.next.Find(ctx, table, filter, opts...)
+}
+
+func (im *instrumentedMongo) FindOne(ctx context.Context, table string, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	started := time.Now()
+
+	res := im.next.FindOne(ctx, table, filter, opts...)
+	observeMongo("find_one", table, started, res.Err())
+
+	return res
+}
+
+func (im *instrumentedMongo) FindOneAndUpdate(ctx context.Context, table string, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	started := time.Now()
+
+	res := im.next.FindOneAndUpdate(ctx, table, filter, update, opts...)
+	observeMongo("find_one_and_update", table, started, res.Err())
+
+	return res
+}
+
+func (im *instrumentedMongo) Watch(ctx context.Context, table string, pipeline interface{}, opts ...*options.ChangeStreamOptions) (cs *mongo.ChangeStream, err error) {
+	defer func(started time.Time) { observeMongo("watch", table, started, err) }(time.Now())
+
+	return im.next.Watch(ctx, table, pipeline, opts...)
+}
+
+func (im *instrumentedMongo) DeleteOne(ctx context.Context, table string, filter interface{}, opts ...*options.DeleteOptions) (err error) {
+	defer func(started time.Time) { observeMongo("delete_one", table, started, err) }(time.Now())
+
+	return im.next.DeleteOne(ctx, table, filter, opts...)
+}
+
+func (im *instrumentedMongo) DeleteMany(ctx context.Context, table string, filter interface{}, opts ...*options.DeleteOptions) (err error) {
+	defer func(started time.Time) { observeMongo("delete_many", table, started, err) }(time.Now())
+
+	return im.next.DeleteMany(ctx, table, filter, opts...)
+}
+
+func (im *instrumentedMongo) UpdateOne(ctx context.Context, table string, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (err error) {
+	defer func(started time.Time) { observeMongo("update_one", table, started, err) }(time.Now())
+
+	return im.next.UpdateOne(ctx, table, filter, update, opts...)
+}
+
+func (im *instrumentedMongo) ReplaceOne(ctx context.Context, table string, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (err error) {
+	defer func(started time.Time) { observeMongo("replace_one", table, started, err) }(time.Now())
+
+	return im.next.ReplaceOne(ctx, table, filter, replacement, opts...)
+}
+
+func (im *instrumentedMongo) UpdateMany(ctx context.Context, table string, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (modified int64, err error) {
+	defer func(started time.Time) { observeMongo("update_many", table, started, err) }(time.Now())
+
+	return im.next.UpdateMany(ctx, table, filter, update, opts...)
+}
+
+func (im *instrumentedMongo) DoesExist(ctx context.Context, table string, filter bson.D, opts ...*options.FindOneOptions) (exists bool, err error) {
+	defer func(started time.Time) { observeMongo("does_exist", table, started, err) }(time.Now())
+
+	return im.next.DoesExist(ctx, table, filter, opts...)
+}
+
+func (im *instrumentedMongo) CreateIndex(ctx context.Context, table string, keys bson.D, opts ...*options.IndexOptions) (name string, err error) {
+	defer func(started time.Time) { observeMongo("create_index", table, started, err) }(time.Now())
+
+	return im.next.CreateIndex(ctx, table, keys, opts...)
+}
+
+func (im *instrumentedMongo) DropIndex(ctx context.Context, table string, name string) (err error) {
+	defer func(started time.Time) { observeMongo("drop_index", table, started, err) }(time.Now())
+
+	return im.next.DropIndex(ctx, table, name)
+}
+
+func (im *instrumentedMongo) Count(ctx context.Context, table string, filter interface{}, opts ...*options.CountOptions) (count int64, err error) {
+	defer func(started time.Time) { observeMongo("count", table, started, err) }(time.Now())
+
+	return im.next.Count(ctx, table, filter, opts...)
+}
+
+func (im *instrumentedMongo) Ping(ctx context.Context) (err error) {
+	defer func(started time.Time) { observeMongo("ping", "", started, err) }(time.Now())
+
+	return im.next.Ping(ctx)
+}
+
+func (im *instrumentedMongo) Disconnect(ctx context.Context) error {
+	return im.next.Disconnect(ctx)
+}
+
+func (im *instrumentedMongo) WithSession() (sources.MongoClient, error) {
+	session, err := im.next.WithSession()
+	if err != nil {
+		return nil, err
+	}
+
+	return InstrumentMongo(session), nil
+}
+
+func (im *instrumentedMongo) WithTransaction(ctx context.Context, callback func(sessCtx mongo.SessionContext) (interface{}, error)) (result interface{}, err error) {
+	defer func(started time.Time) { observeMongo("transaction", "", started, err) }(time.Now())
+
+	return im.next.WithTransaction(ctx, callback)
+}
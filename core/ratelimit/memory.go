@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+)
+
+const memoryCleanupInterval = 10 * time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// memoryStore only limits the requests that reach this replica.
+type memoryStore struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets:     make(map[string]*bucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+func (s *memoryStore) Take(_ context.Context, key string, limit config.Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.cleanup(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = refill(b.tokens, now.Sub(b.updatedAt), limit)
+	b.updatedAt = now
+
+	if b.tokens < 1 {
+		return false, wait(b.tokens, limit), nil
+	}
+
+	b.tokens--
+
+	return true, 0, nil
+}
+
+// cleanup drops the buckets that weren't used for a while, they would be full again anyway.
+func (s *memoryStore) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < memoryCleanupInterval {
+		return
+	}
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updatedAt) > memoryCleanupInterval {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastCleanup = now
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoStore shares the buckets between replicas. Every take is a single atomic pipeline update.
+type mongoStore struct {
+	mongo sources.MongoClient
+}
+
+func NewMongoStore(mongo sources.MongoClient) Store {
+	return &mongoStore{
+		mongo: mongo,
+	}
+}
+
+type mongoBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+func (s *mongoStore) Take(ctx context.Context, key string, limit config.Limit) (bool, time.Duration, error) {
+	now := time.Now()
+	// Bucket dolduktan sonra tutmanın anlamı yok, TTL index siliyor
+	expiresAt := now.Add(time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second)))
+
+	elapsedSeconds := bson.D{{Key: "$divide", Value: bson.A{
+		bson.D{{Key: "$subtract", Value: bson.A{now, bson.D{{Key: "$ifNull", Value: bson.A{"$updated_at", now}}}}}},
+		1000,
+	}}}
+
+	pipeline := bson.A{
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "tokens", Value: bson.D{{Key: "$min", Value: bson.A{
+				limit.Burst,
+				bson.D{{Key: "$add", Value: bson.A{
+					bson.D{{Key: "$ifNull", Value: bson.A{"$tokens", limit.Burst}}},
+					bson.D{{Key: "$multiply", Value: bson.A{elapsedSeconds, limit.Rate}}},
+				}}},
+			}}}},
+			{Key: "updated_at", Value: now},
+			{Key: "expires_at", Value: expiresAt},
+		}}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "allowed", Value: bson.D{{Key: "$gte", Value: bson.A{"$tokens", 1}}}},
+		}}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "tokens", Value: bson.D{{Key: "$cond", Value: bson.A{"$allowed", bson.D{{Key: "$subtract", Value: bson.A{"$tokens", 1}}}, "$tokens"}}}},
+		}}},
+	}
+
+	result := &mongoBucket{}
+	if err := s.mongo.FindOneAndUpdate(ctx, "rate_limits", bson.D{{Key: "_id", Value: key}}, pipeline, options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)).Decode(result); err != nil {
+		return false, 0, err
+	}
+
+	if result.Allowed {
+		return true, 0, nil
+	}
+
+	return false, wait(result.Tokens, limit), nil
+}
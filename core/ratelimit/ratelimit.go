@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Store keeps a token bucket per key. Take removes a token if there is one, otherwise it tells how long to wait.
+type Store interface {
+	Take(ctx context.Context, key string, limit config.Limit) (bool, time.Duration, error)
+}
+
+// Identity is who the request is limited as, a user id for authenticated callers and an ip address otherwise.
+type Identity struct {
+	Key       string
+	Anonymous bool
+	// UserID is the id of the authenticated caller, it is recorded with the rejected requests.
+	UserID string
+}
+
+type Limiter struct {
+	cfg      config.RateLimit
+	store    Store
+	identify func(c *fiber.Ctx) *Identity
+	onLimit  func(c *fiber.Ctx, route string, identity *Identity)
+}
+
+func NewLimiter(cfg config.RateLimit, store Store, identify func(c *fiber.Ctx) *Identity, onLimit func(c *fiber.Ctx, route string, identity *Identity)) *Limiter {
+	return &Limiter{
+		cfg:      cfg,
+		store:    store,
+		identify: identify,
+		onLimit:  onLimit,
+	}
+}
+
+// Middleware limits the requests of a route with the limits configured for it. Routes without limits and errors of
+// the store let the request through, rate limiting must not take the API down with it.
+func (l *Limiter) Middleware(route string) fiber.Handler {
+	limits, ok := l.cfg.Routes[route]
+	if !l.cfg.Enabled || !ok {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		identity := l.identify(c)
+
+		limit := limits.User
+		if identity.Anonymous {
+			limit = limits.Anonymous
+		}
+
+		allowed, retryAfter, err := l.store.Take(c.UserContext(), route+":"+identity.Key, limit)
+		if err != nil {
+			logging.For(c).Errorf("rate limit store failed: %s", err)
+
+			return c.Next()
+		}
+
+		if allowed {
+			return c.Next()
+		}
+
+		if l.onLimit != nil {
+			l.onLimit(c, route, identity)
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+		return c.Status(fiber.StatusTooManyRequests).SendString("Too many requests.")
+	}
+}
+
+// refill returns the tokens of a bucket after elapsed time, capped at the burst.
+func refill(tokens float64, elapsed time.Duration, limit config.Limit) float64 {
+	return math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+}
+
+func wait(tokens float64, limit config.Limit) time.Duration {
+	return time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+}
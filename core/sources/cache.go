@@ -14,6 +14,7 @@ type (
 		Del(key interface{})
 		Clear()
 		Wait()
+		Metrics() *ristretto.Metrics
 	}
 
 	cache struct {
@@ -26,6 +27,7 @@ func NewCache(maxCost, numCounters, bufferItems int64) Cache {
 		MaxCost:     maxCost,
 		NumCounters: numCounters,
 		BufferItems: bufferItems,
+		Metrics:     true,
 	})
 	if err != nil {
 		panic(err)
@@ -61,3 +63,7 @@ func (c *cache) Clear() {
 func (c *cache) Wait() {
 	c.c.Wait()
 }
+
+func (c *cache) Metrics() *ristretto.Metrics {
+	return c.c.Metrics
+}
@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 type (
@@ -19,12 +20,18 @@ type (
 		InsertMany(ctx context.Context, table string, documents []interface{}, opts ...*options.InsertManyOptions) error
 		Find(ctx context.Context, table string, filter interface{}, opts ...*options.FindOptions) (cur *mongo.Cursor, err error)
 		FindOne(ctx context.Context, table string, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+		FindOneAndUpdate(ctx context.Context, table string, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
 		DeleteOne(ctx context.Context, table string, filter interface{}, opts ...*options.DeleteOptions) error
 		DeleteMany(ctx context.Context, table string, filter interface{}, opts ...*options.DeleteOptions) error
 		UpdateOne(ctx context.Context, table string, filter interface{}, update interface{}, opts ...*options.UpdateOptions) error
+		UpdateMany(ctx context.Context, table string, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (int64, error)
+		ReplaceOne(ctx context.Context, table string, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) error
 		DoesExist(ctx context.Context, table string, filter bson.D, opts ...*options.FindOneOptions) (bool, error)
-		CreateIndex(ctx context.Context, table string, keys ...bson.E) (string, error)
+		CreateIndex(ctx context.Context, table string, keys bson.D, opts ...*options.IndexOptions) (string, error)
+		DropIndex(ctx context.Context, table string, name string) error
 		Count(ctx context.Context, table string, filter interface{}, opts ...*options.CountOptions) (int64, error)
+		Watch(ctx context.Context, table string, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error)
+		Ping(ctx context.Context) error
 		Disconnect(ctx context.Context) error
 		WithSession() (MongoClient, error)
 		WithTransaction(ctx context.Context, callback func(sessCtx mongo.SessionContext) (interface{}, error)) (interface{}, error)
@@ -37,10 +44,11 @@ type (
 	}
 )
 
-func NewMongoClient(ctx context.Context, uri, dbName string) MongoClient {
+func NewMongoClient(ctx context.Context, uri, dbName string, maxPoolSize uint64) MongoClient {
 	opts := options.Client()
 	opts.ApplyURI(uri)
-	opts.SetMaxPoolSize(5)
+	opts.SetMaxPoolSize(maxPoolSize)
+	opts.SetMonitor(otelmongo.NewMonitor())
 
 	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
@@ -123,6 +131,10 @@ func (mc *mongoClient) UpsertMany(ctx context.Context, table string, filter inte
 	return nil
 }
 
+func (mc *mongoClient) Ping(ctx context.Context) error {
+	return mc.cl.Ping(ctx, nil)
+}
+
 func (mc *mongoClient) Disconnect(ctx context.Context) error {
 	return mc.cl.Disconnect(ctx)
 }
@@ -135,6 +147,25 @@ func (mc *mongoClient) UpdateOne(ctx context.Context, table string, filter inter
 	return err
 }
 
+func (mc *mongoClient) ReplaceOne(ctx context.Context, table string, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) error {
+	coll := mc.getCollection(table)
+
+	_, err := coll.ReplaceOne(ctx, filter, replacement, opts...)
+
+	return err
+}
+
+func (mc *mongoClient) UpdateMany(ctx context.Context, table string, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (int64, error) {
+	coll := mc.getCollection(table)
+
+	res, err := coll.UpdateMany(ctx, filter, update, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
 func (mc *mongoClient) InsertOne(ctx context.Context, table string, document interface{}, opts ...*options.InsertOneOptions) error {
 	coll := mc.getCollection(table)
 
@@ -163,6 +194,19 @@ func (mc *mongoClient) FindOne(ctx context.Context, table string, filter interfa
 	return coll.FindOne(ctx, filter, opts...)
 }
 
+func (mc *mongoClient) FindOneAndUpdate(ctx context.Context, table string, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	coll := mc.db.Collection(table)
+
+	return coll.FindOneAndUpdate(ctx, filter, update, opts...)
+}
+
+// Watch opens a change stream on the collection, it fails on standalone servers.
+func (mc *mongoClient) Watch(ctx context.Context, table string, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	coll := mc.db.Collection(table)
+
+	return coll.Watch(ctx, pipeline, opts...)
+}
+
 func (mc *mongoClient) DoesExist(ctx context.Context, table string, filter bson.D, opts ...*options.FindOneOptions) (bool, error) {
 	result := make(bson.M)
 
@@ -180,20 +224,25 @@ func (mc *mongoClient) DoesExist(ctx context.Context, table string, filter bson.
 	return true, nil
 }
 
-func (mc *mongoClient) CreateIndex(ctx context.Context, table string, keys ...bson.E) (string, error) {
+func (mc *mongoClient) CreateIndex(ctx context.Context, table string, keys bson.D, opts ...*options.IndexOptions) (string, error) {
 	coll := mc.db.Collection(table)
-	indexKeys := make(bson.D, 0)
-	for _, key := range keys {
-		indexKeys = append(indexKeys, key)
-	}
 
-	model := mongo.IndexModel{Keys: indexKeys}
+	model := mongo.IndexModel{Keys: keys}
+	if len(opts) > 0 {
+		model.Options = opts[0]
+	}
 
 	index, err := coll.Indexes().CreateOne(ctx, model)
 
 	return index, err
 }
 
+func (mc *mongoClient) DropIndex(ctx context.Context, table string, name string) error {
+	_, err := mc.db.Collection(table).Indexes().DropOne(ctx, name)
+
+	return err
+}
+
 func (mc *mongoClient) DeleteOne(ctx context.Context, table string, filter interface{}, opts ...*options.DeleteOptions) error {
 	coll := mc.db.Collection(table)
 
@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	LocalRequestID  = "request_id"
+)
+
+var tracer = otel.Tracer("github.com/YusufOzmen01/veri-kontrol-backend")
+
+// Setup installs the global tracer provider. Without an endpoint spans are not exported anywhere.
+func Setup(ctx context.Context, cfg config.Tracing) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if len(cfg.Endpoint) == 0 {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create the otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if there is one, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Middleware assigns a request id, taken from the X-Request-ID header if the caller sent one, and starts the
+// server span. Handlers must pass c.UserContext() downstream for their spans to be attached to the request.
+func Middleware(c *fiber.Ctx) error {
+	requestID := c.Get(RequestIDHeader)
+	if len(requestID) == 0 || len(requestID) > 128 {
+		requestID = uuid.NewString()
+	}
+
+	c.Locals(LocalRequestID, requestID)
+	c.Set(RequestIDHeader, requestID)
+
+	headers := propagation.HeaderCarrier{}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		headers.Set(string(key), string(value))
+	})
+
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headers)
+	ctx, span := tracer.Start(ctx, c.Method()+" "+c.Path(), trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		semconv.HTTPMethod(c.Method()),
+		semconv.HTTPTarget(c.Path()), // query may carry the auth key of /admin/events
+		attribute.String("request_id", requestID),
+	))
+	defer span.End()
+
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+	}
+
+	span.SetName(c.Method() + " " + c.Route().Path)
+	span.SetAttributes(semconv.HTTPRoute(c.Route().Path), semconv.HTTPStatusCode(status))
+
+	if err != nil || status >= fiber.StatusInternalServerError {
+		span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+func RequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals(LocalRequestID).(string)
+
+	return requestID
+}
+
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
@@ -0,0 +1,89 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ColorInfo    = 0x2ecc71
+	ColorWarning = 0xf1c40f
+)
+
+type Message struct {
+	Content string   `json:"content,omitempty"`
+	Embeds  []*Embed `json:"embeds,omitempty"`
+}
+
+type Embed struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Color       int      `json:"color,omitempty"`
+	Fields      []*Field `json:"fields,omitempty"`
+}
+
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// Client posts to channel webhooks. NewMockClient can stand in for Discord locally.
+type Client interface {
+	Send(ctx context.Context, webhookURL string, message *Message) error
+}
+
+type client struct {
+	http *http.Client
+}
+
+func NewClient() Client {
+	return &client{
+		http: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (cl *client) Send(ctx context.Context, webhookURL string, message *Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cl.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("discord returned %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type mockClient struct{}
+
+// NewMockClient logs the messages instead of sending them.
+func NewMockClient() Client {
+	return &mockClient{}
+}
+
+func (mc *mockClient) Send(_ context.Context, _ string, message *Message) error {
+	data, _ := json.Marshal(message)
+	logrus.WithField("discord", true).Infof("discord message: %s", data)
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/logging"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/users"
+	"github.com/gofiber/fiber/v2"
+)
+
+// https://discord.com/developers/docs/interactions/receiving-and-responding
+const (
+	interactionPing    = 1
+	interactionCommand = 2
+
+	responsePong    = 1
+	responseMessage = 4
+
+	flagEphemeral = 1 << 6
+)
+
+const (
+	LevelSubmitter = "submitter"
+	LevelModerator = "moderator"
+)
+
+type Interaction struct {
+	Type   int          `json:"type"`
+	Data   *CommandData `json:"data"`
+	Member *Member      `json:"member"`
+}
+
+type CommandData struct {
+	Name     string    `json:"name"`
+	Options  []*Option `json:"options"`
+	Resolved struct {
+		Users map[string]*User `json:"users"`
+	} `json:"resolved"`
+}
+
+type Option struct {
+	Name    string      `json:"name"`
+	Value   interface{} `json:"value"`
+	Options []*Option   `json:"options"`
+}
+
+type Member struct {
+	User  *User    `json:"user"`
+	Roles []string `json:"roles"`
+}
+
+type User struct {
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Discriminator string `json:"discriminator"`
+}
+
+// Handle is how the user is stored in users.User.Discord.
+func (u *User) Handle() string {
+	if len(u.Discriminator) == 0 || u.Discriminator == "0" {
+		return u.Username
+	}
+
+	return u.Username + "#" + u.Discriminator
+}
+
+func option(options []*Option, name string) string {
+	for _, o := range options {
+		if o.Name == name {
+			if s, ok := o.Value.(string); ok {
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+var errForbidden = errors.New("you don't have a role allowed to do this")
+
+// Commands answers the /auth-key command of Discord:
+//
+//	/auth-key issue user:@volunteer name:"Ad Soyad" level:submitter|moderator
+//	/auth-key rotate user:@volunteer
+//
+// The key is only shown to the member who ran the command, who passes it to the volunteer.
+type Commands interface {
+	Interactions(c *fiber.Ctx) error
+}
+
+type commands struct {
+	cfg   config.Discord
+	users users.Repository
+}
+
+func NewCommands(cfg config.Discord, users users.Repository) Commands {
+	return &commands{
+		cfg:   cfg,
+		users: users,
+	}
+}
+
+func (cm *commands) verify(c *fiber.Ctx) bool {
+	if cm.cfg.Mock {
+		return true
+	}
+
+	key, err := hex.DecodeString(cm.cfg.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+
+	signature, err := hex.DecodeString(c.Get("X-Signature-Ed25519"))
+	if err != nil {
+		return false
+	}
+
+	message := append([]byte(c.Get("X-Signature-Timestamp")), c.Body()...)
+
+	return ed25519.Verify(key, message, signature)
+}
+
+func (cm *commands) Interactions(c *fiber.Ctx) error {
+	// Discord imzası geçersiz isteklere 401 dönülmesini şart koşuyor
+	if !cm.verify(c) {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid request signature")
+	}
+
+	interaction := &Interaction{}
+	if err := json.Unmarshal(c.Body(), interaction); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	switch interaction.Type {
+	case interactionPing:
+		return c.JSON(fiber.Map{"type": responsePong})
+	case interactionCommand:
+		content, err := cm.command(c.UserContext(), interaction)
+		if err != nil {
+			logging.For(c).Warnf("discord command failed: %s", err)
+			content = "Failed: " + err.Error()
+		}
+
+		return c.JSON(fiber.Map{
+			"type": responseMessage,
+			"data": fiber.Map{
+				"content": content,
+				"flags":   flagEphemeral,
+			},
+		})
+	default:
+		return c.Status(fiber.StatusBadRequest).SendString("unsupported interaction")
+	}
+}
+
+func (cm *commands) command(ctx context.Context, interaction *Interaction) (string, error) {
+	data := interaction.Data
+	if data == nil || data.Name != "auth-key" || len(data.Options) != 1 {
+		return "", errors.New("unknown command")
+	}
+	if interaction.Member == nil {
+		return "", errors.New("the command only works in the server")
+	}
+
+	sub := data.Options[0]
+
+	target, ok := data.Resolved.Users[option(sub.Options, "user")]
+	if !ok {
+		return "", errors.New("user is required")
+	}
+
+	switch sub.Name {
+	case "issue":
+		return cm.issue(ctx, interaction.Member, target, option(sub.Options, "name"), option(sub.Options, "level"))
+	case "rotate":
+		return cm.rotate(ctx, interaction.Member, target)
+	default:
+		return "", fmt.Errorf("unknown subcommand %s", sub.Name)
+	}
+}
+
+func (cm *commands) allowed(member *Member, permLevel int) bool {
+	roles := config.StringList(member.Roles)
+	for _, role := range cm.cfg.ModeratorRoles {
+		if roles.Contains(role) {
+			return true
+		}
+	}
+
+	if permLevel >= users.PermModerator {
+		return false
+	}
+
+	for _, role := range cm.cfg.IssuerRoles {
+		if roles.Contains(role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cm *commands) issue(ctx context.Context, member *Member, target *User, name, level string) (string, error) {
+	permLevel := users.PermSubmit
+	if level == LevelModerator {
+		permLevel = users.PermModerator
+	}
+
+	if !cm.allowed(member, permLevel) {
+		return "", errForbidden
+	}
+
+	if len(name) == 0 {
+		name = target.Username
+	}
+
+	_, err := cm.users.GetUserByDiscord(ctx, target.Handle())
+	if err == nil {
+		return "", fmt.Errorf("%s already has a key, use /auth-key rotate", target.Handle())
+	}
+	if !errors.Is(err, users.ErrNotFound) {
+		return "", err
+	}
+
+	authKey, err := cm.users.AddUser(ctx, name, target.Handle(), permLevel)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Auth-Key of %s: `%s`", target.Handle(), authKey), nil
+}
+
+func (cm *commands) rotate(ctx context.Context, member *Member, target *User) (string, error) {
+	user, err := cm.users.GetUserByDiscord(ctx, target.Handle())
+	if err != nil {
+		return "", err
+	}
+
+	if !cm.allowed(member, user.PermLevel) {
+		return "", errForbidden
+	}
+
+	authKey, err := cm.users.RotateAuthKey(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("New Auth-Key of %s: `%s`, the old one doesn't work anymore.", target.Handle(), authKey), nil
+}
@@ -0,0 +1,176 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/notifications"
+	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier posts milestone summaries, moderator queue alerts and region backlog warnings.
+type Notifier interface {
+	Run(ctx context.Context) error
+	Check(ctx context.Context) error
+}
+
+type notifier struct {
+	cfg       *config.Config
+	client    Client
+	locations locations.Repository
+	feed      tools.Feed
+	claims    notifications.Repository
+}
+
+func NewNotifier(cfg *config.Config, client Client, locations locations.Repository, feed tools.Feed, claims notifications.Repository) Notifier {
+	return &notifier{
+		cfg:       cfg,
+		client:    client,
+		locations: locations,
+		feed:      feed,
+		claims:    claims,
+	}
+}
+
+func (n *notifier) Run(ctx context.Context) error {
+	ticker := time.NewTicker(n.cfg.Discord.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := n.Check(ctx); err != nil && ctx.Err() == nil {
+			logrus.Errorf("discord notifications failed: %s", err)
+		}
+	}
+}
+
+func (n *notifier) Check(ctx context.Context) error {
+	resolved, err := n.locations.GetResolvedIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	unverified, err := n.locations.CountUnverified(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := n.milestone(ctx, len(resolved), unverified); err != nil {
+		return err
+	}
+
+	if err := n.moderatorQueue(ctx, unverified); err != nil {
+		return err
+	}
+
+	return n.regionBacklog(ctx, resolved)
+}
+
+// window is the index of the current cooldown window, an alert is posted at most once per window.
+func (n *notifier) window() int64 {
+	return time.Now().UnixNano() / int64(n.cfg.Discord.AlertCooldown)
+}
+
+func (n *notifier) post(ctx context.Context, webhookURL, claimKey string, claimValue int64, message *Message) error {
+	if len(webhookURL) == 0 {
+		return nil
+	}
+
+	claimed, err := n.claims.Claim(ctx, claimKey, claimValue)
+	if err != nil || !claimed {
+		return err
+	}
+
+	return n.client.Send(ctx, webhookURL, message)
+}
+
+func (n *notifier) milestone(ctx context.Context, resolved int, unverified int64) error {
+	step := n.cfg.Discord.MilestoneStep
+
+	milestone := resolved / step * step
+	if milestone == 0 {
+		return nil
+	}
+
+	return n.post(ctx, n.cfg.Discord.Webhooks.Milestones, "milestone", int64(milestone), &Message{
+		Embeds: []*Embed{{
+			Title:       fmt.Sprintf("%d entries resolved", milestone),
+			Description: "Thank you to every volunteer!",
+			Color:       ColorInfo,
+			Fields: []*Field{
+				{Name: "Resolved", Value: fmt.Sprint(resolved), Inline: true},
+				{Name: "Waiting for moderators", Value: fmt.Sprint(unverified), Inline: true},
+			},
+		}},
+	})
+}
+
+func (n *notifier) moderatorQueue(ctx context.Context, unverified int64) error {
+	if unverified < int64(n.cfg.Discord.ModeratorQueueThreshold) {
+		return nil
+	}
+
+	return n.post(ctx, n.cfg.Discord.Webhooks.Moderators, "moderator-queue", n.window(), &Message{
+		Embeds: []*Embed{{
+			Title:       "Moderator queue is growing",
+			Description: fmt.Sprintf("%d resolutions are waiting for verification.", unverified),
+			Color:       ColorWarning,
+		}},
+	})
+}
+
+func (n *notifier) regionBacklog(ctx context.Context, resolved map[int]bool) error {
+	if len(n.cfg.Discord.Webhooks.Backlog) == 0 {
+		return nil
+	}
+
+	feed, err := n.feed.GetAllLocations(ctx)
+	if err != nil {
+		return err
+	}
+
+	backlog := make(map[int]int)
+	for _, loc := range feed {
+		if resolved[loc.EntryID] {
+			continue
+		}
+
+		if city := n.cfg.CityOf(loc.Loc); city > 0 {
+			backlog[city]++
+		}
+	}
+
+	cities := make([]int, 0, len(backlog))
+	for city := range backlog {
+		cities = append(cities, city)
+	}
+	sort.Ints(cities)
+
+	for _, city := range cities {
+		if backlog[city] < n.cfg.Discord.RegionBacklogThreshold {
+			continue
+		}
+
+		if err := n.post(ctx, n.cfg.Discord.Webhooks.Backlog, fmt.Sprintf("backlog:%d", city), n.window(), &Message{
+			Embeds: []*Embed{{
+				Title:       fmt.Sprintf("Backlog in region %d", city),
+				Description: fmt.Sprintf("%d entries are waiting to be resolved, use city_id=%d to help.", backlog[city], city),
+				Color:       ColorWarning,
+			}},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+// Package extract pulls structured signals out of Turkish tweets with simple rules, no model is involved.
+package extract
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+)
+
+type Urgency int
+
+const (
+	UrgencyNone Urgency = iota
+	UrgencyLow
+	UrgencyMedium
+	UrgencyHigh
+	UrgencyCritical
+)
+
+var urgencyNames = []string{"none", "low", "medium", "high", "critical"}
+
+func (u Urgency) String() string {
+	if u < UrgencyNone || u > UrgencyCritical {
+		return "unknown"
+	}
+
+	return urgencyNames[u]
+}
+
+// ParseUrgency accepts a name or a number, it is used by the admin filters.
+func ParseUrgency(s string) (Urgency, bool) {
+	for i, name := range urgencyNames {
+		if s == name || s == strconv.Itoa(i) {
+			return Urgency(i), true
+		}
+	}
+
+	return UrgencyNone, false
+}
+
+// Score maps the urgency to 0..1 for the queue.
+func (u Urgency) Score() float64 {
+	return float64(u) / float64(UrgencyCritical)
+}
+
+const (
+	NeedShelter  = "shelter"
+	NeedBlanket  = "blanket"
+	NeedWater    = "water"
+	NeedFood     = "food"
+	NeedMedical  = "medical"
+	NeedHeating  = "heating"
+	NeedClothing = "clothing"
+	NeedBaby     = "baby"
+	NeedHygiene  = "hygiene"
+	NeedRescue   = "rescue"
+)
+
+type Signals struct {
+	Urgency      Urgency  `json:"urgency" bson:"urgency"`
+	UrgencyLabel string   `json:"urgency_label" bson:"urgency_label"`
+	People       int      `json:"people,omitempty" bson:"people,omitempty"`
+	Needs        []string `json:"needs" bson:"needs"`
+	Phones       []string `json:"phones" bson:"phones"`
+}
+
+// Masked hides the phone numbers for submitters and webhook subscribers, the rest of the signals are not personal.
+func (s *Signals) Masked() *Signals {
+	masked := *s
+	masked.Phones = make([]string, 0, len(s.Phones))
+	for _, phone := range s.Phones {
+		masked.Phones = append(masked.Phones, pii.MaskPhone(phone))
+	}
+
+	return &masked
+}
+
+// Anahtar kelimeler fold edilmiş halde, "enkaz altinda" hem "enkaz altında" hem "ENKAZ ALTINDA" ile eşleşir
+var urgencyPhrases = []struct {
+	phrase  string
+	urgency Urgency
+}{
+	{"ses geliyor", UrgencyCritical},
+	{"sesi geliyor", UrgencyCritical},
+	{"sesler geliyor", UrgencyCritical},
+	{"canli", UrgencyCritical},
+	{"yasiyor", UrgencyCritical},
+	{"nefes", UrgencyCritical},
+	{"enkaz altinda", UrgencyHigh},
+	{"enkazin altinda", UrgencyHigh},
+	{"mahsur", UrgencyHigh},
+	{"gocuk", UrgencyHigh},
+	{"kurtarma", UrgencyHigh},
+	{"yarali", UrgencyMedium},
+	{"ambulans", UrgencyMedium},
+	{"acil", UrgencyMedium},
+	{"yardim", UrgencyLow},
+	{"ihtiyac", UrgencyLow},
+}
+
+var needPhrases = map[string][]string{
+	NeedShelter:  {"cadir", "konteyner", "barinma", "kalacak yer"},
+	NeedBlanket:  {"battaniye", "uyku tulumu", "yorgan"},
+	NeedWater:    {"su", "suya", "suyu", "icme suyu"},
+	NeedFood:     {"gida", "yemek", "ekmek", "erzak", "sicak corba"},
+	NeedMedical:  {"ilac", "doktor", "saglik", "ambulans", "insulin", "yarali"},
+	NeedHeating:  {"soba", "isitici", "odun", "komur", "yakacak"},
+	NeedClothing: {"giysi", "kiyafet", "mont", "bot", "corap", "kazak"},
+	NeedBaby:     {"mama", "bebek bezi", "bez"},
+	NeedHygiene:  {"hijyen", "ped", "tuvalet", "sabun"},
+	NeedRescue:   {"enkaz", "kurtarma", "vinc", "is makinesi", "kepce"},
+}
+
+var numberWords = map[string]int{
+	"bir": 1, "iki": 2, "uc": 3, "dort": 4, "bes": 5,
+	"alti": 6, "yedi": 7, "sekiz": 8, "dokuz": 9, "on": 10,
+}
+
+var (
+	peopleRe = regexp.MustCompile(`\b(\d{1,3}|bir|iki|uc|dort|bes|alti|yedi|sekiz|dokuz|on)\s+(kisi|kisilik|can|insan|yarali|cocuk)\b`)
+	spaceRe  = regexp.MustCompile(`\s+`)
+)
+
+var folder = strings.NewReplacer(
+	"İ", "i", "I", "ı", "Ş", "ş", "Ğ", "ğ", "Ü", "ü", "Ö", "ö", "Ç", "ç",
+)
+
+var asciiFolder = strings.NewReplacer(
+	"ı", "i", "ş", "s", "ğ", "g", "ü", "u", "ö", "o", "ç", "c", "â", "a", "î", "i", "û", "u",
+)
+
+// Normalize lowercases with Turkish casing rules, then folds the Turkish letters to ASCII and collapses the spaces.
+func Normalize(text string) string {
+	text = strings.ToLower(folder.Replace(text))
+	text = asciiFolder.Replace(text)
+
+	return strings.TrimSpace(spaceRe.ReplaceAllString(text, " "))
+}
+
+// containsPhrase matches whole words only, "su" must not match "sus" or "suriye".
+func containsPhrase(text, phrase string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], phrase)
+		if i < 0 {
+			return false
+		}
+
+		i += start
+		end := i + len(phrase)
+
+		if (i == 0 || !isLetter(text[i-1])) && (end == len(text) || !isLetter(text[end])) {
+			return true
+		}
+
+		start = i + 1
+	}
+}
+
+// Türkçe ekleri de kabul etmek için kelime sonu kontrolü sadece başta yapılır, "cadira" da "cadir" sayılır
+func containsStem(text, stem string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], stem)
+		if i < 0 {
+			return false
+		}
+
+		i += start
+		if i == 0 || !isLetter(text[i-1]) {
+			return true
+		}
+
+		start = i + 1
+	}
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9')
+}
+
+func Extract(text string) *Signals {
+	normalized := Normalize(text)
+
+	signals := &Signals{
+		Needs:  make([]string, 0),
+		Phones: Phones(text),
+	}
+
+	for _, p := range urgencyPhrases {
+		if p.urgency > signals.Urgency && containsStem(normalized, p.phrase) {
+			signals.Urgency = p.urgency
+		}
+	}
+	signals.UrgencyLabel = signals.Urgency.String()
+
+	for need, phrases := range needPhrases {
+		for _, phrase := range phrases {
+			// Kısa kelimeler ek almış başka kelimelerle karışıyor ("su" - "suriye"), onlar tam eşleşmeli
+			matched := containsStem(normalized, phrase)
+			if len(phrase) <= 3 {
+				matched = containsPhrase(normalized, phrase)
+			}
+
+			if matched {
+				signals.Needs = append(signals.Needs, need)
+
+				break
+			}
+		}
+	}
+	sort.Strings(signals.Needs)
+
+	for _, m := range peopleRe.FindAllStringSubmatch(normalized, -1) {
+		count, err := strconv.Atoi(m[1])
+		if err != nil {
+			count = numberWords[m[1]]
+		}
+
+		signals.People += count
+	}
+
+	return signals
+}
+
+// Phones returns the phone numbers in the text as +90XXXXXXXXXX, without duplicates. Longer digit runs like TC
+// kimlik numbers are not phone numbers.
+func Phones(text string) []string {
+	return pii.Phones(text)
+}
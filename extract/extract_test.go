@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"ENKAZ ALTINDA", "enkaz altinda"},
+		{"İSKENDERUN Işıklı", "iskenderun isikli"},
+		{"Göçük  var\n\tacil ", "gocuk var acil"},
+		{"Çadır, şişe suyu", "cadir, sise suyu"},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.text); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		urgency Urgency
+		people  int
+		needs   []string
+		phones  []string
+	}{
+		{
+			name:    "nothing",
+			text:    "Antakya merkez",
+			urgency: UrgencyNone,
+			needs:   []string{},
+			phones:  []string{},
+		},
+		{
+			name:    "highest urgency wins",
+			text:    "Acil yardım, enkaz altında ses geliyor",
+			urgency: UrgencyCritical,
+			needs:   []string{NeedRescue},
+			phones:  []string{},
+		},
+		{
+			name:    "suffixed words",
+			text:    "ENKAZIN ALTINDA 3 kişi mahsur, çadıra ihtiyaç var",
+			urgency: UrgencyHigh,
+			people:  3,
+			needs:   []string{NeedRescue, NeedShelter},
+			phones:  []string{},
+		},
+		{
+			name:    "number words are summed",
+			text:    "iki çocuk ve beş kişi yaralı, ilaç lazım",
+			urgency: UrgencyMedium,
+			people:  7,
+			needs:   []string{NeedMedical},
+			phones:  []string{},
+		},
+		{
+			name:    "short needs match whole words only",
+			text:    "Suriye sınırında sus dediler, su lazım",
+			urgency: UrgencyNone,
+			needs:   []string{NeedWater},
+			phones:  []string{},
+		},
+		{
+			name:    "no water in suriye",
+			text:    "Suriye sınırı",
+			urgency: UrgencyNone,
+			needs:   []string{},
+			phones:  []string{},
+		},
+		{
+			name:    "phones",
+			text:    "Ulaşın: 0532 123 45 67 veya +90 (532) 123-45-67",
+			urgency: UrgencyNone,
+			needs:   []string{},
+			phones:  []string{"+905321234567"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(tt.text)
+
+			if got.Urgency != tt.urgency || got.UrgencyLabel != tt.urgency.String() {
+				t.Errorf("Extract(%q) urgency = %s (%s), want %s", tt.text, got.Urgency, got.UrgencyLabel, tt.urgency)
+			}
+			if got.People != tt.people {
+				t.Errorf("Extract(%q) people = %d, want %d", tt.text, got.People, tt.people)
+			}
+			if !reflect.DeepEqual(got.Needs, tt.needs) {
+				t.Errorf("Extract(%q) needs = %v, want %v", tt.text, got.Needs, tt.needs)
+			}
+			if !reflect.DeepEqual(got.Phones, tt.phones) {
+				t.Errorf("Extract(%q) phones = %v, want %v", tt.text, got.Phones, tt.phones)
+			}
+		})
+	}
+}
+
+func TestParseUrgency(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Urgency
+		ok   bool
+	}{
+		{"critical", UrgencyCritical, true},
+		{"2", UrgencyMedium, true},
+		{"0", UrgencyNone, true},
+		{"urgent", UrgencyNone, false},
+		{"5", UrgencyNone, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseUrgency(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseUrgency(%q) = %s, %t, want %s, %t", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMasked(t *testing.T) {
+	signals := &Signals{Urgency: UrgencyHigh, Needs: []string{NeedWater}, Phones: []string{"+905321234567"}}
+
+	masked := signals.Masked()
+	if masked.Phones[0] == signals.Phones[0] {
+		t.Errorf("Masked() phone = %s, want it masked", masked.Phones[0])
+	}
+	if signals.Phones[0] != "+905321234567" {
+		t.Errorf("Masked() changed the original phones: %v", signals.Phones)
+	}
+	if masked.Urgency != signals.Urgency || !reflect.DeepEqual(masked.Needs, signals.Needs) {
+		t.Errorf("Masked() = %+v, want the other signals kept", masked)
+	}
+}
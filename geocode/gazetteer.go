@@ -0,0 +1,341 @@
+// Package geocode finds coordinates of typed addresses and the mahalle of coordinates from a local gazetteer, no
+// outside service is called.
+package geocode
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/address"
+)
+
+// Place is a row of the gazetteer, a mahalle centroid when Street is empty.
+type Place struct {
+	Il      string  `json:"il" bson:"il"`
+	Ilce    string  `json:"ilce" bson:"ilce"`
+	Mahalle string  `json:"mahalle" bson:"mahalle"`
+	Street  string  `json:"street,omitempty" bson:"street,omitempty"`
+	Lat     float64 `json:"lat" bson:"lat"`
+	Lng     float64 `json:"lng" bson:"lng"`
+}
+
+const (
+	LevelStreet  = "street"
+	LevelMahalle = "mahalle"
+)
+
+type Match struct {
+	*Place `bson:",inline"`
+	Level  string  `json:"level" bson:"level"`
+	Score  float64 `json:"score" bson:"score"`
+	// DistanceKm is only set by Reverse.
+	DistanceKm float64 `json:"distance_km,omitempty" bson:"distance_km,omitempty"`
+}
+
+type Gazetteer interface {
+	// Forward returns the best matches of a typed address, best first.
+	Forward(query string, limit int) []*Match
+	// Reverse returns the nearest mahalle, nil when it is farther than maxDistanceKm.
+	Reverse(lat, lng, maxDistanceKm float64) *Match
+	Len() int
+}
+
+// Parts of the score of a forward match, at most 1.
+const (
+	scoreMahalle      = 0.6
+	scoreGuessMahalle = 0.45
+	scoreIlce         = 0.1
+	scoreIl           = 0.05
+	scoreStreet       = 0.25
+)
+
+type mahalle struct {
+	*Place
+	ilKey   string
+	ilceKey string
+	streets map[string]*Place
+}
+
+type gazetteer struct {
+	mahalleler []*mahalle
+	byName     map[string][]*mahalle
+	places     int
+}
+
+// NewGazetteer indexes the places. A mahalle without its own centroid row gets the mean of its streets.
+func NewGazetteer(places []*Place) Gazetteer {
+	g := &gazetteer{
+		byName: make(map[string][]*mahalle),
+		places: len(places),
+	}
+
+	groups := make(map[string]*mahalle)
+	sums := make(map[string][]float64)
+
+	for _, p := range places {
+		key := strings.Join([]string{address.Key(p.Il), address.Key(p.Ilce), address.Key(p.Mahalle)}, "|")
+
+		m, ok := groups[key]
+		if !ok {
+			m = &mahalle{
+				Place:   &Place{Il: p.Il, Ilce: p.Ilce, Mahalle: p.Mahalle},
+				ilKey:   address.Key(p.Il),
+				ilceKey: address.Key(p.Ilce),
+				streets: make(map[string]*Place),
+			}
+			groups[key] = m
+			sums[key] = []float64{0, 0, 0}
+
+			g.mahalleler = append(g.mahalleler, m)
+			name := address.Key(p.Mahalle)
+			g.byName[name] = append(g.byName[name], m)
+		}
+
+		if len(p.Street) > 0 {
+			m.streets[address.Key(p.Street)] = p
+			sums[key][0] += p.Lat
+			sums[key][1] += p.Lng
+			sums[key][2]++
+		} else {
+			m.Lat, m.Lng = p.Lat, p.Lng
+			sums[key][2] = -1
+		}
+	}
+
+	for key, m := range groups {
+		if sum := sums[key]; sum[2] > 0 {
+			m.Lat, m.Lng = sum[0]/sum[2], sum[1]/sum[2]
+		}
+	}
+
+	return g
+}
+
+func (g *gazetteer) Len() int {
+	return g.places
+}
+
+func (g *gazetteer) Forward(query string, limit int) []*Match {
+	parsed := address.Parse(query)
+	ilKey, ilceKey := address.Key(parsed.Il), address.Key(parsed.Ilce)
+
+	base := scoreMahalle
+	candidates := g.byName[address.Key(parsed.Mahalle)]
+
+	// "Mah." yazılmamışsa sorgudaki kelimelerden mahalle adı arıyoruz, önce iki kelimelik adlar
+	if len(parsed.Mahalle) == 0 {
+		base = scoreGuessMahalle
+		words := strings.Fields(address.Key(query))
+
+		for n := 2; n >= 1 && len(candidates) == 0; n-- {
+			for i := 0; i+n <= len(words) && len(candidates) == 0; i++ {
+				name := strings.Join(words[i:i+n], " ")
+				candidates = g.byName[name]
+
+				// "Yenişehir" hem ilçe hem mahalle adı, mahalle diye aldığımız kelimeden çıkarılan il ve ilçeyi kullanmıyoruz
+				if len(candidates) > 0 && name == ilceKey {
+					ilKey, ilceKey = "", ""
+				}
+			}
+		}
+	}
+
+	streets := make([]string, 0, 2)
+	for _, street := range []string{parsed.Sokak, parsed.Cadde} {
+		if len(street) > 0 {
+			streets = append(streets, address.Key(street))
+		}
+	}
+
+	matches := make([]*Match, 0, len(candidates))
+	for _, m := range candidates {
+		if (len(ilKey) > 0 && ilKey != m.ilKey) || (len(ilceKey) > 0 && ilceKey != m.ilceKey) {
+			continue
+		}
+
+		match := &Match{Place: m.Place, Level: LevelMahalle, Score: base}
+		if len(ilceKey) > 0 {
+			match.Score += scoreIlce
+		}
+		if len(ilKey) > 0 {
+			match.Score += scoreIl
+		}
+
+		// Sokak caddeden daha kesin, önce o aranır
+		for _, street := range streets {
+			if p, ok := m.streets[street]; ok {
+				match.Place = p
+				match.Level = LevelStreet
+				match.Score += scoreStreet
+
+				break
+			}
+		}
+
+		matches = append(matches, match)
+	}
+
+	// Aynı adda birden fazla mahalle kaldıysa hangisi olduğu belli değil
+	for _, match := range matches {
+		match.Score = math.Round(math.Min(match.Score, 1)/float64(len(matches))*100) / 100
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches
+}
+
+func (g *gazetteer) Reverse(lat, lng, maxDistanceKm float64) *Match {
+	var nearest *mahalle
+	best := math.Inf(1)
+
+	for _, m := range g.mahalleler {
+		if d := distanceKm(lat, lng, m.Lat, m.Lng); d < best {
+			nearest, best = m, d
+		}
+	}
+
+	if nearest == nil || best > maxDistanceKm {
+		return nil
+	}
+
+	return &Match{
+		Place:      nearest.Place,
+		Level:      LevelMahalle,
+		Score:      math.Round((1-best/maxDistanceKm)*100) / 100,
+		DistanceKm: math.Round(best*1000) / 1000,
+	}
+}
+
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * 6371 * math.Asin(math.Sqrt(h))
+}
+
+// Load reads a gazetteer CSV, gzipped when the path ends with .gz.
+func Load(path string) (Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		r = gz
+	}
+
+	places, err := ReadCSV(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return NewGazetteer(places), nil
+}
+
+var columnNames = map[string][]string{
+	"il":      {"il", "province"},
+	"ilce":    {"ilce", "ilçe", "district"},
+	"mahalle": {"mahalle", "neighbourhood", "neighborhood"},
+	"street":  {"street", "sokak", "cadde"},
+	"lat":     {"lat", "latitude"},
+	"lng":     {"lng", "lon", "longitude"},
+}
+
+// ReadCSV reads the places of a CSV with a header row, the street column is optional. OSM extracts can be converted
+// to it with the centroids of the admin_level=8 boundaries and the named highways.
+func ReadCSV(r io.Reader) ([]*Place, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for column, names := range columnNames {
+			for _, n := range names {
+				if n == name {
+					columns[column] = i
+				}
+			}
+		}
+	}
+
+	for _, column := range []string{"il", "ilce", "mahalle", "lat", "lng"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("missing column %q", column)
+		}
+	}
+
+	get := func(rec []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+
+		return strings.TrimSpace(rec[i])
+	}
+
+	places := make([]*Place, 0)
+	for line := 2; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		lat, err := strconv.ParseFloat(get(rec, "lat"), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("line %d: invalid latitude %q", line, get(rec, "lat"))
+		}
+
+		lng, err := strconv.ParseFloat(get(rec, "lng"), 64)
+		if err != nil || lng < -180 || lng > 180 {
+			return nil, fmt.Errorf("line %d: invalid longitude %q", line, get(rec, "lng"))
+		}
+
+		if len(get(rec, "mahalle")) == 0 {
+			return nil, fmt.Errorf("line %d: mahalle is empty", line)
+		}
+
+		places = append(places, &Place{
+			Il:      get(rec, "il"),
+			Ilce:    get(rec, "ilce"),
+			Mahalle: get(rec, "mahalle"),
+			Street:  get(rec, "street"),
+			Lat:     lat,
+			Lng:     lng,
+		})
+	}
+
+	return places, nil
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/events"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Events interface {
+	// Stream is the SSE endpoint, it only sends the events of the project of the request. ?events=resolution,admin_update,
+	// ?region=1,2 (region ids of the project), ?category= (category ids) and ?type=1,2 (legacy entry types) filter the
+	// stream.
+	Stream(c *fiber.Ctx) error
+	// Stop closes the open streams, the HTTP server waits for them otherwise.
+	Stop(ctx context.Context) error
+}
+
+type eventStream struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	bus       events.Bus
+	heartbeat time.Duration
+	// project returns the project the caller is a moderator of
+	project func(c *fiber.Ctx) string
+}
+
+func NewEvents(bus events.Bus, heartbeat time.Duration, project func(c *fiber.Ctx) string) Events {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &eventStream{
+		ctx:       ctx,
+		cancel:    cancel,
+		bus:       bus,
+		heartbeat: heartbeat,
+		project:   project,
+	}
+}
+
+func (es *eventStream) Stream(c *fiber.Ctx) error {
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	filter.Projects = []string{es.project(c)}
+
+	sub := es.bus.Subscribe(filter)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		ticker := time.NewTicker(es.heartbeat)
+		defer ticker.Stop()
+
+		fmt.Fprint(w, "retry: 5000\n\n")
+
+		for {
+			// Yazma hatası bağlantının koptuğunu gösterir
+			if err := w.Flush(); err != nil {
+				return
+			}
+
+			select {
+			case <-es.ctx.Done():
+				return
+			case e, ok := <-sub.C:
+				if !ok {
+					return
+				}
+
+				data, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			case <-ticker.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+		}
+	})
+
+	return nil
+}
+
+func (es *eventStream) Stop(_ context.Context) error {
+	es.cancel()
+
+	return nil
+}
+
+func parseEventFilter(c *fiber.Ctx) (*events.Filter, error) {
+	filter := &events.Filter{}
+
+	for _, t := range splitQuery(c.Query("events")) {
+		filter.Types = append(filter.Types, events.Type(t))
+	}
+
+	regions, err := parseInts(c.Query("region"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid region: %w", err)
+	}
+	filter.Regions = regions
+
+	filter.Categories = splitQuery(c.Query("category"))
+
+	entryTypes, err := parseInts(c.Query("type"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid type: %w", err)
+	}
+	filter.EntryTypes = entryTypes
+
+	return filter, nil
+}
+
+func splitQuery(query string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(query, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func parseInts(query string) ([]int, error) {
+	ints := make([]int, 0)
+	for _, item := range splitQuery(query) {
+		i, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+
+		ints = append(ints, i)
+	}
+
+	return ints, nil
+}
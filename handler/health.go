@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+type Check struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string                  `json:"status"`
+	Checks map[string]*CheckResult `json:"checks,omitempty"`
+}
+
+type Health interface {
+	Livez(c *fiber.Ctx) error
+	Readyz(c *fiber.Ctx) error
+}
+
+type health struct {
+	checks  []*Check
+	timeout time.Duration
+}
+
+func NewHealth(timeout time.Duration, checks ...*Check) Health {
+	return &health{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Livez only tells that the process is able to serve requests, dependencies are not checked.
+func (h *health) Livez(c *fiber.Ctx) error {
+	return c.JSON(&HealthResponse{Status: StatusOK})
+}
+
+// Readyz fails with 503 when a critical dependency is down, non critical failures only degrade the status.
+func (h *health) Readyz(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), h.timeout)
+	defer cancel()
+
+	response := &HealthResponse{
+		Status: StatusOK,
+		Checks: make(map[string]*CheckResult),
+	}
+
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+
+	for _, check := range h.checks {
+		wg.Add(1)
+
+		go func(check *Check) {
+			defer wg.Done()
+
+			started := time.Now()
+			err := check.Check(ctx)
+
+			result := &CheckResult{
+				Status:    StatusOK,
+				Critical:  check.Critical,
+				LatencyMs: float64(time.Since(started).Microseconds()) / 1000,
+			}
+
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			response.Checks[check.Name] = result
+
+			if err == nil {
+				return
+			}
+
+			if check.Critical {
+				response.Status = StatusFail
+			} else if response.Status == StatusOK {
+				response.Status = StatusDegraded
+			}
+		}(check)
+	}
+
+	wg.Wait()
+
+	if response.Status == StatusFail {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+func MongoCheck(mongo sources.MongoClient) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return mongo.Ping(ctx)
+	}
+}
+
+// FeedCheck refreshes the feed through the cache if the last successful fetch is older than maxAge.
+func FeedCheck(feed tools.Feed, maxAge time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if time.Since(feed.LastFetch()) <= maxAge {
+			return nil
+		}
+
+		if _, err := feed.GetAllLocations(ctx); err != nil {
+			return err
+		}
+
+		last := feed.LastFetch()
+		if last.IsZero() {
+			return fmt.Errorf("feed was never fetched successfully")
+		}
+
+		if age := time.Since(last); age > maxAge {
+			return fmt.Errorf("last successful feed fetch was %s ago", age.Round(time.Second))
+		}
+
+		return nil
+	}
+}
+
+func CacheCheck(cache sources.Cache) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		key := fmt.Sprintf("readyz_%d", time.Now().UnixNano())
+
+		if !cache.SetWithTTL(key, true, 1, time.Minute) {
+			return fmt.Errorf("cache rejected the write")
+		}
+		cache.Wait()
+
+		if _, ok := cache.Get(key); !ok {
+			return fmt.Errorf("cache lost the written key")
+		}
+
+		cache.Del(key)
+
+		return nil
+	}
+}
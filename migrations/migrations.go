@@ -0,0 +1,213 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collection = "schema_migrations"
+
+// Migration is a single schema change. Up must be safe to run again if it fails halfway.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, mongo sources.MongoClient) error
+}
+
+type Record struct {
+	Version     int       `json:"version" bson:"_id"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"applied_at" bson:"applied_at"`
+}
+
+type Status struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"applied_at"`
+}
+
+// Migrations must stay ordered by version, never change or remove an applied migration, add a new one instead.
+var Migrations = []*Migration{
+	{
+		Version:     1,
+		Description: "unique index on locations.entry_id",
+		Up:          uniqueEntryIDIndex,
+	},
+	{
+		Version:     2,
+		Description: "hashed index on locations.tweet_contents",
+		Up:          tweetContentsIndex,
+	},
+	{
+		Version:     3,
+		Description: "index on users.auth_key_hash",
+		Up:          authKeyHashIndex,
+	},
+	{
+		Version:     4,
+		Description: "backfill locations.geo and add 2dsphere index",
+		Up:          geoIndex,
+	},
+	{
+		Version:     5,
+		Description: "backfill default fields of old locations",
+		Up:          locationDefaults,
+	},
+	{
+		Version:     6,
+		Description: "ttl indexes for rate limit buckets and hits",
+		Up:          rateLimitIndexes,
+	},
+	{
+		Version:     7,
+		Description: "index on locations.updated_at for the event poller",
+		Up:          updatedAtIndex,
+	},
+	{
+		Version:     8,
+		Description: "indexes of the webhook outbox",
+		Up:          webhookIndexes,
+	},
+	{
+		Version:     9,
+		Description: "index on users.discord for the /auth-key command",
+		Up:          discordIndex,
+	},
+	{
+		Version:     10,
+		Description: "indexes of skips",
+		Up:          skipIndexes,
+	},
+	{
+		Version:     11,
+		Description: "index on queue_leases.lease_until",
+		Up:          leaseIndex,
+	},
+	{
+		Version:     12,
+		Description: "indexes on the extracted signals of locations",
+		Up:          signalIndexes,
+	},
+	{
+		Version:     13,
+		Description: "index on locations.tweet_contents_hash and indexes of the audit log",
+		Up:          piiIndexes,
+	},
+	{
+		Version:     14,
+		Description: "indexes on locations.signals.phones and locations.sender._id for subject requests",
+		Up:          subjectIndexes,
+	},
+	{
+		Version:     15,
+		Description: "index on the il, ilçe and mahalle of locations.parsed_address",
+		Up:          parsedAddressIndex,
+	},
+	{
+		Version:     16,
+		Description: "move locations and skips into the default project and make entry ids unique per project",
+		Up:          projectScope,
+	},
+	{
+		Version:     17,
+		Description: "category registry with the two legacy location types, categories on locations and projects",
+		Up:          legacyCategories,
+	},
+	{
+		Version:     18,
+		Description: "key queue leases and signals by project and entry id",
+		Up:          queueProjectScope,
+	},
+	{
+		Version:     19,
+		Description: "legacy categories don't require open_address and apartment, the current frontend sends them empty",
+		Up:          legacyRequiredFields,
+	},
+	{
+		Version:     20,
+		Description: "webhook endpoints filter by category instead of the legacy type",
+		Up:          webhookCategories,
+	},
+}
+
+func applied(ctx context.Context, mongo sources.MongoClient) (map[int]*Record, error) {
+	cur, err := mongo.Find(ctx, collection, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0)
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	result := make(map[int]*Record)
+	for _, record := range records {
+		result[record.Version] = record
+	}
+
+	return result, nil
+}
+
+// Run applies every migration that isn't recorded in schema_migrations yet, in order.
+func Run(ctx context.Context, mongoClient sources.MongoClient) error {
+	done, err := applied(ctx, mongoClient)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range Migrations {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+
+		logrus.Infof("Applying migration %d: %s", m.Version, m.Description)
+
+		started := time.Now()
+		if err := m.Up(ctx, mongoClient); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+
+		if err := mongoClient.InsertOne(ctx, collection, &Record{
+			Version:     m.Version,
+			Description: m.Description,
+			AppliedAt:   time.Now(),
+		}); err != nil && !mongo.IsDuplicateKeyError(err) {
+			// Başka bir replika aynı anda uygulamış olabilir
+			return err
+		}
+
+		logrus.Infof("Applied migration %d in %s", m.Version, time.Since(started).Round(time.Millisecond))
+	}
+
+	return nil
+}
+
+func GetStatus(ctx context.Context, mongo sources.MongoClient) ([]*Status, error) {
+	done, err := applied(ctx, mongo)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*Status, 0, len(Migrations))
+	for _, m := range Migrations {
+		status := &Status{
+			Version:     m.Version,
+			Description: m.Description,
+		}
+
+		if record, ok := done[m.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
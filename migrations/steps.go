@@ -0,0 +1,556 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/categories"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mongo'nun IndexNotFound hata kodu
+const indexNotFound = 27
+
+// duplicatesCollection keeps the documents moved aside by uniqueEntryIDIndex for a manual review.
+const duplicatesCollection = "locations_duplicates"
+
+// uniqueEntryIDIndex keeps the newest document of an entry id and moves the others to duplicatesCollection, the
+// unique index can't be built and the later migrations can't run otherwise. Databases without duplicates are migrated
+// as before.
+func uniqueEntryIDIndex(ctx context.Context, mongo sources.MongoClient) error {
+	cur, err := mongo.Aggregate(ctx, "locations", bson.A{
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$entry_id"},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+		}}},
+		bson.D{{Key: "$match", Value: bson.D{{Key: "ids.1", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	duplicates := make([]struct {
+		EntryID int           `bson:"_id"`
+		IDs     []interface{} `bson:"ids"`
+	}, 0)
+	if err := cur.All(ctx, &duplicates); err != nil {
+		return err
+	}
+
+	moved := 0
+	for _, duplicate := range duplicates {
+		// İlki en yeni kayıt, diğerleri silinmeden önce kopyalanıyor, yarıda kalırsa tekrar çalıştırılabilir
+		older := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: duplicate.IDs[1:]}}}}
+
+		docs, err := mongo.Find(ctx, "locations", older)
+		if err != nil {
+			return err
+		}
+
+		for docs.Next(ctx) {
+			doc := docs.Current
+			if err := mongo.ReplaceOne(ctx, duplicatesCollection, bson.D{{Key: "_id", Value: doc.Lookup("_id")}}, doc, options.Replace().SetUpsert(true)); err != nil {
+				docs.Close(ctx)
+
+				return err
+			}
+		}
+		if err := docs.Err(); err != nil {
+			return err
+		}
+		docs.Close(ctx)
+
+		if err := mongo.DeleteMany(ctx, "locations", older); err != nil {
+			return err
+		}
+
+		moved += len(duplicate.IDs) - 1
+	}
+
+	if moved > 0 {
+		logrus.Warnf("Moved %d duplicate documents of %d entry ids to %s, the newest one of each entry is kept", moved, len(duplicates), duplicatesCollection)
+	}
+
+	_, err = mongo.CreateIndex(ctx, "locations", bson.D{{Key: "entry_id", Value: 1}}, options.Index().
+		SetName("entry_id_unique").
+		SetUnique(true))
+
+	return err
+}
+
+func tweetContentsIndex(ctx context.Context, mongo sources.MongoClient) error {
+	_, err := mongo.CreateIndex(ctx, "locations", bson.D{{Key: "tweet_contents", Value: "hashed"}}, options.Index().
+		SetName("tweet_contents_hashed"))
+
+	return err
+}
+
+func authKeyHashIndex(ctx context.Context, mongo sources.MongoClient) error {
+	_, err := mongo.CreateIndex(ctx, "users", bson.D{{Key: "auth_key_hash", Value: 1}}, options.Index().
+		SetName("auth_key_hash"))
+
+	return err
+}
+
+func geoIndex(ctx context.Context, mongo sources.MongoClient) error {
+	cur, err := mongo.Find(ctx, "locations", bson.D{{
+		Key:   "geo",
+		Value: bson.D{{Key: "$exists", Value: false}},
+	}}, options.Find().SetProjection(bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "location", Value: 1},
+	}))
+	if err != nil {
+		return err
+	}
+
+	docs := make([]*locations.LocationDB, 0)
+	if err := cur.All(ctx, &docs); err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		geo := locations.NewGeoPoint(doc.Location)
+		if geo == nil {
+			continue
+		}
+
+		if err := mongo.UpdateOne(ctx, "locations", bson.D{{Key: "_id", Value: doc.ID}}, bson.D{{
+			Key:   "$set",
+			Value: bson.D{{Key: "geo", Value: geo}},
+		}}); err != nil {
+			return err
+		}
+	}
+
+	_, err = mongo.CreateIndex(ctx, "locations", bson.D{{Key: "geo", Value: "2dsphere"}}, options.Index().
+		SetName("geo_2dsphere"))
+
+	return err
+}
+
+func locationDefaults(ctx context.Context, mongo sources.MongoClient) error {
+	defaults := bson.D{
+		{Key: "verified", Value: false},
+		{Key: "corrected", Value: false},
+		{Key: "type", Value: 0},
+		{Key: "reason", Value: ""},
+		{Key: "open_address", Value: ""},
+		{Key: "apartment", Value: ""},
+		{Key: "tweet_contents", Value: ""},
+	}
+
+	for _, field := range defaults {
+		if _, err := mongo.UpdateMany(ctx, "locations", bson.D{{
+			Key:   field.Key,
+			Value: bson.D{{Key: "$exists", Value: false}},
+		}}, bson.D{{
+			Key:   "$set",
+			Value: bson.D{field},
+		}}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rateLimitIndexes(ctx context.Context, mongo sources.MongoClient) error {
+	if _, err := mongo.CreateIndex(ctx, "rate_limits", bson.D{{Key: "expires_at", Value: 1}}, options.Index().
+		SetName("expires_at_ttl").
+		SetExpireAfterSeconds(0)); err != nil {
+		return err
+	}
+
+	if _, err := mongo.CreateIndex(ctx, "rate_limit_hits", bson.D{
+		{Key: "route", Value: 1},
+		{Key: "key", Value: 1},
+		{Key: "minute", Value: 1},
+	}, options.Index().SetName("route_key_minute").SetUnique(true)); err != nil {
+		return err
+	}
+
+	// Moderatörlerin son 30 günü görmesi yeterli
+	_, err := mongo.CreateIndex(ctx, "rate_limit_hits", bson.D{{Key: "minute", Value: 1}}, options.Index().
+		SetName("minute_ttl").
+		SetExpireAfterSeconds(int32((30 * 24 * time.Hour).Seconds())))
+
+	return err
+}
+
+func updatedAtIndex(ctx context.Context, mongo sources.MongoClient) error {
+	_, err := mongo.CreateIndex(ctx, "locations", bson.D{{Key: "updated_at", Value: 1}}, options.Index().SetName("updated_at"))
+
+	return err
+}
+
+func webhookIndexes(ctx context.Context, mongo sources.MongoClient) error {
+	if _, err := mongo.CreateIndex(ctx, "webhook_deliveries", bson.D{{Key: "dedupe_key", Value: 1}}, options.Index().
+		SetName("dedupe_key").
+		SetUnique(true)); err != nil {
+		return err
+	}
+
+	if _, err := mongo.CreateIndex(ctx, "webhook_deliveries", bson.D{
+		{Key: "status", Value: 1},
+		{Key: "next_attempt_at", Value: 1},
+	}, options.Index().SetName("status_next_attempt_at")); err != nil {
+		return err
+	}
+
+	_, err := mongo.CreateIndex(ctx, "webhook_deliveries", bson.D{
+		{Key: "endpoint_id", Value: 1},
+		{Key: "created_at", Value: -1},
+	}, options.Index().SetName("endpoint_id_created_at"))
+
+	return err
+}
+
+func discordIndex(ctx context.Context, mongo sources.MongoClient) error {
+	_, err := mongo.CreateIndex(ctx, "users", bson.D{{Key: "discord", Value: 1}}, options.Index().SetName("discord"))
+
+	return err
+}
+
+func skipIndexes(ctx context.Context, mongo sources.MongoClient) error {
+	if _, err := mongo.CreateIndex(ctx, "skips", bson.D{
+		{Key: "entry_id", Value: 1},
+		{Key: "user_id", Value: 1},
+	}, options.Index().SetName("entry_id_user_id").SetUnique(true)); err != nil {
+		return err
+	}
+
+	_, err := mongo.CreateIndex(ctx, "skips", bson.D{{Key: "user_id", Value: 1}}, options.Index().SetName("user_id"))
+
+	return err
+}
+
+func leaseIndex(ctx context.Context, mongo sources.MongoClient) error {
+	_, err := mongo.CreateIndex(ctx, "queue_leases", bson.D{{Key: "lease_until", Value: 1}}, options.Index().SetName("lease_until"))
+
+	return err
+}
+
+func signalIndexes(ctx context.Context, mongo sources.MongoClient) error {
+	if _, err := mongo.CreateIndex(ctx, "locations", bson.D{{Key: "signals.urgency", Value: 1}}, options.Index().
+		SetName("signals_urgency")); err != nil {
+		return err
+	}
+
+	_, err := mongo.CreateIndex(ctx, "locations", bson.D{{Key: "signals.needs", Value: 1}}, options.Index().SetName("signals_needs"))
+
+	return err
+}
+
+func piiIndexes(ctx context.Context, mongo sources.MongoClient) error {
+	if _, err := mongo.CreateIndex(ctx, "locations", bson.D{{Key: "tweet_contents_hash", Value: 1}}, options.Index().
+		SetName("tweet_contents_hash")); err != nil {
+		return err
+	}
+
+	if _, err := mongo.CreateIndex(ctx, "audit_log", bson.D{
+		{Key: "user_id", Value: 1},
+		{Key: "created_at", Value: -1},
+	}, options.Index().SetName("user_id_created_at")); err != nil {
+		return err
+	}
+
+	_, err := mongo.CreateIndex(ctx, "audit_log", bson.D{
+		{Key: "entry_id", Value: 1},
+		{Key: "created_at", Value: -1},
+	}, options.Index().SetName("entry_id_created_at"))
+
+	return err
+}
+
+func subjectIndexes(ctx context.Context, mongo sources.MongoClient) error {
+	if _, err := mongo.CreateIndex(ctx, "locations", bson.D{{Key: "signals.phones", Value: 1}}, options.Index().
+		SetName("signals_phones")); err != nil {
+		return err
+	}
+
+	_, err := mongo.CreateIndex(ctx, "locations", bson.D{
+		{Key: "sender._id", Value: 1},
+		{Key: "updated_at", Value: 1},
+	}, options.Index().SetName("sender_id_updated_at"))
+
+	return err
+}
+
+func parsedAddressIndex(ctx context.Context, mongo sources.MongoClient) error {
+	_, err := mongo.CreateIndex(ctx, "locations", bson.D{
+		{Key: "parsed_address.il", Value: 1},
+		{Key: "parsed_address.ilce", Value: 1},
+		{Key: "parsed_address.mahalle", Value: 1},
+	}, options.Index().SetName("parsed_address_il_ilce_mahalle"))
+
+	return err
+}
+
+func projectScope(ctx context.Context, mongo sources.MongoClient) error {
+	for _, table := range []string{"locations", "skips"} {
+		if _, err := mongo.UpdateMany(ctx, table, bson.D{{Key: "project_id", Value: bson.D{{Key: "$exists", Value: false}}}}, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "project_id", Value: projects.DefaultID}}},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := mongo.CreateIndex(ctx, "locations", bson.D{
+		{Key: "project_id", Value: 1},
+		{Key: "entry_id", Value: 1},
+	}, options.Index().SetName("project_id_entry_id_unique").SetUnique(true)); err != nil {
+		return err
+	}
+
+	if err := dropIndex(ctx, mongo, "locations", "entry_id_unique"); err != nil {
+		return err
+	}
+
+	if _, err := mongo.CreateIndex(ctx, "skips", bson.D{
+		{Key: "project_id", Value: 1},
+		{Key: "entry_id", Value: 1},
+		{Key: "user_id", Value: 1},
+	}, options.Index().SetName("project_id_entry_id_user_id").SetUnique(true)); err != nil {
+		return err
+	}
+
+	return dropIndex(ctx, mongo, "skips", "entry_id_user_id")
+}
+
+// dropIndex ignores indexes that are already gone so the migration can be re-run after a partial failure.
+func dropIndex(ctx context.Context, mongo sources.MongoClient, table, name string) error {
+	err := mongo.DropIndex(ctx, table, name)
+
+	var cmdErr mongoDriver.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == indexNotFound {
+		return nil
+	}
+
+	return err
+}
+
+func legacyCategories(ctx context.Context, mongo sources.MongoClient) error {
+	legacy := []struct {
+		ID       string
+		Type     int
+		Names    map[string]string
+		Color    string
+		Required []string
+	}{
+		{categories.Wreckage, locations.TypeWreckage, map[string]string{"tr": "Enkaz", "en": "Wreckage"}, "#d32f2f", []string{"open_address", "apartment"}},
+		{categories.SupplyHelp, locations.TypeSupplyHelp, map[string]string{"tr": "Malzeme Yardımı", "en": "Supply help"}, "#1976d2", []string{"open_address"}},
+	}
+
+	for i, category := range legacy {
+		now := time.Now()
+
+		// Tekrar çalıştırılırsa admin'in yaptığı değişikliklerin üzerine yazmıyoruz
+		if err := mongo.UpdateOne(ctx, "categories", bson.D{{Key: "_id", Value: category.ID}}, bson.D{
+			{Key: "$setOnInsert", Value: bson.D{
+				{Key: "legacy_type", Value: category.Type},
+				{Key: "names", Value: category.Names},
+				{Key: "color", Value: category.Color},
+				{Key: "required_fields", Value: category.Required},
+				{Key: "order", Value: i + 1},
+				{Key: "status", Value: categories.StatusActive},
+				{Key: "created_at", Value: now},
+				{Key: "updated_at", Value: now},
+			}},
+		}, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+
+		if _, err := mongo.UpdateMany(ctx, "locations", bson.D{
+			{Key: "type", Value: category.Type},
+			{Key: "category", Value: bson.D{{Key: "$exists", Value: false}}},
+		}, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "category", Value: category.ID}}},
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Projeler tip yerine kategori listesi tutuyor
+	cur, err := mongo.Find(ctx, "projects", bson.D{{Key: "types", Value: bson.D{{Key: "$exists", Value: true}}}})
+	if err != nil {
+		return err
+	}
+
+	projects := make([]struct {
+		ID    string `bson:"_id"`
+		Types []int  `bson:"types"`
+	}, 0)
+	if err := cur.All(ctx, &projects); err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		ids := make([]string, 0, len(project.Types))
+		for _, t := range project.Types {
+			for _, category := range legacy {
+				if category.Type == t {
+					ids = append(ids, category.ID)
+				}
+			}
+		}
+
+		if err := mongo.UpdateOne(ctx, "projects", bson.D{{Key: "_id", Value: project.ID}}, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "categories", Value: ids}}},
+			{Key: "$unset", Value: bson.D{{Key: "types", Value: ""}}},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := mongo.CreateIndex(ctx, "categories", bson.D{{Key: "legacy_type", Value: 1}}, options.Index().
+		SetName("legacy_type_unique").
+		SetUnique(true).
+		SetPartialFilterExpression(bson.D{{Key: "legacy_type", Value: bson.D{{Key: "$gt", Value: 0}}}})); err != nil {
+		return err
+	}
+
+	_, err = mongo.CreateIndex(ctx, "locations", bson.D{{Key: "category", Value: 1}}, options.Index().SetName("category"))
+
+	return err
+}
+
+func queueProjectScope(ctx context.Context, mongo sources.MongoClient) error {
+	unscoped := bson.D{{Key: "project_id", Value: bson.D{{Key: "$exists", Value: false}}}}
+
+	// Kiralar birkaç dakikalık, taşımak yerine siliyoruz
+	if err := mongo.DeleteMany(ctx, "queue_leases", unscoped); err != nil {
+		return err
+	}
+
+	cur, err := mongo.Find(ctx, "queue_signals", unscoped)
+	if err != nil {
+		return err
+	}
+
+	signals := make([]struct {
+		EntryID int     `bson:"_id"`
+		Urgency float64 `bson:"urgency"`
+	}, 0)
+	if err := cur.All(ctx, &signals); err != nil {
+		return err
+	}
+
+	for _, signal := range signals {
+		if err := mongo.UpsertOne(ctx, "queue_signals", bson.D{
+			{Key: "project_id", Value: projects.DefaultID},
+			{Key: "entry_id", Value: signal.EntryID},
+		}, bson.D{{Key: "$set", Value: bson.D{{Key: "urgency", Value: signal.Urgency}}}}); err != nil {
+			return err
+		}
+
+		if err := mongo.DeleteOne(ctx, "queue_signals", bson.D{{Key: "_id", Value: signal.EntryID}}); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range []string{"queue_leases", "queue_signals"} {
+		if _, err := mongo.CreateIndex(ctx, table, bson.D{
+			{Key: "project_id", Value: 1},
+			{Key: "entry_id", Value: 1},
+		}, options.Index().SetName("project_id_entry_id_unique").SetUnique(true)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// legacyRequiredFields clears the required fields migration 17 seeds the legacy categories with, unless an admin
+// changed them since. The current frontend sends open_address and apartment empty for the legacy types.
+func legacyRequiredFields(ctx context.Context, mongo sources.MongoClient) error {
+	seeded := map[string]bson.A{
+		categories.Wreckage:   {"open_address", "apartment"},
+		categories.SupplyHelp: {"open_address"},
+	}
+
+	for id, fields := range seeded {
+		if _, err := mongo.UpdateMany(ctx, "categories", bson.D{
+			{Key: "_id", Value: id},
+			{Key: "required_fields", Value: fields},
+		}, bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "required_fields", Value: bson.A{}},
+				{Key: "updated_at", Value: time.Now()},
+			}},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func webhookCategories(ctx context.Context, mongo sources.MongoClient) error {
+	cur, err := mongo.Find(ctx, "categories", bson.D{{Key: "legacy_type", Value: bson.D{{Key: "$gt", Value: 0}}}})
+	if err != nil {
+		return err
+	}
+
+	legacy := make([]*categories.Category, 0)
+	if err := cur.All(ctx, &legacy); err != nil {
+		return err
+	}
+
+	for _, category := range legacy {
+		if _, err := mongo.UpdateMany(ctx, "webhook_endpoints", bson.D{{Key: "types", Value: category.LegacyType}}, bson.D{
+			{Key: "$addToSet", Value: bson.D{{Key: "categories", Value: category.ID}}},
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Kategoriler eklendikten sonra siliniyor, yarıda kalırsa tekrar çalıştırılabilsin
+	_, err = mongo.UpdateMany(ctx, "webhook_endpoints", bson.D{{Key: "types", Value: bson.D{{Key: "$exists", Value: true}}}}, bson.D{
+		{Key: "$unset", Value: bson.D{{Key: "types", Value: ""}}},
+	})
+
+	return err
+}
+
+// auditProjects dates the entry reads by the migration that added projects, every entry before it was in the default
+// project. Reads recorded since then are left without a project, their entry id can't be told apart.
+func auditProjects(ctx context.Context, mongo sources.MongoClient) error {
+	scoped := &Record{}
+	if err := mongo.FindOne(ctx, collection, bson.D{{Key: "_id", Value: 16}}).Decode(scoped); err != nil {
+		return err
+	}
+
+	if _, err := mongo.UpdateMany(ctx, "audit_log", bson.D{
+		{Key: "entry_id", Value: bson.D{{Key: "$gt", Value: 0}}},
+		{Key: "project_id", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "created_at", Value: bson.D{{Key: "$lt", Value: scoped.AppliedAt}}},
+	}, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "project_id", Value: projects.DefaultID}}},
+	}); err != nil {
+		return err
+	}
+
+	if _, err := mongo.CreateIndex(ctx, "audit_log", bson.D{
+		{Key: "project_id", Value: 1},
+		{Key: "entry_id", Value: 1},
+		{Key: "created_at", Value: -1},
+	}, options.Index().SetName("project_id_entry_id_created_at")); err != nil {
+		return err
+	}
+
+	_, err := mongo.CreateIndex(ctx, "audit_log", bson.D{
+		{Key: "projects", Value: 1},
+		{Key: "created_at", Value: -1},
+	}, options.Index().SetName("projects_created_at"))
+
+	return err
+}
@@ -0,0 +1,214 @@
+// Package pii finds and redacts the personal data of victims in tweets: phone numbers, TC kimlik numbers, e-mail
+// addresses and IBANs.
+package pii
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Type string
+
+const (
+	TypePhone    Type = "phone"
+	TypeTCKimlik Type = "tc_kimlik"
+	TypeEmail    Type = "email"
+	TypeIBAN     Type = "iban"
+)
+
+var placeholders = map[Type]string{
+	TypePhone:    "[TELEFON]",
+	TypeTCKimlik: "[TC KİMLİK]",
+	TypeEmail:    "[E-POSTA]",
+	TypeIBAN:     "[IBAN]",
+}
+
+type Match struct {
+	Type  Type   `json:"type"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Value string `json:"value"`
+}
+
+var (
+	// 05xx xxx xx xx, +90 5xx..., 5xxxxxxxxx ve sabit hatlar (0xxx) ayraçlı ya da ayraçsız
+	phoneRe    = regexp.MustCompile(`(?:\+?90[\s\-.]?)?\(?0?[2-5]\d{2}\)?[\s\-.]?\d{3}[\s\-.]?\d{2}[\s\-.]?\d{2}`)
+	tcKimlikRe = regexp.MustCompile(`[1-9]\d{10}`)
+	emailRe    = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	ibanRe     = regexp.MustCompile(`(?i)TR\s?\d{2}(?:\s?\d{4}){5}\s?\d{2}`)
+)
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// standalone rejects matches that are part of a longer number.
+func standalone(text string, start, end int) bool {
+	return (start == 0 || !isDigit(text[start-1])) && (end == len(text) || !isDigit(text[end]))
+}
+
+func digitsOf(s string) string {
+	digits := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if isDigit(s[i]) {
+			digits = append(digits, s[i])
+		}
+	}
+
+	return string(digits)
+}
+
+// NormalizePhone returns a phone number as +90XXXXXXXXXX, false if it isn't a Turkish number.
+func NormalizePhone(s string) (string, bool) {
+	number := digitsOf(s)
+
+	switch {
+	case len(number) == 12 && strings.HasPrefix(number, "90"):
+		number = number[2:]
+	case len(number) == 11 && strings.HasPrefix(number, "0"):
+		number = number[1:]
+	}
+
+	if len(number) != 10 || number[0] < '2' || number[0] > '5' {
+		return "", false
+	}
+
+	return "+90" + number, true
+}
+
+// ValidTCKimlik checks the two check digits of a TC kimlik number.
+func ValidTCKimlik(s string) bool {
+	if len(s) != 11 || s[0] == '0' {
+		return false
+	}
+
+	d := make([]int, 11)
+	for i := range s {
+		if !isDigit(s[i]) {
+			return false
+		}
+		d[i] = int(s[i] - '0')
+	}
+
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return false
+	}
+
+	sum := 0
+	for _, digit := range d[:10] {
+		sum += digit
+	}
+
+	return sum%10 == d[10]
+}
+
+func validIBAN(s string) bool {
+	iban := strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if len(iban) != 26 {
+		return false
+	}
+
+	// Mod 97, ülke kodu harfleri sayıya çevrilip sona taşınır
+	rearranged := iban[4:] + "2927" + iban[2:4]
+	remainder := 0
+	for i := 0; i < len(rearranged); i++ {
+		remainder = (remainder*10 + int(rearranged[i]-'0')) % 97
+	}
+
+	return remainder == 1
+}
+
+// Find returns the personal data in text ordered by position, overlapping matches are dropped.
+func Find(text string) []*Match {
+	matches := make([]*Match, 0)
+
+	add := func(t Type, re *regexp.Regexp, valid func(value string, start, end int) bool) {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if valid(text[loc[0]:loc[1]], loc[0], loc[1]) {
+				matches = append(matches, &Match{Type: t, Start: loc[0], End: loc[1], Value: text[loc[0]:loc[1]]})
+			}
+		}
+	}
+
+	// Önce uzun ve kesin olanlar, telefon deseni IBAN ve TC kimlik parçalarıyla da eşleşebiliyor
+	add(TypeIBAN, ibanRe, func(value string, _, _ int) bool { return validIBAN(value) })
+	add(TypeEmail, emailRe, func(string, int, int) bool { return true })
+	add(TypeTCKimlik, tcKimlikRe, func(value string, start, end int) bool {
+		return standalone(text, start, end) && ValidTCKimlik(value)
+	})
+	add(TypePhone, phoneRe, func(value string, start, end int) bool {
+		_, ok := NormalizePhone(value)
+
+		return ok && standalone(text, start, end)
+	})
+
+	kept := make([]*Match, 0, len(matches))
+	for _, m := range matches {
+		overlaps := false
+		for _, k := range kept {
+			if m.Start < k.End && k.Start < m.End {
+				overlaps = true
+
+				break
+			}
+		}
+
+		if !overlaps {
+			kept = append(kept, m)
+		}
+	}
+
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Start < kept[j].Start })
+
+	return kept
+}
+
+// Redact replaces the personal data in text with placeholders like [TELEFON].
+func Redact(text string) string {
+	matches := Find(text)
+	if len(matches) == 0 {
+		return text
+	}
+
+	b := strings.Builder{}
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString(placeholders[m.Type])
+		last = m.End
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
+
+// Phones returns the phone numbers in text normalized, without duplicates.
+func Phones(text string) []string {
+	phones := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, m := range Find(text) {
+		if m.Type != TypePhone {
+			continue
+		}
+
+		if phone, ok := NormalizePhone(m.Value); ok && !seen[phone] {
+			seen[phone] = true
+			phones = append(phones, phone)
+		}
+	}
+
+	return phones
+}
+
+// MaskPhone keeps the operator code and the last two digits, +90532*****67.
+func MaskPhone(phone string) string {
+	if len(phone) != 13 {
+		return placeholders[TypePhone]
+	}
+
+	return phone[:6] + "*****" + phone[11:]
+}
@@ -0,0 +1,134 @@
+package pii
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Kontrol basamakları geçerli ama kimseye ait olmayan örnekler
+const (
+	sampleTCKimlik = "10000000146"
+	sampleIBAN     = "TR330006100519786457841326"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+		ok   bool
+	}{
+		{"0532 123 45 67", "+905321234567", true},
+		{"+90 (532) 123-45-67", "+905321234567", true},
+		{"905321234567", "+905321234567", true},
+		{"5321234567", "+905321234567", true},
+		{"0326 214 00 00", "+903262140000", true},
+		{"0632 123 45 67", "", false},
+		{"532 123 45", "", false},
+		{"+49 151 12345678", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NormalizePhone(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizePhone(%q) = %q, %t, want %q, %t", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidTCKimlik(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{sampleTCKimlik, true},
+		{"10000000147", false},
+		{"10000000136", false},
+		{"01000000146", false},
+		{"1000000014", false},
+		{"1000000014a", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidTCKimlik(tt.s); got != tt.want {
+			t.Errorf("ValidTCKimlik(%q) = %t, want %t", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestValidIBAN(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{sampleIBAN, true},
+		{"TR33 0006 1005 1978 6457 8413 26", true},
+		{"tr330006100519786457841326", true},
+		{"TR340006100519786457841326", false},
+		{"TR3300061005197864578413", false},
+	}
+
+	for _, tt := range tests {
+		if got := validIBAN(tt.s); got != tt.want {
+			t.Errorf("validIBAN(%q) = %t, want %t", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"nothing", "Antakya Cumhuriyet Mah. 12 numara", "Antakya Cumhuriyet Mah. 12 numara"},
+		{"phone", "Ulaşın 0532 123 45 67 lütfen", "Ulaşın [TELEFON] lütfen"},
+		{"tc kimlik", "TC " + sampleTCKimlik, "TC [TC KİMLİK]"},
+		{"invalid tc kimlik is kept", "No 10000000147", "No 10000000147"},
+		{"email", "yaz: ali.veli@example.com", "yaz: [E-POSTA]"},
+		{"iban is not read as phones", "IBAN TR33 0006 1005 1978 6457 8413 26", "IBAN [IBAN]"},
+		{"part of a longer number", "Seri 1205321234567890", "Seri 1205321234567890"},
+		{"several", "0532 123 45 67 / ali@example.com", "[TELEFON] / [E-POSTA]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhones(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"telefon yok", []string{}},
+		{"0532 123 45 67 ve 05321234567", []string{"+905321234567"}},
+		{"0532 123 45 67, (0326) 214 00 00", []string{"+905321234567", "+903262140000"}},
+		{"TC " + sampleTCKimlik, []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := Phones(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Phones(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{"+905321234567", "+90532*****67"},
+		{"0532", "[TELEFON]"},
+	}
+
+	for _, tt := range tests {
+		if got := MaskPhone(tt.phone); got != tt.want {
+			t.Errorf("MaskPhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownKey = errors.New("text was encrypted with an unknown key")
+
+// Protected is the form tweet contents are stored in. Redacted is shown to everyone, Encrypted holds the raw text
+// for moderators and Hash finds duplicates without decrypting.
+type Protected struct {
+	Redacted  string
+	Encrypted string
+	Hash      string
+}
+
+type Protector interface {
+	Protect(text string) (*Protected, error)
+	Reveal(encrypted string) (string, error)
+	Hash(text string) string
+}
+
+type protector struct {
+	keyID   string
+	aead    cipher.AEAD
+	hashKey []byte
+}
+
+// NewProtector takes a base64 encoded 32 byte key, the raw text is encrypted with AES-256-GCM.
+func NewProtector(encodedKey string) (Protector, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not base64: %w", err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anahtar değişirse eski kayıtları ayırt edebilmek için şifreli metnin başına anahtarın kimliğini koyuyoruz
+	sum := sha256.Sum256(key)
+	hashKey := hmac.New(sha256.New, key)
+	hashKey.Write([]byte("tweet_contents_hash"))
+
+	return &protector{
+		keyID:   hex.EncodeToString(sum[:4]),
+		aead:    aead,
+		hashKey: hashKey.Sum(nil),
+	}, nil
+}
+
+func (p *protector) Protect(text string) (*Protected, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := p.aead.Seal(nonce, nonce, []byte(text), []byte(p.keyID))
+
+	return &Protected{
+		Redacted:  Redact(text),
+		Encrypted: p.keyID + ":" + base64.StdEncoding.EncodeToString(sealed),
+		Hash:      p.Hash(text),
+	}, nil
+}
+
+func (p *protector) Reveal(encrypted string) (string, error) {
+	keyID, encoded, ok := strings.Cut(encrypted, ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted text")
+	}
+
+	if keyID != p.keyID {
+		return "", ErrUnknownKey
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < p.aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted text")
+	}
+
+	plain, err := p.aead.Open(nil, sealed[:p.aead.NonceSize()], sealed[p.aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+// Hash is keyed so the hashes of short texts can't be reversed by trying every phone number.
+func (p *protector) Hash(text string) string {
+	mac := hmac.New(sha256.New, p.hashKey)
+	mac.Write([]byte(text))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,139 @@
+package pii
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	testKey  = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	otherKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func TestNewProtector(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"valid", testKey, false},
+		{"not base64", "not a key!", true},
+		{"short", "c2hvcnQ=", true},
+		{"empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewProtector(tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("NewProtector(%q) error = %v, want error %t", tt.key, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProtectReveal(t *testing.T) {
+	p, err := NewProtector(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		redacted string
+	}{
+		{"empty", "", ""},
+		{"plain", "Antakya enkaz altında", "Antakya enkaz altında"},
+		{"with a phone", "Hatay 0532 123 45 67", "Hatay [TELEFON]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protected, err := p.Protect(tt.text)
+			if err != nil {
+				t.Fatalf("Protect(%q) failed: %s", tt.text, err)
+			}
+
+			if protected.Redacted != tt.redacted {
+				t.Errorf("Protect(%q) redacted = %q, want %q", tt.text, protected.Redacted, tt.redacted)
+			}
+			if protected.Hash != p.Hash(tt.text) {
+				t.Errorf("Protect(%q) hash = %s, want %s", tt.text, protected.Hash, p.Hash(tt.text))
+			}
+			if len(tt.text) > 0 && strings.Contains(protected.Encrypted, tt.text) {
+				t.Errorf("Protect(%q) encrypted = %q holds the text", tt.text, protected.Encrypted)
+			}
+
+			revealed, err := p.Reveal(protected.Encrypted)
+			if err != nil {
+				t.Fatalf("Reveal(%q) failed: %s", protected.Encrypted, err)
+			}
+			if revealed != tt.text {
+				t.Errorf("Reveal() = %q, want %q", revealed, tt.text)
+			}
+		})
+	}
+}
+
+func TestProtectUsesFreshNonces(t *testing.T) {
+	p, err := NewProtector(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := p.Protect("aynı metin")
+	b, _ := p.Protect("aynı metin")
+
+	if a.Encrypted == b.Encrypted {
+		t.Errorf("Protect() returned the same ciphertext twice: %s", a.Encrypted)
+	}
+	if a.Hash != b.Hash {
+		t.Errorf("Protect() hashes differ: %s, %s", a.Hash, b.Hash)
+	}
+}
+
+func TestRevealInvalid(t *testing.T) {
+	p, err := NewProtector(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewProtector(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protected, err := other.Protect("gizli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, err := p.Protect("gizli")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyID, encoded, _ := strings.Cut(own.Encrypted, ":")
+	tampered := keyID + ":" + encoded[:len(encoded)-4] + "AAA="
+
+	if _, err := p.Reveal(protected.Encrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Reveal() with another key = %v, want ErrUnknownKey", err)
+	}
+
+	for _, encrypted := range []string{"", "no separator", keyID + ":not base64!", keyID + ":AAAA", tampered} {
+		if _, err := p.Reveal(encrypted); err == nil {
+			t.Errorf("Reveal(%q) succeeded, want an error", encrypted)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	p, _ := NewProtector(testKey)
+	other, _ := NewProtector(otherKey)
+
+	if p.Hash("0532 123 45 67") == p.Hash("0532 123 45 68") {
+		t.Error("Hash() is the same for different texts")
+	}
+	if p.Hash("0532 123 45 67") == other.Hash("0532 123 45 67") {
+		t.Error("Hash() is the same for different keys")
+	}
+}
@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/extract"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/notifications"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	queueRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/queue"
+	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	"github.com/sirupsen/logrus"
+)
+
+// Ingester scores the urgency of the feed entries before they are ranked, /get-location only reads the text of the
+// entries it serves and the rest would always have an urgency of 0.
+type Ingester interface {
+	// Ingest stores the urgency of up to Queue.IngestBatch unresolved entries of the project that have none yet, the
+	// newest first. It returns how many it stored.
+	Ingest(ctx context.Context, project *projects.Project) (int, error)
+	// Run ingests every active project once per Queue.IngestInterval on a single replica.
+	Run(ctx context.Context) error
+}
+
+type ingester struct {
+	cfg       config.Queue
+	repo      queueRepository.Repository
+	projects  projects.Repository
+	locations locations.Repository
+	feeds     tools.Feeds
+	claims    notifications.Repository
+}
+
+func NewIngester(cfg config.Queue, repo queueRepository.Repository, projects projects.Repository, locations locations.Repository, feeds tools.Feeds, claims notifications.Repository) Ingester {
+	return &ingester{
+		cfg:       cfg,
+		repo:      repo,
+		projects:  projects,
+		locations: locations,
+		feeds:     feeds,
+		claims:    claims,
+	}
+}
+
+func (i *ingester) Ingest(ctx context.Context, project *projects.Project) (int, error) {
+	feed := i.feeds.For(project)
+	repo := i.repo.WithProject(project.ID)
+
+	entries, err := feed.GetAllLocations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resolved, err := i.locations.WithProject(project.ID).GetResolvedIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	urgencies, err := repo.GetUrgencies(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := make([]*locations.Location, 0)
+	for _, entry := range entries {
+		if _, ok := urgencies[entry.EntryID]; ok || resolved[entry.EntryID] {
+			continue
+		}
+
+		pending = append(pending, entry)
+	}
+
+	// Yeni girdiler önce, eskilerin de sırası gelir
+	sort.Slice(pending, func(a, b int) bool { return pending[a].Epoch > pending[b].Epoch })
+	if len(pending) > i.cfg.IngestBatch {
+		pending = pending[:i.cfg.IngestBatch]
+	}
+
+	stored := 0
+	for _, entry := range pending {
+		single, err := feed.GetSingleLocation(ctx, entry.EntryID)
+		if err != nil {
+			return stored, err
+		}
+
+		if err := repo.SetUrgency(ctx, entry.EntryID, extract.Extract(single.FullText).Urgency.Score()); err != nil {
+			return stored, err
+		}
+
+		stored++
+	}
+
+	return stored, nil
+}
+
+func (i *ingester) Run(ctx context.Context) error {
+	ticker := time.NewTicker(i.cfg.IngestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		claimed, err := i.claims.Claim(ctx, "queue-ingest", time.Now().UnixNano()/int64(i.cfg.IngestInterval))
+		if err != nil || !claimed {
+			if err != nil && ctx.Err() == nil {
+				logrus.Errorf("couldn't claim the queue ingestion: %s", err)
+			}
+
+			continue
+		}
+
+		active, err := i.projects.GetProjects(ctx, false)
+		if err != nil {
+			if ctx.Err() == nil {
+				logrus.Errorf("couldn't get the projects to ingest: %s", err)
+			}
+
+			continue
+		}
+
+		for _, project := range active {
+			stored, err := i.Ingest(ctx, project)
+			if err != nil && ctx.Err() == nil {
+				logrus.Errorf("ingesting the entries of project %s failed: %s", project.ID, err)
+			}
+
+			if stored > 0 {
+				logrus.Infof("Stored the urgency of %d entries of project %s", stored, project.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package queue
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/events"
+	"github.com/YusufOzmen01/veri-kontrol-backend/extract"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	queueRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/queue"
+	"github.com/sirupsen/logrus"
+)
+
+// Factors are the parts of the score before weighting, each between 0 and 1.
+type Factors struct {
+	Age           float64 `json:"age"`
+	Urgency       float64 `json:"urgency"`
+	Cluster       float64 `json:"cluster"`
+	RegionBacklog float64 `json:"region_backlog"`
+	Skips         float64 `json:"skips"`
+}
+
+type Candidate struct {
+	Location *locations.Location `json:"location"`
+	Score    float64             `json:"score"`
+	Factors  *Factors            `json:"factors"`
+}
+
+type Scheduler interface {
+	Weights(ctx context.Context) (*config.Weights, error)
+	SetWeights(ctx context.Context, weights *config.Weights, updatedBy string) error
+	// Rank scores the entries and sorts them by descending score.
+	Rank(ctx context.Context, locs []*locations.Location, skipCounts map[int]int) ([]*Candidate, error)
+	// Next leases the best candidate not leased by someone else and accepted by accept. Rejected candidates are
+	// released again. It returns nil when there is none within the configured number of tries.
+	Next(ctx context.Context, candidates []*Candidate, holder string, accept func(loc *locations.Location) (bool, error)) (*Candidate, time.Time, error)
+	Release(ctx context.Context, entryID int) error
+	// ObserveText stores the urgency of an entry once its text is known.
+	ObserveText(ctx context.Context, entryID int, text string) error
+	// Sweep publishes the expired leases of every project on the bus until ctx is done. Every replica publishes them
+	// to its own subscribers.
+	Sweep(bus events.Bus) func(ctx context.Context) error
+	// WithProject returns the scheduler of one project, leases and urgencies are kept per project and the region
+	// backlog uses its regions. The weights are shared.
+	WithProject(project *projects.Project) Scheduler
+}
+
+type scheduler struct {
+	cfg     *config.Config
+	repo    queueRepository.Repository
+	project *projects.Project
+}
+
+func NewScheduler(cfg *config.Config, repo queueRepository.Repository) Scheduler {
+	return &scheduler{
+		cfg:  cfg,
+		repo: repo,
+	}
+}
+
+func (s *scheduler) WithProject(project *projects.Project) Scheduler {
+	return &scheduler{
+		cfg:     s.cfg,
+		repo:    s.repo.WithProject(project.ID),
+		project: project,
+	}
+}
+
+// regionOf is 0 without a project, the region backlog is then left out of the score.
+func (s *scheduler) regionOf(location []float64) int {
+	if s.project == nil {
+		return 0
+	}
+
+	return s.project.RegionOf(location)
+}
+
+func (s *scheduler) Weights(ctx context.Context) (*config.Weights, error) {
+	weights, err := s.repo.GetWeights(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if weights == nil {
+		defaults := s.cfg.Queue.Weights
+		weights = &defaults
+	}
+
+	return weights, nil
+}
+
+func (s *scheduler) SetWeights(ctx context.Context, weights *config.Weights, updatedBy string) error {
+	return s.repo.SetWeights(ctx, weights, updatedBy)
+}
+
+func ratio(value, max float64) float64 {
+	if max <= 0 {
+		return 0
+	}
+
+	return math.Min(1, value/max)
+}
+
+func (s *scheduler) Rank(ctx context.Context, locs []*locations.Location, skipCounts map[int]int) ([]*Candidate, error) {
+	weights, err := s.Weights(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	urgencies, err := s.repo.GetUrgencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type cell struct{ lat, lng int64 }
+	cellOf := func(loc *locations.Location) cell {
+		return cell{
+			lat: int64(math.Floor(loc.Loc[0] / s.cfg.Queue.ClusterCell)),
+			lng: int64(math.Floor(loc.Loc[1] / s.cfg.Queue.ClusterCell)),
+		}
+	}
+
+	clusters := make(map[cell]int)
+	backlogs := make(map[int]int)
+	regions := make(map[int]int, len(locs))
+	minEpoch, maxEpoch := math.MaxInt, 0
+	maxCluster, maxBacklog := 0, 0
+
+	for _, loc := range locs {
+		if loc.Epoch < minEpoch {
+			minEpoch = loc.Epoch
+		}
+		if loc.Epoch > maxEpoch {
+			maxEpoch = loc.Epoch
+		}
+
+		if len(loc.Loc) != 2 {
+			continue
+		}
+
+		c := cellOf(loc)
+		clusters[c]++
+		if clusters[c] > maxCluster {
+			maxCluster = clusters[c]
+		}
+
+		if region := s.regionOf(loc.Loc); region > 0 {
+			regions[loc.EntryID] = region
+			backlogs[region]++
+			if backlogs[region] > maxBacklog {
+				maxBacklog = backlogs[region]
+			}
+		}
+	}
+
+	candidates := make([]*Candidate, 0, len(locs))
+	for _, loc := range locs {
+		factors := &Factors{
+			// En eski girdi 1, en yeni 0
+			Age:     ratio(float64(maxEpoch-loc.Epoch), float64(maxEpoch-minEpoch)),
+			Urgency: urgencies[loc.EntryID],
+			Skips:   ratio(float64(skipCounts[loc.EntryID]), float64(s.cfg.Skips.ExpertThreshold)),
+		}
+
+		if len(loc.Loc) == 2 {
+			// Tek başına olan girdi 0
+			factors.Cluster = ratio(float64(clusters[cellOf(loc)]-1), float64(maxCluster-1))
+		}
+		if region, ok := regions[loc.EntryID]; ok {
+			factors.RegionBacklog = ratio(float64(backlogs[region]), float64(maxBacklog))
+		}
+
+		candidates = append(candidates, &Candidate{
+			Location: loc,
+			Factors:  factors,
+			Score: weights.Age*factors.Age +
+				weights.Urgency*factors.Urgency +
+				weights.Cluster*factors.Cluster +
+				weights.RegionBacklog*factors.RegionBacklog +
+				weights.Skips*factors.Skips,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+
+		return candidates[i].Location.EntryID < candidates[j].Location.EntryID
+	})
+
+	return candidates, nil
+}
+
+func (s *scheduler) Next(ctx context.Context, candidates []*Candidate, holder string, accept func(loc *locations.Location) (bool, error)) (*Candidate, time.Time, error) {
+	leases, err := s.repo.GetActiveLeases(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	tries := 0
+	for _, candidate := range candidates {
+		if tries >= s.cfg.Queue.MaxCandidates {
+			break
+		}
+
+		entryID := candidate.Location.EntryID
+		if lease, ok := leases[entryID]; ok && lease.Holder != holder {
+			continue
+		}
+
+		tries++
+
+		until := time.Now().Add(s.cfg.Queue.LeaseDuration)
+
+		acquired, err := s.repo.Acquire(ctx, entryID, holder, until)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if !acquired {
+			continue
+		}
+
+		accepted, err := accept(candidate.Location)
+		if err != nil || !accepted {
+			if releaseErr := s.repo.Release(ctx, entryID); releaseErr != nil {
+				logrus.Errorf("couldn't release lease of entry %d: %s", entryID, releaseErr)
+			}
+
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+
+			continue
+		}
+
+		return candidate, until, nil
+	}
+
+	return nil, time.Time{}, nil
+}
+
+func (s *scheduler) Release(ctx context.Context, entryID int) error {
+	return s.repo.Release(ctx, entryID)
+}
+
+func (s *scheduler) ObserveText(ctx context.Context, entryID int, text string) error {
+	return s.repo.SetUrgency(ctx, entryID, extract.Extract(text).Urgency.Score())
+}
+
+func (s *scheduler) Sweep(bus events.Bus) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(s.cfg.Queue.SweepInterval)
+		defer ticker.Stop()
+
+		last := time.Now()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			now := time.Now()
+
+			expired, err := s.repo.GetExpired(ctx, last, now)
+			if err != nil {
+				if ctx.Err() == nil {
+					logrus.Errorf("couldn't get expired leases: %s", err)
+				}
+
+				continue
+			}
+			last = now
+
+			for _, lease := range expired {
+				bus.Publish(&events.Event{
+					Type:    events.TypeLeaseExpired,
+					Project: lease.ProjectID,
+					EntryID: lease.EntryID,
+					Time:    lease.LeaseUntil,
+					Data:    lease,
+				})
+			}
+		}
+	}
+}
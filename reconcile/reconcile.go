@@ -0,0 +1,378 @@
+// Package reconcile compares the feed of a project with its resolutions. Entries deleted from the feed leave orphaned
+// resolutions behind, moved entries leave resolutions at the old location and some feed entries are never resolved.
+package reconcile
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/coords"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/events"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/notifications"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/YusufOzmen01/veri-kontrol-backend/tools"
+	"github.com/YusufOzmen01/veri-kontrol-backend/util"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/sirupsen/logrus"
+)
+
+// sameLocationKm is the distance under which a resolution is taken to have kept the feed location, the stored map
+// link only has 6 decimals.
+const sameLocationKm = 0.001
+
+type Orphan struct {
+	EntryID    int        `json:"entry_id"`
+	Location   []float64  `json:"location"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	OrphanedAt *time.Time `json:"orphaned_at,omitempty"`
+}
+
+// Drift is an entry whose feed location moved after it was resolved. Corrected resolutions have a location of their
+// own and are only reported, the others follow the feed when fixing.
+type Drift struct {
+	EntryID    int       `json:"entry_id"`
+	Resolved   []float64 `json:"resolved"`
+	Feed       []float64 `json:"feed"`
+	Location   []float64 `json:"location"`
+	DistanceKm float64   `json:"distance_km"`
+	Corrected  bool      `json:"corrected"`
+}
+
+// Region counts the feed entries in a region of the project, region 0 holds the ones outside every region.
+type Region struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name,omitempty"`
+	Feed       int    `json:"feed"`
+	Resolved   int    `json:"resolved"`
+	Unresolved int    `json:"unresolved"`
+}
+
+type Fixed struct {
+	Orphaned int64 `json:"orphaned"`
+	// Restored are the orphans that came back to the feed, their mark is cleared.
+	Restored int64 `json:"restored"`
+	Moved    int64 `json:"moved"`
+	// Located are the resolutions stored without a location that got the one of the feed.
+	Located int64 `json:"located"`
+}
+
+type Report struct {
+	Project     string    `json:"project"`
+	StartedAt   time.Time `json:"started_at"`
+	Fix         bool      `json:"fix"`
+	FeedEntries int       `json:"feed_entries"`
+	Resolutions int       `json:"resolutions"`
+	Orphans     []*Orphan `json:"orphans"`
+	// Restored are resolutions marked as orphaned whose entry is in the feed again.
+	Restored []int    `json:"restored"`
+	Drifted  []*Drift `json:"drifted"`
+	// MissingLocation are resolutions stored without coordinates, the ones still in the feed can be fixed.
+	MissingLocation []int     `json:"missing_location"`
+	Regions         []*Region `json:"regions"`
+	Fixed           *Fixed    `json:"fixed,omitempty"`
+}
+
+// Summary is published on the event bus, the full lists stay in the logs and the admin report.
+type Summary struct {
+	Project         string `json:"project"`
+	FeedEntries     int    `json:"feed_entries"`
+	Resolutions     int    `json:"resolutions"`
+	Orphans         int    `json:"orphans"`
+	Drifted         int    `json:"drifted"`
+	MissingLocation int    `json:"missing_location"`
+	Unresolved      int    `json:"unresolved"`
+	Fixed           *Fixed `json:"fixed,omitempty"`
+}
+
+func (r *Report) Summary() *Summary {
+	summary := &Summary{
+		Project:         r.Project,
+		FeedEntries:     r.FeedEntries,
+		Resolutions:     r.Resolutions,
+		Orphans:         len(r.Orphans),
+		Drifted:         len(r.Drifted),
+		MissingLocation: len(r.MissingLocation),
+		Fixed:           r.Fixed,
+	}
+
+	for _, region := range r.Regions {
+		summary.Unresolved += region.Unresolved
+	}
+
+	return summary
+}
+
+type Service interface {
+	// Reconcile only reports unless fix is set.
+	Reconcile(ctx context.Context, project *projects.Project, fix bool) (*Report, error)
+	// Run reconciles every active project once per interval on a single replica and publishes a sync event each.
+	Run(bus events.Bus) func(ctx context.Context) error
+}
+
+type service struct {
+	cfg        config.Reconcile
+	projects   projects.Repository
+	locations  locations.Repository
+	feeds      tools.Feeds
+	claims     notifications.Repository
+	normalizer coords.Normalizer
+}
+
+func NewService(cfg config.Reconcile, projects projects.Repository, locations locations.Repository, feeds tools.Feeds, claims notifications.Repository) Service {
+	return &service{
+		cfg:       cfg,
+		projects:  projects,
+		locations: locations,
+		feeds:     feeds,
+		claims:    claims,
+		// Saklanan orijinal adres bizim ürettiğimiz harita linki, kısa link açmaya gerek yok
+		normalizer: coords.NewNormalizer(nil),
+	}
+}
+
+func (s *service) Reconcile(ctx context.Context, project *projects.Project, fix bool) (*Report, error) {
+	report := &Report{
+		Project:         project.ID,
+		StartedAt:       time.Now(),
+		Fix:             fix,
+		Orphans:         make([]*Orphan, 0),
+		Restored:        make([]int, 0),
+		Drifted:         make([]*Drift, 0),
+		MissingLocation: make([]int, 0),
+		Regions:         make([]*Region, 0),
+	}
+
+	feed, err := s.feeds.For(project).GetAllLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := s.locations.WithProject(project.ID)
+
+	resolutions, err := repo.GetLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report.FeedEntries = len(feed)
+	report.Resolutions = len(resolutions)
+
+	feedByID := make(map[int][]float64, len(feed))
+	for _, entry := range feed {
+		feedByID[entry.EntryID] = entry.Loc
+	}
+
+	resolved := make(map[int]bool, len(resolutions))
+	movable := make(map[int][]float64)
+	locatable := make(map[int][]float64)
+
+	for _, resolution := range resolutions {
+		loc, inFeed := feedByID[resolution.EntryID]
+
+		if !inFeed {
+			report.Orphans = append(report.Orphans, &Orphan{
+				EntryID:    resolution.EntryID,
+				Location:   resolution.Location,
+				UpdatedAt:  resolution.UpdatedAt,
+				OrphanedAt: resolution.OrphanedAt,
+			})
+
+			if len(resolution.Location) != 2 {
+				report.MissingLocation = append(report.MissingLocation, resolution.EntryID)
+			}
+
+			continue
+		}
+
+		resolved[resolution.EntryID] = true
+
+		if resolution.OrphanedAt != nil {
+			report.Restored = append(report.Restored, resolution.EntryID)
+		}
+
+		if len(resolution.Location) != 2 {
+			report.MissingLocation = append(report.MissingLocation, resolution.EntryID)
+			locatable[resolution.EntryID] = loc
+
+			continue
+		}
+
+		original := s.originalLocation(resolution)
+		if original == nil || len(loc) != 2 {
+			continue
+		}
+
+		distance := validation.DistanceKm(original, loc)
+		if distance <= s.cfg.DriftKm {
+			continue
+		}
+
+		drift := &Drift{
+			EntryID:    resolution.EntryID,
+			Resolved:   original,
+			Feed:       loc,
+			Location:   resolution.Location,
+			DistanceKm: distance,
+			Corrected:  validation.DistanceKm(original, resolution.Location) > sameLocationKm,
+		}
+		report.Drifted = append(report.Drifted, drift)
+
+		if !drift.Corrected {
+			movable[resolution.EntryID] = loc
+		}
+	}
+
+	report.Regions = regions(project, feed, resolved)
+
+	if fix {
+		if report.Fixed, err = s.fix(ctx, repo, report, movable, locatable); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// originalLocation reads the feed location the resolution was made with from its map link, nil if it can't.
+func (s *service) originalLocation(resolution *locations.LocationDB) []float64 {
+	if len(resolution.OriginalAddress) == 0 {
+		return nil
+	}
+
+	result, err := s.normalizer.Normalize(&coords.Input{Text: resolution.OriginalAddress}, nil)
+	if err != nil {
+		return nil
+	}
+
+	return result.Location
+}
+
+// fix marks the orphans instead of deleting them, an entry can come back to the feed and the resolution is all we have.
+func (s *service) fix(ctx context.Context, repo locations.Repository, report *Report, movable, locatable map[int][]float64) (*Fixed, error) {
+	fixed := &Fixed{}
+
+	orphans := make([]int, 0, len(report.Orphans))
+	for _, orphan := range report.Orphans {
+		if orphan.OrphanedAt == nil {
+			orphans = append(orphans, orphan.EntryID)
+		}
+	}
+
+	var err error
+	if fixed.Orphaned, err = repo.SetOrphaned(ctx, orphans, true); err != nil {
+		return fixed, err
+	}
+	if fixed.Restored, err = repo.SetOrphaned(ctx, report.Restored, false); err != nil {
+		return fixed, err
+	}
+
+	move := func(moves map[int][]float64, count *int64) error {
+		for entryID, loc := range moves {
+			if err := repo.MoveLocation(ctx, entryID, loc, util.MapLink(loc)); err != nil {
+				return err
+			}
+
+			*count++
+		}
+
+		return nil
+	}
+
+	if err := move(movable, &fixed.Moved); err != nil {
+		return fixed, err
+	}
+	if err := move(locatable, &fixed.Located); err != nil {
+		return fixed, err
+	}
+
+	return fixed, nil
+}
+
+func regions(project *projects.Project, feed []*locations.Location, resolved map[int]bool) []*Region {
+	byID := make(map[int]*Region)
+	for _, r := range project.Regions {
+		byID[r.ID] = &Region{ID: r.ID, Name: r.Name}
+	}
+
+	for _, entry := range feed {
+		id := project.RegionOf(entry.Loc)
+
+		region, ok := byID[id]
+		if !ok {
+			region = &Region{ID: id}
+			byID[id] = region
+		}
+
+		region.Feed++
+		if resolved[entry.EntryID] {
+			region.Resolved++
+		} else {
+			region.Unresolved++
+		}
+	}
+
+	result := make([]*Region, 0, len(byID))
+	for _, region := range byID {
+		result = append(result, region)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result
+}
+
+func (s *service) Run(bus events.Bus) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			claimed, err := s.claims.Claim(ctx, "reconcile", time.Now().UnixNano()/int64(s.cfg.Interval))
+			if err != nil || !claimed {
+				if err != nil && ctx.Err() == nil {
+					logrus.Errorf("couldn't claim the reconcile run: %s", err)
+				}
+
+				continue
+			}
+
+			active, err := s.projects.GetProjects(ctx, false)
+			if err != nil {
+				if ctx.Err() == nil {
+					logrus.Errorf("couldn't get the projects to reconcile: %s", err)
+				}
+
+				continue
+			}
+
+			for _, project := range active {
+				report, err := s.Reconcile(ctx, project, s.cfg.Fix)
+				if err != nil {
+					if ctx.Err() == nil {
+						logrus.Errorf("reconciling project %s failed: %s", project.ID, err)
+					}
+
+					continue
+				}
+
+				summary := report.Summary()
+				logrus.WithField("summary", summary).Infof("Reconciled project %s", project.ID)
+
+				bus.Publish(&events.Event{
+					Type:    events.TypeSync,
+					Project: project.ID,
+					Time:    report.StartedAt,
+					Data:    summary,
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	Record(ctx context.Context, record *Record) error
+	GetRecords(ctx context.Context, filter *Filter) ([]*Record, error)
+}
+
+// Actions that read or erase personal data, every one of them is recorded.
+const (
+	ActionReadOriginalMessage = "read_original_message"
+	ActionReadTweetContents   = "read_tweet_contents"
+	ActionLookupSubject       = "lookup_subject"
+	ActionExportSubject       = "export_subject"
+	ActionEraseSubject        = "erase_subject"
+)
+
+type repository struct {
+	mongo sources.MongoClient
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+// Record is one read or erasure. ProjectID is the project of EntryID, entry ids are only unique within a project.
+// Subject requests span every project, Projects lists the projects of the resolutions they found.
+type Record struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	Action    string             `json:"action" bson:"action"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	ProjectID string             `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	EntryID   int                `json:"entry_id" bson:"entry_id"`
+	Projects  []string           `json:"projects,omitempty" bson:"projects,omitempty"`
+	IP        string             `json:"ip" bson:"ip"`
+	RequestID string             `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	// Details summarizes subject requests, it never holds the searched phone or name.
+	Details   string    `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Filter narrows the listing, zero values don't filter.
+type Filter struct {
+	UserID    string
+	ProjectID string
+	EntryID   int
+	Since     time.Time
+	Limit     int64
+}
+
+func (r *repository) Record(ctx context.Context, record *Record) error {
+	record.ID = primitive.NewObjectID()
+	record.CreatedAt = time.Now()
+
+	if err := r.mongo.InsertOne(ctx, "audit_log", record); err != nil {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetRecords(ctx context.Context, filter *Filter) ([]*Record, error) {
+	query := bson.D{}
+	if len(filter.UserID) > 0 {
+		query = append(query, bson.E{Key: "user_id", Value: filter.UserID})
+	}
+	if len(filter.ProjectID) > 0 {
+		query = append(query, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "project_id", Value: filter.ProjectID}},
+			bson.D{{Key: "projects", Value: filter.ProjectID}},
+		}})
+	}
+	if filter.EntryID > 0 {
+		query = append(query, bson.E{Key: "entry_id", Value: filter.EntryID})
+	}
+	if !filter.Since.IsZero() {
+		query = append(query, bson.E{Key: "created_at", Value: bson.D{{Key: "$gte", Value: filter.Since}}})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+
+	cur, err := r.mongo.Find(ctx, "audit_log", query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0)
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
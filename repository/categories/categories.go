@@ -0,0 +1,238 @@
+package categories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	CreateCategory(ctx context.Context, category *Category) error
+	GetCategory(ctx context.Context, id string) (*Category, error)
+	GetCategories(ctx context.Context, includeArchived bool) ([]*Category, error)
+	// UpdateCategory overwrites the names, color, required fields, parent and order, the id and legacy type are fixed.
+	UpdateCategory(ctx context.Context, category *Category) error
+	// ArchiveCategory keeps the category on the existing resolutions, new resolutions can't use it.
+	ArchiveCategory(ctx context.Context, id string) error
+}
+
+var (
+	ErrNotFound = errors.New("category not found")
+	ErrExists   = errors.New("category already exists")
+)
+
+// Categories of the two location types that existed before the registry.
+const (
+	Wreckage   = "wreckage"
+	SupplyHelp = "supply_help"
+)
+
+const (
+	StatusActive   = "active"
+	StatusArchived = "archived"
+)
+
+// DefaultLanguage is the language every category must have a name in, other languages fall back to it.
+const DefaultLanguage = "tr"
+
+// Fields of a resolution that a category can make required.
+var Fields = []string{"open_address", "apartment", "tweet_contents"}
+
+const collection = "categories"
+
+type repository struct {
+	mongo sources.MongoClient
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+type Category struct {
+	ID string `json:"_id" bson:"_id"`
+	// Parent is set on sub-categories, only top level categories can have sub-categories.
+	Parent string `json:"parent,omitempty" bson:"parent,omitempty"`
+	// LegacyType is the integer type older clients send, sub-categories use the one of their parent.
+	LegacyType     int               `json:"legacy_type,omitempty" bson:"legacy_type,omitempty"`
+	Names          map[string]string `json:"names" bson:"names"`
+	Color          string            `json:"color" bson:"color"`
+	RequiredFields []string          `json:"required_fields" bson:"required_fields"`
+	Order          int               `json:"order" bson:"order"`
+	Status         string            `json:"status" bson:"status"`
+	CreatedAt      time.Time         `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" bson:"updated_at"`
+}
+
+func (c *Category) Archived() bool {
+	return c.Status == StatusArchived
+}
+
+// Name returns the name in lang, or in DefaultLanguage when the category isn't translated to it.
+func (c *Category) Name(lang string) string {
+	if name, ok := c.Names[lang]; ok && len(name) > 0 {
+		return name
+	}
+
+	return c.Names[DefaultLanguage]
+}
+
+var (
+	idRe    = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{1,62}$`)
+	colorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// Validate checks the fields of the category alone, Registry.Validate checks it against the other categories.
+func (c *Category) Validate() error {
+	errs := make(validation.Errors, 0)
+
+	if !idRe.MatchString(c.ID) {
+		errs.Add("_id", "must be 2 to 63 lowercase letters, digits or underscores")
+	}
+	if len(c.Names[DefaultLanguage]) == 0 {
+		errs.Add("names", "a %q name is required", DefaultLanguage)
+	}
+	if !colorRe.MatchString(c.Color) {
+		errs.Add("color", "must be a hex color like #d32f2f")
+	}
+	if c.LegacyType < 0 {
+		errs.Add("legacy_type", "can't be negative")
+	}
+	if len(c.Parent) > 0 && c.Parent == c.ID {
+		errs.Add("parent", "a category can't be its own parent")
+	}
+
+	seen := make(map[string]bool)
+	for _, field := range c.RequiredFields {
+		if !isField(field) {
+			errs.Add("required_fields", "unknown field %q, expected one of %v", field, Fields)
+		}
+		if seen[field] {
+			errs.Add("required_fields", "%q is given twice", field)
+		}
+		seen[field] = true
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func isField(field string) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *repository) CreateCategory(ctx context.Context, category *Category) error {
+	category.Status = StatusActive
+	category.CreatedAt = time.Now()
+	category.UpdatedAt = category.CreatedAt
+
+	if err := r.mongo.InsertOne(ctx, collection, category); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrExists
+		}
+
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetCategory(ctx context.Context, id string) (*Category, error) {
+	category := &Category{}
+	if err := r.mongo.FindOne(ctx, collection, bson.D{{Key: "_id", Value: id}}).Decode(category); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (r *repository) GetCategories(ctx context.Context, includeArchived bool) ([]*Category, error) {
+	filter := bson.D{}
+	if !includeArchived {
+		filter = bson.D{{Key: "status", Value: bson.D{{Key: "$ne", Value: StatusArchived}}}}
+	}
+
+	cur, err := r.mongo.Find(ctx, collection, filter, options.Find().SetSort(bson.D{
+		{Key: "order", Value: 1},
+		{Key: "_id", Value: 1},
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]*Category, 0)
+	if err := cur.All(ctx, &categories); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (r *repository) UpdateCategory(ctx context.Context, category *Category) error {
+	category.UpdatedAt = time.Now()
+
+	res := r.mongo.FindOneAndUpdate(ctx, collection, bson.D{{Key: "_id", Value: category.ID}}, bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "parent", Value: category.Parent},
+			{Key: "names", Value: category.Names},
+			{Key: "color", Value: category.Color},
+			{Key: "required_fields", Value: category.RequiredFields},
+			{Key: "order", Value: category.Order},
+			{Key: "updated_at", Value: category.UpdatedAt},
+		},
+	}})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ArchiveCategory(ctx context.Context, id string) error {
+	res := r.mongo.FindOneAndUpdate(ctx, collection, bson.D{{Key: "_id", Value: id}}, bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "status", Value: StatusArchived},
+			{Key: "updated_at", Value: time.Now()},
+		},
+	}})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
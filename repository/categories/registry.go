@@ -0,0 +1,125 @@
+package categories
+
+import (
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+)
+
+// Registry answers the lookups of a single load of the categories, archived ones included so that old resolutions
+// can still be shown.
+type Registry struct {
+	categories []*Category
+	byID       map[string]*Category
+	byLegacy   map[int]*Category
+}
+
+func NewRegistry(categories []*Category) *Registry {
+	r := &Registry{
+		categories: categories,
+		byID:       make(map[string]*Category, len(categories)),
+		byLegacy:   make(map[int]*Category),
+	}
+
+	for _, category := range categories {
+		r.byID[category.ID] = category
+
+		// Alt kategoriler eski tipi üst kategoriden alıyor, eski tip her zaman üst kategoriye çözülür
+		if category.LegacyType > 0 && len(category.Parent) == 0 {
+			r.byLegacy[category.LegacyType] = category
+		}
+	}
+
+	return r
+}
+
+// Get returns nil when there is no such category.
+func (r *Registry) Get(id string) *Category {
+	return r.byID[id]
+}
+
+// Legacy returns the top level category of an integer type sent by older clients, nil if there is none.
+func (r *Registry) Legacy(locationType int) *Category {
+	return r.byLegacy[locationType]
+}
+
+// Active lists the categories that new resolutions can use, in their display order.
+func (r *Registry) Active() []*Category {
+	active := make([]*Category, 0, len(r.categories))
+	for _, category := range r.categories {
+		if !category.Archived() {
+			active = append(active, category)
+		}
+	}
+
+	return active
+}
+
+// LegacyType is the integer type stored next to the category for the consumers that still filter by it.
+func (r *Registry) LegacyType(category *Category) int {
+	if parent := r.Get(category.Parent); parent != nil {
+		return parent.LegacyType
+	}
+
+	return category.LegacyType
+}
+
+// RequiredFields merges the required fields of the category with the ones of its parent.
+func (r *Registry) RequiredFields(category *Category) []string {
+	fields := make([]string, 0, len(category.RequiredFields))
+	seen := make(map[string]bool)
+
+	add := func(c *Category) {
+		for _, field := range c.RequiredFields {
+			if !seen[field] {
+				seen[field] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	if parent := r.Get(category.Parent); parent != nil {
+		add(parent)
+	}
+	add(category)
+
+	return fields
+}
+
+// Validate checks a new or updated category against the rest of the registry.
+func (r *Registry) Validate(category *Category) error {
+	errs := make(validation.Errors, 0)
+
+	if len(category.Parent) > 0 {
+		parent := r.Get(category.Parent)
+
+		switch {
+		case parent == nil:
+			errs.Add("parent", "category %q not found", category.Parent)
+		case parent.Archived():
+			errs.Add("parent", "category %q is archived", category.Parent)
+		case len(parent.Parent) > 0:
+			errs.Add("parent", "category %q is a sub-category itself", category.Parent)
+		}
+
+		if category.LegacyType > 0 {
+			errs.Add("legacy_type", "sub-categories use the legacy type of their parent")
+		}
+
+		for _, c := range r.categories {
+			if c.Parent == category.ID {
+				errs.Add("parent", "category %q has sub-categories, it can't become one", category.ID)
+
+				break
+			}
+		}
+	}
+
+	if existing := r.Legacy(category.LegacyType); category.LegacyType > 0 && existing != nil && existing.ID != category.ID {
+		errs.Add("legacy_type", "already used by %q", existing.ID)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	GetCheckpoint(ctx context.Context, job string) (*Checkpoint, error)
+	SaveCheckpoint(ctx context.Context, checkpoint *Checkpoint) error
+	ResetCheckpoint(ctx context.Context, job string) error
+	AddDeadLetter(ctx context.Context, letter *DeadLetter) error
+	GetDeadLetters(ctx context.Context, job string) ([]*DeadLetter, error)
+}
+
+type repository struct {
+	mongo sources.MongoClient
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+type Checkpoint struct {
+	Job       string    `json:"job" bson:"_id"`
+	Cursor    string    `json:"cursor" bson:"cursor"`
+	Processed int       `json:"processed" bson:"processed"`
+	Failed    int       `json:"failed" bson:"failed"`
+	Done      bool      `json:"done" bson:"done"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type DeadLetter struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	Job       string             `json:"job" bson:"job"`
+	ItemID    string             `json:"item_id" bson:"item_id"`
+	Error     string             `json:"error" bson:"error"`
+	Attempts  int                `json:"attempts" bson:"attempts"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+func (r *repository) GetCheckpoint(ctx context.Context, job string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{}
+	if err := r.mongo.FindOne(ctx, "job_checkpoints", bson.D{{
+		Key:   "_id",
+		Value: job,
+	}}).Decode(checkpoint); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &Checkpoint{Job: job}, nil
+		}
+
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+func (r *repository) SaveCheckpoint(ctx context.Context, checkpoint *Checkpoint) error {
+	checkpoint.UpdatedAt = time.Now()
+
+	if err := r.mongo.UpsertOne(ctx, "job_checkpoints", bson.D{{
+		Key:   "_id",
+		Value: checkpoint.Job,
+	}}, bson.D{{
+		Key:   "$set",
+		Value: checkpoint,
+	}}); err != nil {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ResetCheckpoint(ctx context.Context, job string) error {
+	return r.mongo.DeleteOne(ctx, "job_checkpoints", bson.D{{
+		Key:   "_id",
+		Value: job,
+	}})
+}
+
+func (r *repository) AddDeadLetter(ctx context.Context, letter *DeadLetter) error {
+	letter.ID = primitive.NewObjectIDFromTimestamp(time.Now())
+	letter.CreatedAt = time.Now()
+
+	if err := r.mongo.InsertOne(ctx, "job_dead_letters", letter); err != nil {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetDeadLetters(ctx context.Context, job string) ([]*DeadLetter, error) {
+	cur, err := r.mongo.Find(ctx, "job_dead_letters", bson.D{{
+		Key:   "job",
+		Value: job,
+	}}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*DeadLetter, 0)
+	if err := cur.All(ctx, &letters); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return letters, nil
+}
@@ -2,23 +2,65 @@ package locations
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/address"
+	"github.com/YusufOzmen01/veri-kontrol-backend/coords"
 	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/extract"
+	"github.com/YusufOzmen01/veri-kontrol-backend/geocode"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
 	"github.com/YusufOzmen01/veri-kontrol-backend/repository/users"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Repository interface {
 	GetLocations(ctx context.Context) ([]*LocationDB, error)
+	GetLocation(ctx context.Context, entryID int) (*LocationDB, error)
 	ResolveLocation(ctx context.Context, location *LocationDB) error
 	IsResolved(ctx context.Context, locationID int) (bool, error)
-	IsDuplicate(ctx context.Context, tweetContents string) (bool, error)
-	GetDocumentsWithNoTweetContents(ctx context.Context) ([]*LocationDB, error)
+	IsDuplicate(ctx context.Context, hash, tweetContents string) (bool, error)
+	GetDocumentsWithNoTweetContents(ctx context.Context, after primitive.ObjectID, limit int64) ([]*LocationDB, error)
+	CountDocumentsWithNoTweetContents(ctx context.Context, after primitive.ObjectID) (int64, error)
+	SetTweetContents(ctx context.Context, entryID int, tweetContents *pii.Protected) error
+	GetDocumentsWithPlainTweetContents(ctx context.Context, after primitive.ObjectID, limit int64) ([]*LocationDB, error)
+	CountDocumentsWithPlainTweetContents(ctx context.Context, after primitive.ObjectID) (int64, error)
+	GetDocumentsWithoutSignals(ctx context.Context, after primitive.ObjectID, limit int64) ([]*LocationDB, error)
+	CountDocumentsWithoutSignals(ctx context.Context, after primitive.ObjectID) (int64, error)
+	SetSignals(ctx context.Context, entryID int, signals *extract.Signals) error
+	FindLocations(ctx context.Context, filter *Filter) ([]*LocationDB, error)
+	GetResolvedIDs(ctx context.Context) (map[int]bool, error)
+	CountUnverified(ctx context.Context) (int64, error)
+	CountBySender(ctx context.Context) (map[string]int64, error)
+	GetUpdatedSince(ctx context.Context, since time.Time) ([]*LocationDB, error)
+	Watch(ctx context.Context, onChange func(location *LocationDB)) error
+	PurgeExpired(ctx context.Context, field string, before time.Time, dryRun bool) (int64, error)
+	FindMentions(ctx context.Context, phone, name string) ([]*LocationDB, error)
+	FindSentBy(ctx context.Context, senderIDs []primitive.ObjectID) ([]*LocationDB, error)
+	Purge(ctx context.Context, entryIDs []int, fields []string) (int64, error)
+	GetActiveSenders(ctx context.Context, since time.Time) (map[string]bool, error)
+	GetDocumentsWithoutAddress(ctx context.Context, after primitive.ObjectID, limit int64) ([]*LocationDB, error)
+	CountDocumentsWithoutAddress(ctx context.Context, after primitive.ObjectID) (int64, error)
+	SetParsedAddress(ctx context.Context, entryID int, parsed *address.Address) error
+	// SetOrphaned marks the resolutions whose entry is gone from the feed, orphaned false clears the mark.
+	SetOrphaned(ctx context.Context, entryIDs []int, orphaned bool) (int64, error)
+	// MoveLocation follows the feed when the entry moved, originalAddress is the map link of the new feed location. The
+	// geocode match of the old location is dropped.
+	MoveLocation(ctx context.Context, entryID int, location []float64, originalAddress string) error
+	// WithProject returns the repository narrowed to one project, the repository of NewRepository sees them all.
+	WithProject(projectID string) Repository
 }
 
+var ErrNotFound = errors.New("location not found")
+
 type repository struct {
-	mongo sources.MongoClient
+	mongo   sources.MongoClient
+	project string
 }
 
 func NewRepository(mongo sources.MongoClient) Repository {
@@ -27,6 +69,21 @@ func NewRepository(mongo sources.MongoClient) Repository {
 	}
 }
 
+func (r *repository) WithProject(projectID string) Repository {
+	return &repository{
+		mongo:   r.mongo,
+		project: projectID,
+	}
+}
+
+func (r *repository) scope(filter bson.D) bson.D {
+	if len(r.project) == 0 {
+		return filter
+	}
+
+	return append(bson.D{{Key: "project_id", Value: r.project}}, filter...)
+}
+
 type Location struct {
 	EntryID          int       `json:"entry_id"`
 	Loc              []float64 `json:"loc"`
@@ -35,13 +92,24 @@ type Location struct {
 	OriginalLocation string    `json:"original_location"`
 }
 
+// Legacy types, categories.Wreckage and categories.SupplyHelp in the category registry.
 const (
 	TypeWreckage   = 1
 	TypeSupplyHelp = 2
 )
 
+// Sources of a write, stored on the document so watchers can tell resolutions and admin updates apart.
+const (
+	SourceResolve = "resolve"
+	SourceAdmin   = "admin"
+	SourceImport  = "import"
+	// SourceReconcile is a resolution moved to the new location of its feed entry.
+	SourceReconcile = "reconcile"
+)
+
 type LocationDB struct {
 	ID               primitive.ObjectID `json:"_id" bson:"_id"`
+	ProjectID        string             `json:"project_id,omitempty" bson:"project_id,omitempty"`
 	EntryID          int                `json:"entry_id" bson:"entry_id"`
 	Sender           *users.User        `json:"sender" bson:"sender"`
 	Location         []float64          `json:"location" bson:"location"`
@@ -52,12 +120,49 @@ type LocationDB struct {
 	OpenAddress      string             `json:"open_address" bson:"open_address"`
 	Apartment        string             `json:"apartment" bson:"apartment"`
 	Type             int                `json:"type" bson:"type"`
-	Reason           string             `json:"reason" bson:"reason"`
-	TweetContents    string             `json:"tweet_contents" bson:"tweet_contents"`
+	// Category is the id in the category registry, Type keeps the legacy integer type of its top level category.
+	Category      string `json:"category,omitempty" bson:"category,omitempty"`
+	Reason        string `json:"reason" bson:"reason"`
+	TweetContents string `json:"tweet_contents" bson:"tweet_contents"`
+	// TweetContents is redacted, the raw text is only stored encrypted and is read through pii.Protector.Reveal.
+	TweetContentsEncrypted string           `json:"-" bson:"tweet_contents_enc,omitempty"`
+	TweetContentsHash      string           `json:"-" bson:"tweet_contents_hash,omitempty"`
+	DuplicateOf            int              `json:"duplicate_of,omitempty" bson:"duplicate_of,omitempty"`
+	Geo                    *GeoPoint        `json:"-" bson:"geo,omitempty"`
+	Signals                *extract.Signals `json:"signals,omitempty" bson:"signals,omitempty"`
+	// ParsedAddress is parsed from OpenAddress and Apartment, the raw text is kept as it was written.
+	ParsedAddress *address.Address `json:"parsed_address,omitempty" bson:"parsed_address,omitempty"`
+	// Geocode is the gazetteer match Location was taken from when no URL was given.
+	Geocode *geocode.Match `json:"geocode,omitempty" bson:"geocode,omitempty"`
+	// Provenance is the input Location was read from, CorrectedAddress keeps the raw new_address for old clients.
+	Provenance *coords.Provenance `json:"provenance,omitempty" bson:"provenance,omitempty"`
+	Source     string             `json:"source,omitempty" bson:"source,omitempty"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+	// Purged lists the personal fields removed by retention or erasure, the backfills don't fill them again.
+	Purged []string `json:"purged,omitempty" bson:"purged,omitempty"`
+	// OrphanedAt is set by the reconciliation when the entry is no longer in the feed.
+	OrphanedAt *time.Time `json:"orphaned_at,omitempty" bson:"orphaned_at,omitempty"`
+}
+
+// GeoPoint is the GeoJSON form of Location used by the 2dsphere index, coordinates are in lng, lat order.
+type GeoPoint struct {
+	Type        string    `bson:"type"`
+	Coordinates []float64 `bson:"coordinates"`
+}
+
+func NewGeoPoint(location []float64) *GeoPoint {
+	if len(location) != 2 || (location[0] == 0 && location[1] == 0) {
+		return nil
+	}
+
+	return &GeoPoint{
+		Type:        "Point",
+		Coordinates: []float64{location[1], location[0]},
+	}
 }
 
 func (r *repository) GetLocations(ctx context.Context) ([]*LocationDB, error) {
-	cur, err := r.mongo.Find(ctx, "locations", bson.D{})
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(bson.D{}))
 	if err != nil {
 		return nil, err
 	}
@@ -71,11 +176,33 @@ func (r *repository) GetLocations(ctx context.Context) ([]*LocationDB, error) {
 	return locs, nil
 }
 
+func (r *repository) GetLocation(ctx context.Context, entryID int) (*LocationDB, error) {
+	loc := &LocationDB{}
+	if err := r.mongo.FindOne(ctx, "locations", r.scope(bson.D{{
+		Key:   "entry_id",
+		Value: entryID,
+	}})).Decode(loc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return loc, nil
+}
+
 func (r *repository) ResolveLocation(ctx context.Context, location *LocationDB) error {
-	if err := r.mongo.DeleteOne(ctx, "locations", bson.D{{
+	location.Geo = NewGeoPoint(location.Location)
+	location.UpdatedAt = time.Now()
+	if len(r.project) > 0 {
+		location.ProjectID = r.project
+	}
+
+	if err := r.mongo.DeleteOne(ctx, "locations", r.scope(bson.D{{
 		Key:   "entry_id",
 		Value: location.EntryID,
-	}}); err != nil {
+	}})); err != nil {
 		logrus.Errorln(err)
 
 		return err
@@ -91,10 +218,10 @@ func (r *repository) ResolveLocation(ctx context.Context, location *LocationDB)
 }
 
 func (r *repository) IsResolved(ctx context.Context, locationID int) (bool, error) {
-	exists, err := r.mongo.DoesExist(ctx, "locations", bson.D{{
+	exists, err := r.mongo.DoesExist(ctx, "locations", r.scope(bson.D{{
 		Key:   "entry_id",
 		Value: locationID,
-	}})
+	}}))
 	if err != nil {
 		return false, err
 	}
@@ -102,11 +229,18 @@ func (r *repository) IsResolved(ctx context.Context, locationID int) (bool, erro
 	return exists, nil
 }
 
-func (r *repository) IsDuplicate(ctx context.Context, tweetContents string) (bool, error) {
-	exists, err := r.mongo.DoesExist(ctx, "locations", bson.D{{
-		Key:   "tweet_contents",
-		Value: tweetContents,
-	}})
+// IsDuplicate matches by the hash of the raw text, and by the text itself for documents not protected yet.
+func (r *repository) IsDuplicate(ctx context.Context, hash, tweetContents string) (bool, error) {
+	exists, err := r.mongo.DoesExist(ctx, "locations", r.scope(bson.D{{
+		Key: "$or",
+		Value: bson.A{
+			bson.D{{Key: "tweet_contents_hash", Value: hash}},
+			bson.D{
+				{Key: "tweet_contents", Value: tweetContents},
+				{Key: "tweet_contents_hash", Value: bson.D{{Key: "$exists", Value: false}}},
+			},
+		},
+	}}))
 	if err != nil {
 		return false, err
 	}
@@ -114,13 +248,174 @@ func (r *repository) IsDuplicate(ctx context.Context, tweetContents string) (boo
 	return exists, nil
 }
 
-func (r *repository) GetDocumentsWithNoTweetContents(ctx context.Context) ([]*LocationDB, error) {
+func noTweetContentsFilter(after primitive.ObjectID) bson.D {
+	filter := bson.D{
+		{Key: "tweet_contents", Value: bson.D{{Key: "$in", Value: bson.A{nil, ""}}}},
+		{Key: "purged", Value: bson.D{{Key: "$ne", Value: FieldTweetContents}}},
+	}
+
+	if !after.IsZero() {
+		filter = append(filter, bson.E{
+			Key:   "_id",
+			Value: bson.D{{Key: "$gt", Value: after}},
+		})
+	}
+
+	return filter
+}
+
+func (r *repository) GetDocumentsWithNoTweetContents(ctx context.Context, after primitive.ObjectID, limit int64) ([]*LocationDB, error) {
 	// FOR OLD DB COLLECTIONS ONLY, update the tweet_contents for old tweet data where it does not exist, or is empty
 	// Do not use in app
-	cur, err := r.mongo.Find(ctx, "locations", bson.D{{
-		Key:   "tweet_contents",
-		Value: nil,
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(noTweetContentsFilter(after)), options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	locs := make([]*LocationDB, 0)
+	if err := cur.All(ctx, &locs); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return locs, nil
+}
+
+func (r *repository) CountDocumentsWithNoTweetContents(ctx context.Context, after primitive.ObjectID) (int64, error) {
+	return r.mongo.Count(ctx, "locations", r.scope(noTweetContentsFilter(after)))
+}
+
+func (r *repository) SetTweetContents(ctx context.Context, entryID int, tweetContents *pii.Protected) error {
+	if err := r.mongo.UpdateOne(ctx, "locations", r.scope(bson.D{{
+		Key:   "entry_id",
+		Value: entryID,
+	}}), bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "tweet_contents", Value: tweetContents.Redacted},
+			{Key: "tweet_contents_enc", Value: tweetContents.Encrypted},
+			{Key: "tweet_contents_hash", Value: tweetContents.Hash},
+		},
+	}}); err != nil {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func plainTweetContentsFilter(after primitive.ObjectID) bson.D {
+	filter := bson.D{
+		{Key: "tweet_contents", Value: bson.D{{Key: "$nin", Value: bson.A{nil, ""}}}},
+		{Key: "tweet_contents_enc", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+
+	if !after.IsZero() {
+		filter = append(filter, bson.E{
+			Key:   "_id",
+			Value: bson.D{{Key: "$gt", Value: after}},
+		})
+	}
+
+	return filter
+}
+
+// GetDocumentsWithPlainTweetContents returns the documents stored before the tweet contents were redacted.
+func (r *repository) GetDocumentsWithPlainTweetContents(ctx context.Context, after primitive.ObjectID, limit int64) ([]*LocationDB, error) {
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(plainTweetContentsFilter(after)), options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	locs := make([]*LocationDB, 0)
+	if err := cur.All(ctx, &locs); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return locs, nil
+}
+
+func (r *repository) CountDocumentsWithPlainTweetContents(ctx context.Context, after primitive.ObjectID) (int64, error) {
+	return r.mongo.Count(ctx, "locations", r.scope(plainTweetContentsFilter(after)))
+}
+
+func withoutAddressFilter(after primitive.ObjectID) bson.D {
+	filter := bson.D{
+		{Key: "$or", Value: bson.A{notEmpty("open_address"), notEmpty("apartment")}},
+		{Key: "$and", Value: bson.A{bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "parsed_address", Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{{Key: "parsed_address.version", Value: bson.D{{Key: "$lt", Value: address.Version}}}},
+		}}}}},
+	}
+
+	if !after.IsZero() {
+		filter = append(filter, bson.E{
+			Key:   "_id",
+			Value: bson.D{{Key: "$gt", Value: after}},
+		})
+	}
+
+	return filter
+}
+
+// GetDocumentsWithoutAddress returns the documents whose address was never parsed or parsed by an older parser.
+func (r *repository) GetDocumentsWithoutAddress(ctx context.Context, after primitive.ObjectID, limit int64) ([]*LocationDB, error) {
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(withoutAddressFilter(after)), options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	locs := make([]*LocationDB, 0)
+	if err := cur.All(ctx, &locs); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return locs, nil
+}
+
+func (r *repository) CountDocumentsWithoutAddress(ctx context.Context, after primitive.ObjectID) (int64, error) {
+	return r.mongo.Count(ctx, "locations", r.scope(withoutAddressFilter(after)))
+}
+
+func (r *repository) SetParsedAddress(ctx context.Context, entryID int, parsed *address.Address) error {
+	return r.mongo.UpdateOne(ctx, "locations", r.scope(bson.D{{
+		Key:   "entry_id",
+		Value: entryID,
+	}}), bson.D{{
+		Key:   "$set",
+		Value: bson.D{{Key: "parsed_address", Value: parsed}},
 	}})
+}
+
+func withoutSignalsFilter(after primitive.ObjectID) bson.D {
+	filter := bson.D{
+		{Key: "signals", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "tweet_contents", Value: bson.D{{Key: "$nin", Value: bson.A{nil, ""}}}},
+	}
+
+	if !after.IsZero() {
+		filter = append(filter, bson.E{
+			Key:   "_id",
+			Value: bson.D{{Key: "$gt", Value: after}},
+		})
+	}
+
+	return filter
+}
+
+func (r *repository) GetDocumentsWithoutSignals(ctx context.Context, after primitive.ObjectID, limit int64) ([]*LocationDB, error) {
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(withoutSignalsFilter(after)), options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(limit))
 	if err != nil {
 		return nil, err
 	}
@@ -133,3 +428,240 @@ func (r *repository) GetDocumentsWithNoTweetContents(ctx context.Context) ([]*Lo
 
 	return locs, nil
 }
+
+func (r *repository) CountDocumentsWithoutSignals(ctx context.Context, after primitive.ObjectID) (int64, error) {
+	return r.mongo.Count(ctx, "locations", r.scope(withoutSignalsFilter(after)))
+}
+
+func (r *repository) SetSignals(ctx context.Context, entryID int, signals *extract.Signals) error {
+	return r.mongo.UpdateOne(ctx, "locations", r.scope(bson.D{{
+		Key:   "entry_id",
+		Value: entryID,
+	}}), bson.D{{
+		Key:   "$set",
+		Value: bson.D{{Key: "signals", Value: signals}},
+	}})
+}
+
+func (r *repository) SetOrphaned(ctx context.Context, entryIDs []int, orphaned bool) (int64, error) {
+	if len(entryIDs) == 0 {
+		return 0, nil
+	}
+
+	// İşaretliyken tekrar işaretlemiyoruz, ilk görüldüğü zaman kalsın
+	filter := bson.D{
+		{Key: "entry_id", Value: bson.D{{Key: "$in", Value: entryIDs}}},
+		{Key: "orphaned_at", Value: bson.D{{Key: "$exists", Value: !orphaned}}},
+	}
+
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "orphaned_at", Value: ""}}}}
+	if orphaned {
+		update = bson.D{{Key: "$set", Value: bson.D{{Key: "orphaned_at", Value: time.Now()}}}}
+	}
+
+	modified, err := r.mongo.UpdateMany(ctx, "locations", r.scope(filter), update)
+	if err != nil {
+		logrus.Errorln(err)
+
+		return 0, err
+	}
+
+	return modified, nil
+}
+
+func (r *repository) MoveLocation(ctx context.Context, entryID int, location []float64, originalAddress string) error {
+	// updated_at değişmezse taşınan kayıt dashboard'lara ve webhook'lara gitmez, eski konumun eşleşmesi de silinir
+	return r.mongo.UpdateOne(ctx, "locations", r.scope(bson.D{{
+		Key:   "entry_id",
+		Value: entryID,
+	}}), bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "location", Value: location},
+			{Key: "geo", Value: NewGeoPoint(location)},
+			{Key: "original_address", Value: originalAddress},
+			{Key: "source", Value: SourceReconcile},
+			{Key: "updated_at", Value: time.Now()},
+		}},
+		{Key: "$unset", Value: bson.D{{Key: "geocode", Value: ""}}},
+	})
+}
+
+// Filter narrows the admin listing, zero values don't filter. The address components ignore case and the Turkish
+// letters, Il and Ilce must match exactly, Mahalle and Street only partly.
+type Filter struct {
+	MinUrgency extract.Urgency
+	Needs      []string
+	HasPhone   bool
+	MinPeople  int
+	Il         string
+	Ilce       string
+	Mahalle    string
+	Street     string
+	Category   string
+}
+
+func (r *repository) FindLocations(ctx context.Context, filter *Filter) ([]*LocationDB, error) {
+	query := bson.D{}
+	if filter.MinUrgency > extract.UrgencyNone {
+		query = append(query, bson.E{Key: "signals.urgency", Value: bson.D{{Key: "$gte", Value: filter.MinUrgency}}})
+	}
+	if len(filter.Needs) > 0 {
+		query = append(query, bson.E{Key: "signals.needs", Value: bson.D{{Key: "$all", Value: filter.Needs}}})
+	}
+	if filter.HasPhone {
+		query = append(query, bson.E{Key: "signals.phones.0", Value: bson.D{{Key: "$exists", Value: true}}})
+	}
+	if filter.MinPeople > 0 {
+		query = append(query, bson.E{Key: "signals.people", Value: bson.D{{Key: "$gte", Value: filter.MinPeople}}})
+	}
+	if len(filter.Il) > 0 {
+		query = append(query, bson.E{Key: "parsed_address.il", Value: primitive.Regex{Pattern: "^" + address.Pattern(filter.Il) + "$", Options: "i"}})
+	}
+	if len(filter.Ilce) > 0 {
+		query = append(query, bson.E{Key: "parsed_address.ilce", Value: primitive.Regex{Pattern: "^" + address.Pattern(filter.Ilce) + "$", Options: "i"}})
+	}
+	if len(filter.Mahalle) > 0 {
+		query = append(query, bson.E{Key: "parsed_address.mahalle", Value: primitive.Regex{Pattern: address.Pattern(filter.Mahalle), Options: "i"}})
+	}
+	if len(filter.Street) > 0 {
+		street := primitive.Regex{Pattern: address.Pattern(filter.Street), Options: "i"}
+		query = append(query, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "parsed_address.cadde", Value: street}},
+			bson.D{{Key: "parsed_address.sokak", Value: street}},
+		}})
+	}
+	if len(filter.Category) > 0 {
+		query = append(query, bson.E{Key: "category", Value: filter.Category})
+	}
+
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(query))
+	if err != nil {
+		return nil, err
+	}
+
+	locs := make([]*LocationDB, 0)
+	if err := cur.All(ctx, &locs); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return locs, nil
+}
+
+// GetResolvedIDs only reads the entry ids, GetLocations is too heavy to run periodically.
+func (r *repository) GetResolvedIDs(ctx context.Context) (map[int]bool, error) {
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(bson.D{}), options.Find().SetProjection(bson.D{{Key: "entry_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]struct {
+		EntryID int `bson:"entry_id"`
+	}, 0)
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[int]bool, len(docs))
+	for _, doc := range docs {
+		ids[doc.EntryID] = true
+	}
+
+	return ids, nil
+}
+
+// CountUnverified counts the resolutions waiting for a moderator.
+func (r *repository) CountUnverified(ctx context.Context) (int64, error) {
+	return r.mongo.Count(ctx, "locations", r.scope(bson.D{{Key: "verified", Value: bson.D{{Key: "$ne", Value: true}}}}))
+}
+
+// CountBySender counts the resolutions of each user, keyed by the hex id. Admin updates have no sender.
+func (r *repository) CountBySender(ctx context.Context) (map[string]int64, error) {
+	cur, err := r.mongo.Aggregate(ctx, "locations", mongo.Pipeline{
+		{{Key: "$match", Value: r.scope(bson.D{{Key: "sender._id", Value: bson.D{{Key: "$exists", Value: true}}}})}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$sender._id"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}, 0)
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(groups))
+	for _, g := range groups {
+		counts[g.ID.Hex()] = g.Count
+	}
+
+	return counts, nil
+}
+
+// GetUpdatedSince returns the documents written after since, oldest first. It is the fallback of Watch on standalone
+// servers.
+func (r *repository) GetUpdatedSince(ctx context.Context, since time.Time) ([]*LocationDB, error) {
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(bson.D{{
+		Key:   "updated_at",
+		Value: bson.D{{Key: "$gt", Value: since}},
+	}}), options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	locs := make([]*LocationDB, 0)
+	if err := cur.All(ctx, &locs); err != nil {
+		return nil, err
+	}
+
+	return locs, nil
+}
+
+// Watch calls onChange for every resolution written to the collection until ctx is done. Deletes of the
+// delete+insert in ResolveLocation and writes that don't touch updated_at (like backfills) are ignored.
+func (r *repository) Watch(ctx context.Context, onChange func(location *LocationDB)) error {
+	pipeline := mongo.Pipeline{{{
+		Key: "$match",
+		Value: bson.D{{
+			Key: "$or",
+			Value: bson.A{
+				bson.D{{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "replace"}}}}},
+				bson.D{{Key: "updateDescription.updatedFields.updated_at", Value: bson.D{{Key: "$exists", Value: true}}}},
+			},
+		}},
+	}}}
+	if len(r.project) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: "fullDocument.project_id", Value: r.project}}}})
+	}
+
+	stream, err := r.mongo.Watch(ctx, "locations", pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		change := struct {
+			FullDocument *LocationDB `bson:"fullDocument"`
+		}{}
+
+		if err := stream.Decode(&change); err != nil {
+			logrus.Errorln(err)
+
+			continue
+		}
+
+		// Update sonrası doküman silinmiş olabilir
+		if change.FullDocument != nil {
+			onChange(change.FullDocument)
+		}
+	}
+
+	return stream.Err()
+}
@@ -0,0 +1,243 @@
+package locations
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Personal fields of a resolution. The coordinates, type and signals other than the phones are not personal and
+// are kept for statistics.
+const (
+	FieldTweetContents = "tweet_contents"
+	FieldOpenAddress   = "open_address"
+	FieldApartment     = "apartment"
+	FieldSender        = "sender"
+)
+
+var PersonalFields = []string{FieldTweetContents, FieldOpenAddress, FieldApartment, FieldSender}
+
+// ContentFields mention the victims, FieldSender is the volunteer who resolved the entry.
+var ContentFields = []string{FieldTweetContents, FieldOpenAddress, FieldApartment}
+
+func notEmpty(field string) bson.D {
+	return bson.D{{Key: field, Value: bson.D{{Key: "$nin", Value: bson.A{nil, ""}}}}}
+}
+
+func exists(field string) bson.D {
+	return bson.D{{Key: field, Value: bson.D{{Key: "$exists", Value: true}}}}
+}
+
+// present matches the documents that still hold the field.
+func present(field string) (bson.D, error) {
+	switch field {
+	case FieldTweetContents:
+		return bson.D{{Key: "$or", Value: bson.A{
+			notEmpty("tweet_contents"),
+			exists("tweet_contents_enc"),
+			exists("signals.phones.0"),
+		}}}, nil
+	case FieldOpenAddress, FieldApartment:
+		return notEmpty(field), nil
+	case FieldSender:
+		return bson.D{{Key: "$or", Value: bson.A{
+			notEmpty("sender.name"),
+			notEmpty("sender.discord"),
+			exists("sender.auth_key_hash"),
+		}}}, nil
+	default:
+		return nil, fmt.Errorf("unknown personal field %q", field)
+	}
+}
+
+// PurgeUpdate removes the fields, tweet_contents_hash is kept so a purged tweet isn't served as a new entry again. The
+// restore applies it again to the documents purged since their snapshot.
+func PurgeUpdate(fields []string) (bson.D, error) {
+	set := bson.D{}
+	unset := bson.D{}
+	parsedAddress := false
+
+	for _, field := range fields {
+		switch field {
+		case FieldTweetContents:
+			set = append(set, bson.E{Key: "tweet_contents", Value: ""})
+			unset = append(unset, bson.E{Key: "tweet_contents_enc", Value: ""}, bson.E{Key: "signals.phones", Value: ""})
+		case FieldOpenAddress, FieldApartment:
+			set = append(set, bson.E{Key: field, Value: ""})
+			parsedAddress = true
+		case FieldSender:
+			unset = append(unset,
+				bson.E{Key: "sender.name", Value: ""},
+				bson.E{Key: "sender.discord", Value: ""},
+				bson.E{Key: "sender.auth_key_hash", Value: ""})
+		default:
+			return nil, fmt.Errorf("unknown personal field %q", field)
+		}
+	}
+
+	// Ayrıştırılmış adres de aynı kişisel veriyi taşıyor
+	if parsedAddress {
+		unset = append(unset, bson.E{Key: "parsed_address", Value: ""})
+	}
+
+	update := bson.D{{Key: "$addToSet", Value: bson.D{{Key: "purged", Value: bson.D{{Key: "$each", Value: fields}}}}}}
+	if len(set) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+
+	return update, nil
+}
+
+// PurgeExpired purges the field of the resolutions last written before the given time. Documents older than
+// updated_at are dated by their _id.
+func (r *repository) PurgeExpired(ctx context.Context, field string, before time.Time, dryRun bool) (int64, error) {
+	filter, err := present(field)
+	if err != nil {
+		return 0, err
+	}
+
+	filter = bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "updated_at", Value: bson.D{{Key: "$lt", Value: before}}}},
+		bson.D{
+			{Key: "updated_at", Value: bson.D{{Key: "$exists", Value: false}}},
+			{Key: "_id", Value: bson.D{{Key: "$lt", Value: primitive.NewObjectIDFromTimestamp(before)}}},
+		},
+	}}}}}}
+
+	if dryRun {
+		return r.mongo.Count(ctx, "locations", r.scope(filter))
+	}
+
+	update, err := PurgeUpdate([]string{field})
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := r.mongo.UpdateMany(ctx, "locations", r.scope(filter), update)
+	if err != nil {
+		logrus.Errorln(err)
+
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// phonePattern matches the national number with any separators in between, like 0532 123 45 67.
+func phonePattern(phone string) string {
+	digits := strings.Split(strings.TrimPrefix(phone, "+90"), "")
+
+	return strings.Join(digits, `[\s\-.()]*`)
+}
+
+// FindMentions returns the resolutions whose content mentions the phone or the name, either may be empty. Names
+// are searched in the redacted tweet, redaction only removes numbers and addresses.
+func (r *repository) FindMentions(ctx context.Context, phone, name string) ([]*LocationDB, error) {
+	or := bson.A{}
+
+	if len(phone) > 0 {
+		pattern := primitive.Regex{Pattern: phonePattern(phone)}
+		or = append(or,
+			bson.D{{Key: "signals.phones", Value: phone}},
+			bson.D{{Key: "open_address", Value: pattern}},
+			bson.D{{Key: "apartment", Value: pattern}})
+	}
+
+	if len(name) > 0 {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(name), Options: "i"}
+		for _, field := range ContentFields {
+			or = append(or, bson.D{{Key: field, Value: pattern}})
+		}
+	}
+
+	if len(or) == 0 {
+		return []*LocationDB{}, nil
+	}
+
+	return r.find(ctx, bson.D{{Key: "$or", Value: or}})
+}
+
+func (r *repository) FindSentBy(ctx context.Context, senderIDs []primitive.ObjectID) ([]*LocationDB, error) {
+	if len(senderIDs) == 0 {
+		return []*LocationDB{}, nil
+	}
+
+	return r.find(ctx, bson.D{{Key: "sender._id", Value: bson.D{{Key: "$in", Value: senderIDs}}}})
+}
+
+func (r *repository) find(ctx context.Context, filter bson.D) ([]*LocationDB, error) {
+	cur, err := r.mongo.Find(ctx, "locations", r.scope(filter))
+	if err != nil {
+		return nil, err
+	}
+
+	locs := make([]*LocationDB, 0)
+	if err := cur.All(ctx, &locs); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return locs, nil
+}
+
+// Purge removes the personal fields of the resolutions, the rest of the documents is kept.
+func (r *repository) Purge(ctx context.Context, entryIDs []int, fields []string) (int64, error) {
+	if len(entryIDs) == 0 || len(fields) == 0 {
+		return 0, nil
+	}
+
+	update, err := PurgeUpdate(fields)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := r.mongo.UpdateMany(ctx, "locations", r.scope(bson.D{{
+		Key:   "entry_id",
+		Value: bson.D{{Key: "$in", Value: entryIDs}},
+	}}), update)
+	if err != nil {
+		logrus.Errorln(err)
+
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// GetActiveSenders returns the ids of the users who wrote a resolution since the given time.
+func (r *repository) GetActiveSenders(ctx context.Context, since time.Time) (map[string]bool, error) {
+	cur, err := r.mongo.Aggregate(ctx, "locations", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "updated_at", Value: bson.D{{Key: "$gte", Value: since}}},
+			{Key: "sender._id", Value: bson.D{{Key: "$exists", Value: true}}},
+		}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$sender._id"}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}, 0)
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	active := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		active[g.ID.Hex()] = true
+	}
+
+	return active, nil
+}
@@ -0,0 +1,96 @@
+package locations
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPurgeUpdate(t *testing.T) {
+	addToSet := func(fields ...string) bson.E {
+		return bson.E{Key: "$addToSet", Value: bson.D{{Key: "purged", Value: bson.D{{Key: "$each", Value: fields}}}}}
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   bson.D
+	}{
+		{"tweet contents", []string{FieldTweetContents}, bson.D{
+			addToSet(FieldTweetContents),
+			{Key: "$set", Value: bson.D{{Key: "tweet_contents", Value: ""}}},
+			{Key: "$unset", Value: bson.D{{Key: "tweet_contents_enc", Value: ""}, {Key: "signals.phones", Value: ""}}},
+		}},
+		{"address drops the parsed address", []string{FieldOpenAddress}, bson.D{
+			addToSet(FieldOpenAddress),
+			{Key: "$set", Value: bson.D{{Key: "open_address", Value: ""}}},
+			{Key: "$unset", Value: bson.D{{Key: "parsed_address", Value: ""}}},
+		}},
+		{"sender keeps the id", []string{FieldSender}, bson.D{
+			addToSet(FieldSender),
+			{Key: "$unset", Value: bson.D{
+				{Key: "sender.name", Value: ""},
+				{Key: "sender.discord", Value: ""},
+				{Key: "sender.auth_key_hash", Value: ""},
+			}},
+		}},
+		{"content fields", ContentFields, bson.D{
+			addToSet(ContentFields...),
+			{Key: "$set", Value: bson.D{
+				{Key: "tweet_contents", Value: ""},
+				{Key: "open_address", Value: ""},
+				{Key: "apartment", Value: ""},
+			}},
+			{Key: "$unset", Value: bson.D{
+				{Key: "tweet_contents_enc", Value: ""},
+				{Key: "signals.phones", Value: ""},
+				{Key: "parsed_address", Value: ""},
+			}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PurgeUpdate(tt.fields)
+			if err != nil {
+				t.Fatalf("PurgeUpdate(%v) failed: %s", tt.fields, err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PurgeUpdate(%v) = %v, want %v", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeUpdateUnknownField(t *testing.T) {
+	if _, err := PurgeUpdate([]string{FieldSender, "location"}); err == nil {
+		t.Error("PurgeUpdate() with an unknown field succeeded, want an error")
+	}
+}
+
+func TestPhonePattern(t *testing.T) {
+	re := regexp.MustCompile(phonePattern("+905321234567"))
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"5321234567", true},
+		{"0532 123 45 67", true},
+		{"(532) 123-45-67", true},
+		{"532.123.45.67", true},
+		{"Kat 2, tel 0532 123 4567", true},
+		{"0532 123 45 68", false},
+		{"0532 123 45 6", false},
+		{"532 x 123 45 67", false},
+	}
+
+	for _, tt := range tests {
+		if got := re.MatchString(tt.text); got != tt.want {
+			t.Errorf("phonePattern matches %q = %t, want %t", tt.text, got, tt.want)
+		}
+	}
+}
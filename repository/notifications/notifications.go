@@ -0,0 +1,50 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	// Claim stores value for key if it is greater than the stored one and reports whether it did. Every replica runs
+	// the notifier, only the one claiming a milestone or an alert window posts it.
+	Claim(ctx context.Context, key string, value int64) (bool, error)
+}
+
+type repository struct {
+	mongo sources.MongoClient
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+func (r *repository) Claim(ctx context.Context, key string, value int64) (bool, error) {
+	// Daha büyük değer zaten varsa filtre eşleşmez, upsert de _id çakışmasıyla başarısız olur
+	err := r.mongo.FindOneAndUpdate(ctx, "notification_claims", bson.D{
+		{Key: "_id", Value: key},
+		{Key: "value", Value: bson.D{{Key: "$lt", Value: value}}},
+	}, bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "value", Value: value},
+			{Key: "claimed_at", Value: time.Now()},
+		},
+	}}, options.FindOneAndUpdate().SetUpsert(true)).Err()
+
+	switch {
+	case err == nil, err == mongo.ErrNoDocuments:
+		return true, nil
+	case mongo.IsDuplicateKeyError(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
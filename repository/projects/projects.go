@@ -0,0 +1,264 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/categories"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	CreateProject(ctx context.Context, project *Project) error
+	GetProject(ctx context.Context, id string) (*Project, error)
+	GetProjects(ctx context.Context, includeArchived bool) ([]*Project, error)
+	// ArchiveProject keeps the data of the project readable, new resolutions and skips are refused.
+	ArchiveProject(ctx context.Context, id, archivedBy string) error
+	// EnsureProject creates the project or overwrites its feed, regions and categories, the status is kept.
+	EnsureProject(ctx context.Context, project *Project) error
+}
+
+var (
+	ErrNotFound = errors.New("project not found")
+	ErrExists   = errors.New("project already exists")
+)
+
+// DefaultID is the project of the records written before projects existed and of the routes without a project.
+const DefaultID = "default"
+
+const (
+	StatusActive   = "active"
+	StatusArchived = "archived"
+)
+
+const collection = "projects"
+
+type repository struct {
+	mongo sources.MongoClient
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+// Feed overrides the areas and single URLs of the feed config, empty fields use the config.
+type Feed struct {
+	AreasURL  string `json:"areas_url,omitempty" bson:"areas_url,omitempty"`
+	SingleURL string `json:"single_url,omitempty" bson:"single_url,omitempty"`
+}
+
+// Region is a box used by /get-location?city_id=, ne_lat, ne_lng, sw_lat, sw_lng.
+type Region struct {
+	ID   int       `json:"id" bson:"id"`
+	Name string    `json:"name,omitempty" bson:"name,omitempty"`
+	Box  []float64 `json:"box" bson:"box"`
+}
+
+type Project struct {
+	ID      string   `json:"_id" bson:"_id"`
+	Name    string   `json:"name" bson:"name"`
+	Status  string   `json:"status" bson:"status"`
+	Feed    Feed     `json:"feed" bson:"feed"`
+	Regions []Region `json:"regions" bson:"regions"`
+	// Categories are the category ids accepted by /resolve, a top level category allows its sub-categories too. Empty
+	// accepts every category.
+	Categories []string   `json:"categories" bson:"categories"`
+	CreatedBy  string     `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	ArchivedBy string     `json:"archived_by,omitempty" bson:"archived_by,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+}
+
+func (p *Project) Archived() bool {
+	return p.Status == StatusArchived
+}
+
+// Region returns the box of the region, nil if the project has no such region.
+func (p *Project) Region(id int) []float64 {
+	for _, region := range p.Regions {
+		if region.ID == id {
+			return region.Box
+		}
+	}
+
+	return nil
+}
+
+// RegionOf returns the region the location is in, the one with the lowest id when the boxes overlap, 0 if none.
+func (p *Project) RegionOf(location []float64) int {
+	if len(location) != 2 {
+		return 0
+	}
+
+	region := 0
+	for _, r := range p.Regions {
+		box := r.Box
+		if len(box) != 4 || (region > 0 && r.ID > region) {
+			continue
+		}
+
+		if box[0] >= location[0] && box[1] >= location[1] && box[2] <= location[0] && box[3] <= location[1] {
+			region = r.ID
+		}
+	}
+
+	return region
+}
+
+// Area returns the boxes of the regions, the default project also accepts the whole AffectedRegion.
+func (p *Project) Area() validation.Area {
+	area := make(validation.Area, 0, len(p.Regions)+1)
+	for _, region := range p.Regions {
+		if box := region.Box; len(box) == 4 {
+			area = append(area, validation.Bounds{MinLat: box[2], MaxLat: box[0], MinLng: box[3], MaxLng: box[1]})
+		}
+	}
+
+	if p.ID == DefaultID {
+		area = append(area, validation.AffectedRegion)
+	}
+
+	return area
+}
+
+func (p *Project) AllowsCategory(category *categories.Category) bool {
+	if len(p.Categories) == 0 {
+		return true
+	}
+
+	for _, id := range p.Categories {
+		if id == category.ID || id == category.Parent {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Proje id'si route'larda ve users.projects alanında anahtar olarak kullanılıyor, nokta ve $ içeremez
+var idRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+func (p *Project) Validate() error {
+	errs := make(validation.Errors, 0)
+
+	if !idRe.MatchString(p.ID) {
+		errs.Add("_id", "must be 2 to 63 lowercase letters, digits or dashes")
+	}
+	if len(p.Name) == 0 {
+		errs.Add("name", "is required")
+	}
+
+	seen := make(map[int]bool)
+	for _, region := range p.Regions {
+		if seen[region.ID] {
+			errs.Add("regions", "region %d is given twice", region.ID)
+		}
+		seen[region.ID] = true
+
+		if len(region.Box) != 4 {
+			errs.Add("regions", "region %d must have 4 coordinates", region.ID)
+		} else if region.Box[0] < region.Box[2] || region.Box[1] < region.Box[3] {
+			errs.Add("regions", "region %d: north east corner must be above and right of the south west corner", region.ID)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (r *repository) CreateProject(ctx context.Context, project *Project) error {
+	project.Status = StatusActive
+	project.CreatedAt = time.Now()
+
+	if err := r.mongo.InsertOne(ctx, collection, project); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrExists
+		}
+
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetProject(ctx context.Context, id string) (*Project, error) {
+	project := &Project{}
+	if err := r.mongo.FindOne(ctx, collection, bson.D{{Key: "_id", Value: id}}).Decode(project); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (r *repository) GetProjects(ctx context.Context, includeArchived bool) ([]*Project, error) {
+	filter := bson.D{}
+	if !includeArchived {
+		filter = bson.D{{Key: "status", Value: bson.D{{Key: "$ne", Value: StatusArchived}}}}
+	}
+
+	cur, err := r.mongo.Find(ctx, collection, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make([]*Project, 0)
+	if err := cur.All(ctx, &projects); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+func (r *repository) ArchiveProject(ctx context.Context, id, archivedBy string) error {
+	res := r.mongo.FindOneAndUpdate(ctx, collection, bson.D{{Key: "_id", Value: id}}, bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "status", Value: StatusArchived},
+			{Key: "archived_by", Value: archivedBy},
+			{Key: "archived_at", Value: time.Now()},
+		},
+	}})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) EnsureProject(ctx context.Context, project *Project) error {
+	return r.mongo.UpdateOne(ctx, collection, bson.D{{Key: "_id", Value: project.ID}}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "name", Value: project.Name},
+			{Key: "feed", Value: project.Feed},
+			{Key: "regions", Value: project.Regions},
+			{Key: "categories", Value: project.Categories},
+		}},
+		{Key: "$setOnInsert", Value: bson.D{
+			{Key: "status", Value: StatusActive},
+			{Key: "created_at", Value: time.Now()},
+		}},
+	}, options.Update().SetUpsert(true))
+}
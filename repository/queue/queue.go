@@ -0,0 +1,212 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	// Acquire leases an entry to holder until the given time. It fails if someone else holds an unexpired lease, the
+	// holder of a lease can extend it.
+	Acquire(ctx context.Context, entryID int, holder string, until time.Time) (bool, error)
+	Release(ctx context.Context, entryID int) error
+	GetActiveLeases(ctx context.Context) (map[int]*Lease, error)
+	// GetExpired returns the leases that expired within (from, to]. Released leases are deleted and never expire.
+	GetExpired(ctx context.Context, from, to time.Time) ([]*Lease, error)
+
+	// GetWeights returns nil when the weights were never changed at runtime.
+	GetWeights(ctx context.Context) (*config.Weights, error)
+	SetWeights(ctx context.Context, weights *config.Weights, updatedBy string) error
+
+	SetUrgency(ctx context.Context, entryID int, urgency float64) error
+	GetUrgencies(ctx context.Context) (map[int]float64, error)
+
+	// WithProject returns the repository narrowed to one project. Leases and urgencies are only written through it,
+	// entry ids of different projects may collide.
+	WithProject(projectID string) Repository
+}
+
+const (
+	leasesCollection   = "queue_leases"
+	settingsCollection = "queue_settings"
+	signalsCollection  = "queue_signals"
+)
+
+type repository struct {
+	mongo   sources.MongoClient
+	project string
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+func (r *repository) WithProject(projectID string) Repository {
+	return &repository{
+		mongo:   r.mongo,
+		project: projectID,
+	}
+}
+
+func (r *repository) scope(filter bson.D) bson.D {
+	if len(r.project) == 0 {
+		return filter
+	}
+
+	return append(bson.D{{Key: "project_id", Value: r.project}}, filter...)
+}
+
+type Lease struct {
+	ProjectID  string    `json:"project_id" bson:"project_id"`
+	EntryID    int       `json:"entry_id" bson:"entry_id"`
+	Holder     string    `json:"holder" bson:"holder"`
+	LeasedAt   time.Time `json:"leased_at" bson:"leased_at"`
+	LeaseUntil time.Time `json:"lease_until" bson:"lease_until"`
+}
+
+func (r *repository) Acquire(ctx context.Context, entryID int, holder string, until time.Time) (bool, error) {
+	now := time.Now()
+
+	// Başkasının geçerli kirası varsa filtre eşleşmez, upsert de project_id+entry_id çakışmasıyla başarısız olur
+	err := r.mongo.FindOneAndUpdate(ctx, leasesCollection, r.scope(bson.D{
+		{Key: "entry_id", Value: entryID},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "lease_until", Value: bson.D{{Key: "$lte", Value: now}}}},
+			bson.D{{Key: "holder", Value: holder}},
+		}},
+	}), bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "holder", Value: holder},
+			{Key: "leased_at", Value: now},
+			{Key: "lease_until", Value: until},
+		},
+	}}, options.FindOneAndUpdate().SetUpsert(true)).Err()
+
+	switch {
+	case err == nil, errors.Is(err, mongo.ErrNoDocuments):
+		return true, nil
+	case mongo.IsDuplicateKeyError(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (r *repository) Release(ctx context.Context, entryID int) error {
+	return r.mongo.DeleteOne(ctx, leasesCollection, r.scope(bson.D{{Key: "entry_id", Value: entryID}}))
+}
+
+func (r *repository) GetActiveLeases(ctx context.Context) (map[int]*Lease, error) {
+	cur, err := r.mongo.Find(ctx, leasesCollection, r.scope(bson.D{{
+		Key:   "lease_until",
+		Value: bson.D{{Key: "$gt", Value: time.Now()}},
+	}}))
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]*Lease, 0)
+	if err := cur.All(ctx, &leases); err != nil {
+		return nil, err
+	}
+
+	active := make(map[int]*Lease, len(leases))
+	for _, lease := range leases {
+		active[lease.EntryID] = lease
+	}
+
+	return active, nil
+}
+
+func (r *repository) GetExpired(ctx context.Context, from, to time.Time) ([]*Lease, error) {
+	cur, err := r.mongo.Find(ctx, leasesCollection, r.scope(bson.D{{
+		Key:   "lease_until",
+		Value: bson.D{{Key: "$gt", Value: from}, {Key: "$lte", Value: to}},
+	}}))
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]*Lease, 0)
+	if err := cur.All(ctx, &leases); err != nil {
+		return nil, err
+	}
+
+	return leases, nil
+}
+
+type settings struct {
+	Weights   *config.Weights `bson:"weights"`
+	UpdatedBy string          `bson:"updated_by"`
+	UpdatedAt time.Time       `bson:"updated_at"`
+}
+
+func (r *repository) GetWeights(ctx context.Context) (*config.Weights, error) {
+	s := &settings{}
+	if err := r.mongo.FindOne(ctx, settingsCollection, bson.D{{Key: "_id", Value: "weights"}}).Decode(s); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return s.Weights, nil
+}
+
+func (r *repository) SetWeights(ctx context.Context, weights *config.Weights, updatedBy string) error {
+	if err := r.mongo.UpsertOne(ctx, settingsCollection, bson.D{{Key: "_id", Value: "weights"}}, bson.D{{
+		Key: "$set",
+		Value: &settings{
+			Weights:   weights,
+			UpdatedBy: updatedBy,
+			UpdatedAt: time.Now(),
+		},
+	}}); err != nil {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) SetUrgency(ctx context.Context, entryID int, urgency float64) error {
+	return r.mongo.UpsertOne(ctx, signalsCollection, r.scope(bson.D{{Key: "entry_id", Value: entryID}}), bson.D{{
+		Key:   "$set",
+		Value: bson.D{{Key: "urgency", Value: urgency}},
+	}})
+}
+
+func (r *repository) GetUrgencies(ctx context.Context) (map[int]float64, error) {
+	cur, err := r.mongo.Find(ctx, signalsCollection, r.scope(bson.D{}))
+	if err != nil {
+		return nil, err
+	}
+
+	signals := make([]struct {
+		EntryID int     `bson:"entry_id"`
+		Urgency float64 `bson:"urgency"`
+	}, 0)
+	if err := cur.All(ctx, &signals); err != nil {
+		return nil, err
+	}
+
+	urgencies := make(map[int]float64, len(signals))
+	for _, s := range signals {
+		urgencies[s.EntryID] = s.Urgency
+	}
+
+	return urgencies, nil
+}
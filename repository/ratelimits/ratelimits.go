@@ -0,0 +1,78 @@
+package ratelimits
+
+import (
+	"context"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	RecordHit(ctx context.Context, hit *Hit) error
+	GetHits(ctx context.Context, since time.Time) ([]*Hit, error)
+}
+
+type repository struct {
+	mongo sources.MongoClient
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+// Hit counts the rejected requests of a caller on a route within one minute.
+type Hit struct {
+	Route     string    `json:"route" bson:"route"`
+	Key       string    `json:"key" bson:"key"`
+	UserID    string    `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	IP        string    `json:"ip" bson:"ip"`
+	Minute    time.Time `json:"minute" bson:"minute"`
+	Count     int       `json:"count" bson:"count"`
+	LastHitAt time.Time `json:"last_hit_at" bson:"last_hit_at"`
+}
+
+func (r *repository) RecordHit(ctx context.Context, hit *Hit) error {
+	now := time.Now()
+
+	if err := r.mongo.UpsertOne(ctx, "rate_limit_hits", bson.D{
+		{Key: "route", Value: hit.Route},
+		{Key: "key", Value: hit.Key},
+		{Key: "minute", Value: now.Truncate(time.Minute)},
+	}, bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "count", Value: 1}}},
+		{Key: "$set", Value: bson.D{
+			{Key: "last_hit_at", Value: now},
+			{Key: "ip", Value: hit.IP},
+		}},
+		{Key: "$setOnInsert", Value: bson.D{{Key: "user_id", Value: hit.UserID}}},
+	}); err != nil {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetHits(ctx context.Context, since time.Time) ([]*Hit, error) {
+	cur, err := r.mongo.Find(ctx, "rate_limit_hits", bson.D{{
+		Key:   "minute",
+		Value: bson.D{{Key: "$gte", Value: since}},
+	}}, options.Find().SetSort(bson.D{{Key: "minute", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]*Hit, 0)
+	if err := cur.All(ctx, &hits); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return hits, nil
+}
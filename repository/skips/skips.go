@@ -0,0 +1,195 @@
+package skips
+
+import (
+	"context"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	// AddSkip records a skip, skipping the same entry twice only updates the reason.
+	AddSkip(ctx context.Context, skip *Skip) error
+	GetSkippedByUser(ctx context.Context, userID string) (map[int]bool, error)
+	GetSkipCounts(ctx context.Context) (map[int]int, error)
+	GetStats(ctx context.Context) (*Stats, error)
+	WithProject(projectID string) Repository
+}
+
+const (
+	ReasonSkip                = "skip"
+	ReasonNeedsLocalKnowledge = "needs_local_knowledge"
+)
+
+var Reasons = []string{ReasonSkip, ReasonNeedsLocalKnowledge}
+
+func IsReason(reason string) bool {
+	for _, r := range Reasons {
+		if r == reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+type repository struct {
+	mongo   sources.MongoClient
+	project string
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+func (r *repository) WithProject(projectID string) Repository {
+	return &repository{
+		mongo:   r.mongo,
+		project: projectID,
+	}
+}
+
+func (r *repository) scope(filter bson.D) bson.D {
+	if len(r.project) == 0 {
+		return filter
+	}
+
+	return append(bson.D{{Key: "project_id", Value: r.project}}, filter...)
+}
+
+type Skip struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	ProjectID string             `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	EntryID   int                `json:"entry_id" bson:"entry_id"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	Reason    string             `json:"reason" bson:"reason"`
+	Note      string             `json:"note,omitempty" bson:"note,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type Stats struct {
+	Total    int64            `json:"total"`
+	ByReason map[string]int64 `json:"by_reason"`
+	ByUser   map[string]int64 `json:"by_user"`
+}
+
+func (r *repository) AddSkip(ctx context.Context, skip *Skip) error {
+	if err := r.mongo.UpdateOne(ctx, "skips", r.scope(bson.D{
+		{Key: "entry_id", Value: skip.EntryID},
+		{Key: "user_id", Value: skip.UserID},
+	}), bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "reason", Value: skip.Reason},
+			{Key: "note", Value: skip.Note},
+			{Key: "created_at", Value: time.Now()},
+		}},
+		{Key: "$setOnInsert", Value: bson.D{{Key: "_id", Value: primitive.NewObjectID()}}},
+	}, options.Update().SetUpsert(true)); err != nil {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetSkippedByUser(ctx context.Context, userID string) (map[int]bool, error) {
+	cur, err := r.mongo.Find(ctx, "skips", r.scope(bson.D{{Key: "user_id", Value: userID}}), options.Find().
+		SetProjection(bson.D{{Key: "entry_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	skips := make([]*Skip, 0)
+	if err := cur.All(ctx, &skips); err != nil {
+		return nil, err
+	}
+
+	skipped := make(map[int]bool, len(skips))
+	for _, skip := range skips {
+		skipped[skip.EntryID] = true
+	}
+
+	return skipped, nil
+}
+
+type group struct {
+	ID    interface{} `bson:"_id"`
+	Count int64       `bson:"count"`
+}
+
+func (r *repository) groupBy(ctx context.Context, field string) ([]*group, error) {
+	cur, err := r.mongo.Aggregate(ctx, "skips", mongo.Pipeline{
+		{{Key: "$match", Value: r.scope(bson.D{})}},
+		{{
+			Key: "$group",
+			Value: bson.D{
+				{Key: "_id", Value: "$" + field},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*group, 0)
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (r *repository) GetSkipCounts(ctx context.Context) (map[int]int, error) {
+	groups, err := r.groupBy(ctx, "entry_id")
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int, len(groups))
+	for _, g := range groups {
+		if id, ok := g.ID.(int32); ok {
+			counts[int(id)] = int(g.Count)
+		} else if id, ok := g.ID.(int64); ok {
+			counts[int(id)] = int(g.Count)
+		}
+	}
+
+	return counts, nil
+}
+
+func (r *repository) GetStats(ctx context.Context) (*Stats, error) {
+	stats := &Stats{
+		ByReason: make(map[string]int64),
+		ByUser:   make(map[string]int64),
+	}
+
+	byReason, err := r.groupBy(ctx, "reason")
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range byReason {
+		reason, _ := g.ID.(string)
+		stats.ByReason[reason] = g.Count
+		stats.Total += g.Count
+	}
+
+	byUser, err := r.groupBy(ctx, "user_id")
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range byUser {
+		userID, _ := g.ID.(string)
+		stats.ByUser[userID] = g.Count
+	}
+
+	return stats, nil
+}
@@ -2,19 +2,36 @@ package users
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"regexp"
+	"time"
+
 	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
 	"github.com/YusufOzmen01/veri-kontrol-backend/util"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Repository interface {
 	GetUser(ctx context.Context, authKey string) (*User, error)
 	AddUser(ctx context.Context, name, discord string, permLevel int) (string, error)
+	GetUserByDiscord(ctx context.Context, discord string) (*User, error)
+	RotateAuthKey(ctx context.Context, id primitive.ObjectID) (string, error)
+	FindByName(ctx context.Context, name string) ([]*User, error)
+	GetExpired(ctx context.Context, field string, before time.Time) ([]*User, error)
+	Purge(ctx context.Context, ids []primitive.ObjectID, fields []string) (int64, error)
+	DeleteUsers(ctx context.Context, ids []primitive.ObjectID) error
+	// SetProjectRole gives the user a permission level in one project, 0 falls back to the global level.
+	SetProjectRole(ctx context.Context, id primitive.ObjectID, projectID string, permLevel int) error
 }
 
+var ErrNotFound = errors.New("user not found")
+
 type repository struct {
 	mongo sources.MongoClient
 }
@@ -30,41 +47,58 @@ const (
 	PermModerator = 2
 )
 
+// Personal fields of a user, purged by the retention policies and on erasure.
+const (
+	FieldName    = "name"
+	FieldDiscord = "discord"
+)
+
+var PersonalFields = []string{FieldName, FieldDiscord}
+
 type User struct {
 	ID          primitive.ObjectID `json:"_id" bson:"_id"`
 	Name        string             `json:"name" bson:"name"`
 	Discord     string             `json:"discord" bson:"discord"`
 	AuthKeyHash uint32             `json:"auth_key_hash" bson:"auth_key_hash"`
 	PermLevel   int                `json:"perm_level" bson:"perm_level"`
+	// Projects are the permission levels of the user in single projects, keyed by the project id.
+	Projects map[string]int `json:"projects,omitempty" bson:"projects,omitempty"`
 }
 
-func (r *repository) GetUser(ctx context.Context, authKey string) (*User, error) {
-	cur, err := r.mongo.Find(ctx, "users", bson.D{})
-	if err != nil {
-		return nil, err
+// PermIn returns the permission level of the user in the project, PermLevel when the project doesn't set one.
+func (u *User) PermIn(projectID string) int {
+	if level, ok := u.Projects[projectID]; ok {
+		return level
 	}
 
-	// Saçma bir yöntem farkındayım ama filtreler çalışmadığından böyle yapmak zorunda kaldım
+	return u.PermLevel
+}
+
+func (r *repository) GetUser(ctx context.Context, authKey string) (*User, error) {
+	user := &User{}
+	if err := r.mongo.FindOne(ctx, "users", bson.D{{
+		Key:   "auth_key_hash",
+		Value: int64(util.Hash(authKey)),
+	}}).Decode(user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
 
-	user := make([]*User, 0)
-	if err := cur.All(ctx, &user); err != nil {
 		logrus.Errorln(err)
 		return nil, err
 	}
 
-	for _, u := range user {
-		if u.AuthKeyHash == util.Hash(authKey) {
-			return u, nil
-		}
-	}
-
-	return nil, fmt.Errorf("user not found")
+	return user, nil
 }
 
 func (r *repository) AddUser(ctx context.Context, name, discord string, permLevel int) (string, error) {
-	authKey := util.RandomString(32)
+	authKey, err := newAuthKey()
+	if err != nil {
+		return "", err
+	}
 
 	if err := r.mongo.InsertOne(ctx, "users", &User{
+		ID:          primitive.NewObjectID(),
 		Name:        name,
 		Discord:     discord,
 		AuthKeyHash: util.Hash(authKey),
@@ -77,3 +111,157 @@ func (r *repository) AddUser(ctx context.Context, name, discord string, permLeve
 
 	return authKey, nil
 }
+
+func (r *repository) GetUserByDiscord(ctx context.Context, discord string) (*User, error) {
+	user := &User{}
+	if err := r.mongo.FindOne(ctx, "users", bson.D{{
+		Key:   "discord",
+		Value: discord,
+	}}).Decode(user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// RotateAuthKey replaces the key of a user, the old key stops working right away.
+func (r *repository) RotateAuthKey(ctx context.Context, id primitive.ObjectID) (string, error) {
+	authKey, err := newAuthKey()
+	if err != nil {
+		return "", err
+	}
+
+	res := r.mongo.FindOneAndUpdate(ctx, "users", bson.D{{
+		Key:   "_id",
+		Value: id,
+	}}, bson.D{{
+		Key:   "$set",
+		Value: bson.D{{Key: "auth_key_hash", Value: util.Hash(authKey)}},
+	}})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", ErrNotFound
+		}
+
+		logrus.Errorln(err)
+
+		return "", err
+	}
+
+	return authKey, nil
+}
+
+// newAuthKey returns 32 random bytes from crypto/rand, base64url encoded so the key can be sent in a header or a URL.
+func newAuthKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (r *repository) find(ctx context.Context, filter bson.D) ([]*User, error) {
+	cur, err := r.mongo.Find(ctx, "users", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*User, 0)
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// FindByName matches the name and the Discord id case insensitively.
+func (r *repository) FindByName(ctx context.Context, name string) ([]*User, error) {
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(name), Options: "i"}
+
+	return r.find(ctx, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "name", Value: pattern}},
+		bson.D{{Key: "discord", Value: pattern}},
+	}}})
+}
+
+// GetExpired returns the users created before the given time that still have the field.
+func (r *repository) GetExpired(ctx context.Context, field string, before time.Time) ([]*User, error) {
+	if field != FieldName && field != FieldDiscord {
+		return nil, fmt.Errorf("unknown personal field %q", field)
+	}
+
+	return r.find(ctx, bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$lt", Value: primitive.NewObjectIDFromTimestamp(before)}}},
+		{Key: field, Value: bson.D{{Key: "$nin", Value: bson.A{nil, ""}}}},
+	})
+}
+
+// Purge empties the personal fields, the users keep their keys and permissions.
+func (r *repository) Purge(ctx context.Context, ids []primitive.ObjectID, fields []string) (int64, error) {
+	if len(ids) == 0 || len(fields) == 0 {
+		return 0, nil
+	}
+
+	set := bson.D{}
+	for _, field := range fields {
+		if field != FieldName && field != FieldDiscord {
+			return 0, fmt.Errorf("unknown personal field %q", field)
+		}
+
+		set = append(set, bson.E{Key: field, Value: ""})
+	}
+
+	purged, err := r.mongo.UpdateMany(ctx, "users", bson.D{{
+		Key:   "_id",
+		Value: bson.D{{Key: "$in", Value: ids}},
+	}}, bson.D{{Key: "$set", Value: set}})
+	if err != nil {
+		logrus.Errorln(err)
+
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+func (r *repository) DeleteUsers(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := r.mongo.DeleteMany(ctx, "users", bson.D{{
+		Key:   "_id",
+		Value: bson.D{{Key: "$in", Value: ids}},
+	}}); err != nil {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) SetProjectRole(ctx context.Context, id primitive.ObjectID, projectID string, permLevel int) error {
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "projects." + projectID, Value: permLevel}}}}
+	if permLevel <= 0 {
+		update = bson.D{{Key: "$unset", Value: bson.D{{Key: "projects." + projectID, Value: ""}}}}
+	}
+
+	res := r.mongo.FindOneAndUpdate(ctx, "users", bson.D{{Key: "_id", Value: id}}, update)
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotFound
+		}
+
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
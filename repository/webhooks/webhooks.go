@@ -0,0 +1,271 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	CreateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	GetEndpoints(ctx context.Context, activeOnly bool) ([]*Endpoint, error)
+	DeactivateEndpoint(ctx context.Context, id primitive.ObjectID) error
+
+	// Enqueue adds a delivery to the outbox. A delivery with the same dedupe key is only added once, every replica
+	// sees the same writes and enqueues them.
+	Enqueue(ctx context.Context, delivery *Delivery) error
+	// ClaimDue locks the oldest due delivery until lockUntil, it returns ErrNotFound when nothing is due.
+	ClaimDue(ctx context.Context, lockUntil time.Time) (*Delivery, error)
+	RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt *Attempt, status string, nextAttemptAt time.Time) error
+	GetDeliveries(ctx context.Context, endpointID primitive.ObjectID, status string, limit int64) ([]*Delivery, error)
+	Replay(ctx context.Context, id primitive.ObjectID) error
+	ReplayFailed(ctx context.Context, endpointID primitive.ObjectID) (int64, error)
+
+	// GetCursor returns the updated_at up to which the writes to locations were enqueued, zero if nothing was yet.
+	GetCursor(ctx context.Context) (time.Time, error)
+	// SetCursor moves the cursor forward, a replica behind the others can't move it back.
+	SetCursor(ctx context.Context, cursor time.Time) error
+}
+
+var ErrNotFound = errors.New("webhook not found")
+
+const (
+	endpointsCollection  = "webhook_endpoints"
+	deliveriesCollection = "webhook_deliveries"
+	cursorCollection     = "webhook_cursor"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+type repository struct {
+	mongo sources.MongoClient
+}
+
+func NewRepository(mongo sources.MongoClient) Repository {
+	return &repository{
+		mongo: mongo,
+	}
+}
+
+// Endpoint receives the deliveries of the events it subscribed to. Empty Categories means every category.
+type Endpoint struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	URL        string             `json:"url" bson:"url"`
+	Secret     string             `json:"secret,omitempty" bson:"secret"`
+	Events     []string           `json:"events" bson:"events"`
+	Categories []string           `json:"categories" bson:"categories"`
+	Active     bool               `json:"active" bson:"active"`
+	CreatedBy  string             `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type Delivery struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	EndpointID primitive.ObjectID `json:"endpoint_id" bson:"endpoint_id"`
+	DedupeKey  string             `json:"-" bson:"dedupe_key"`
+	Event      string             `json:"event" bson:"event"`
+	EntryID    int                `json:"entry_id" bson:"entry_id"`
+	Payload    string             `json:"payload" bson:"payload"`
+	Status     string             `json:"status" bson:"status"`
+	// AttemptCount counts the attempts since the delivery was enqueued or replayed, Attempts is the whole log.
+	AttemptCount  int        `json:"attempt_count" bson:"attempt_count"`
+	Attempts      []*Attempt `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" bson:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+	ReplayedAt    *time.Time `json:"replayed_at,omitempty" bson:"replayed_at,omitempty"`
+}
+
+// Attempt is one entry of the delivery log.
+type Attempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs float64   `json:"duration_ms" bson:"duration_ms"`
+}
+
+func (r *repository) CreateEndpoint(ctx context.Context, endpoint *Endpoint) error {
+	endpoint.ID = primitive.NewObjectID()
+	endpoint.Active = true
+	endpoint.CreatedAt = time.Now()
+
+	return r.mongo.InsertOne(ctx, endpointsCollection, endpoint)
+}
+
+func (r *repository) GetEndpoints(ctx context.Context, activeOnly bool) ([]*Endpoint, error) {
+	filter := bson.D{}
+	if activeOnly {
+		filter = bson.D{{Key: "active", Value: true}}
+	}
+
+	cur, err := r.mongo.Find(ctx, endpointsCollection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]*Endpoint, 0)
+	if err := cur.All(ctx, &endpoints); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (r *repository) DeactivateEndpoint(ctx context.Context, id primitive.ObjectID) error {
+	res := r.mongo.FindOneAndUpdate(ctx, endpointsCollection, bson.D{{Key: "_id", Value: id}}, bson.D{{
+		Key:   "$set",
+		Value: bson.D{{Key: "active", Value: false}},
+	}})
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+
+	return res.Err()
+}
+
+func (r *repository) Enqueue(ctx context.Context, delivery *Delivery) error {
+	now := time.Now()
+
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	delivery.Status = StatusPending
+	delivery.Attempts = make([]*Attempt, 0)
+	delivery.NextAttemptAt = now
+	delivery.CreatedAt = now
+
+	if err := r.mongo.InsertOne(ctx, deliveriesCollection, delivery); err != nil && !mongo.IsDuplicateKeyError(err) {
+		logrus.Errorln(err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ClaimDue(ctx context.Context, lockUntil time.Time) (*Delivery, error) {
+	delivery := &Delivery{}
+
+	if err := r.mongo.FindOneAndUpdate(ctx, deliveriesCollection, bson.D{
+		{Key: "status", Value: StatusPending},
+		{Key: "next_attempt_at", Value: bson.D{{Key: "$lte", Value: time.Now()}}},
+	}, bson.D{{
+		Key:   "$set",
+		Value: bson.D{{Key: "next_attempt_at", Value: lockUntil}},
+	}}, options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)).Decode(delivery); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (r *repository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt *Attempt, status string, nextAttemptAt time.Time) error {
+	set := bson.D{
+		{Key: "status", Value: status},
+		{Key: "next_attempt_at", Value: nextAttemptAt},
+	}
+	if status == StatusDelivered {
+		set = append(set, bson.E{Key: "delivered_at", Value: attempt.At})
+	}
+
+	return r.mongo.UpdateOne(ctx, deliveriesCollection, bson.D{{Key: "_id", Value: id}}, bson.D{
+		{Key: "$set", Value: set},
+		{Key: "$inc", Value: bson.D{{Key: "attempt_count", Value: 1}}},
+		{Key: "$push", Value: bson.D{{Key: "attempts", Value: attempt}}},
+	})
+}
+
+func (r *repository) GetDeliveries(ctx context.Context, endpointID primitive.ObjectID, status string, limit int64) ([]*Delivery, error) {
+	filter := bson.D{{Key: "endpoint_id", Value: endpointID}}
+	if len(status) > 0 {
+		filter = append(filter, bson.E{Key: "status", Value: status})
+	}
+
+	cur, err := r.mongo.Find(ctx, deliveriesCollection, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*Delivery, 0)
+	if err := cur.All(ctx, &deliveries); err != nil {
+		logrus.Errorln(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Replay sends a delivery again whatever its status is, the attempts of the delivery log are kept.
+func (r *repository) Replay(ctx context.Context, id primitive.ObjectID) error {
+	res := r.mongo.FindOneAndUpdate(ctx, deliveriesCollection, bson.D{{Key: "_id", Value: id}}, replayUpdate())
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+
+	return res.Err()
+}
+
+func (r *repository) ReplayFailed(ctx context.Context, endpointID primitive.ObjectID) (int64, error) {
+	return r.mongo.UpdateMany(ctx, deliveriesCollection, bson.D{
+		{Key: "endpoint_id", Value: endpointID},
+		{Key: "status", Value: StatusFailed},
+	}, replayUpdate())
+}
+
+func replayUpdate() bson.D {
+	now := time.Now()
+
+	return bson.D{{
+		Key: "$set",
+		Value: bson.D{
+			{Key: "status", Value: StatusPending},
+			{Key: "attempt_count", Value: 0},
+			{Key: "next_attempt_at", Value: now},
+			{Key: "replayed_at", Value: now},
+		},
+	}}
+}
+
+type cursor struct {
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+func (r *repository) GetCursor(ctx context.Context) (time.Time, error) {
+	c := &cursor{}
+	if err := r.mongo.FindOne(ctx, cursorCollection, bson.D{{Key: "_id", Value: "locations"}}).Decode(c); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, err
+	}
+
+	return c.UpdatedAt, nil
+}
+
+func (r *repository) SetCursor(ctx context.Context, cursor time.Time) error {
+	return r.mongo.UpsertOne(ctx, cursorCollection, bson.D{{Key: "_id", Value: "locations"}}, bson.D{{
+		Key:   "$max",
+		Value: bson.D{{Key: "updated_at", Value: cursor}},
+	}})
+}
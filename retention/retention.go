@@ -0,0 +1,433 @@
+// Package retention implements the KVKK obligations: personal fields are purged after their retention period and a
+// data subject can be looked up, exported and erased. Coordinates are never removed, they are kept for statistics.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/pii"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/audit"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/notifications"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/users"
+	"github.com/YusufOzmen01/veri-kontrol-backend/validation"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MinNameLength keeps a short name from matching most of the collection.
+const MinNameLength = 3
+
+const (
+	// ModeAnonymize purges the personal fields and keeps the users able to log in.
+	ModeAnonymize = "anonymize"
+	// ModeDelete also deletes the matched users.
+	ModeDelete = "delete"
+)
+
+type Subject struct {
+	Phone string `json:"phone"`
+	Name  string `json:"name"`
+}
+
+// Validate normalizes the phone to +90XXXXXXXXXX, at least one of the phone and the name is required.
+func (s *Subject) Validate() error {
+	errs := make(validation.Errors, 0)
+
+	s.Name = strings.TrimSpace(s.Name)
+
+	if len(s.Phone) > 0 {
+		phone, ok := pii.NormalizePhone(s.Phone)
+		if !ok {
+			errs.Add("phone", "not a Turkish phone number")
+		}
+
+		s.Phone = phone
+	}
+
+	if len(s.Name) > 0 && len([]rune(s.Name)) < MinNameLength {
+		errs.Add("name", "must be at least %d characters", MinNameLength)
+	}
+
+	if len(s.Phone) == 0 && len(s.Name) == 0 && len(errs) == 0 {
+		errs.Add("phone", "phone or name is required")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+type ExportedLocation struct {
+	*locations.LocationDB
+	RawTweetContents string `json:"raw_tweet_contents,omitempty"`
+}
+
+// Export holds every record of a subject. Mentions are the resolutions whose content mentions the subject,
+// Submitted the ones resolved by the matched users.
+type Export struct {
+	Subject    *Subject            `json:"subject"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Mentions   []*ExportedLocation `json:"mentions"`
+	Submitted  []*ExportedLocation `json:"submitted"`
+	Users      []*users.User       `json:"users"`
+}
+
+func (e *Export) summary() string {
+	return fmt.Sprintf("%d mentions, %d submitted, %d users", len(e.Mentions), len(e.Submitted), len(e.Users))
+}
+
+// projects returns the sorted projects of the mentions and the submitted resolutions.
+func (e *Export) projects() []string {
+	seen := make(map[string]bool)
+	projects := make([]string, 0)
+	for _, locs := range [][]*ExportedLocation{e.Mentions, e.Submitted} {
+		for _, loc := range locs {
+			if !seen[loc.ProjectID] {
+				seen[loc.ProjectID] = true
+				projects = append(projects, loc.ProjectID)
+			}
+		}
+	}
+	sort.Strings(projects)
+
+	return projects
+}
+
+type Erasure struct {
+	Mode      string   `json:"mode"`
+	DryRun    bool     `json:"dry_run"`
+	Mentions  []int    `json:"mentions"`
+	Submitted []int    `json:"submitted"`
+	Users     []string `json:"users"`
+	// Purged is the number of documents changed, 0 on a dry run.
+	Purged int64 `json:"purged"`
+}
+
+type Report struct {
+	DryRun    bool             `json:"dry_run"`
+	StartedAt time.Time        `json:"started_at"`
+	Purged    map[string]int64 `json:"purged"`
+}
+
+type Service interface {
+	// Lookup finds the records of a subject without decrypting the tweets.
+	Lookup(ctx context.Context, subject *Subject, actor *audit.Record) (*Export, error)
+	// Export also decrypts the raw tweets, for the subject's access request.
+	Export(ctx context.Context, subject *Subject, actor *audit.Record) (*Export, error)
+	Erase(ctx context.Context, subject *Subject, mode string, dryRun bool, actor *audit.Record) (*Erasure, error)
+	Purge(ctx context.Context, dryRun bool) (*Report, error)
+	Run(ctx context.Context) error
+}
+
+type service struct {
+	cfg       config.Retention
+	locations locations.Repository
+	users     users.Repository
+	audits    audit.Repository
+	claims    notifications.Repository
+	protector pii.Protector
+}
+
+func NewService(cfg config.Retention, locations locations.Repository, users users.Repository, audits audit.Repository, claims notifications.Repository, protector pii.Protector) Service {
+	return &service{
+		cfg:       cfg,
+		locations: locations,
+		users:     users,
+		audits:    audits,
+		claims:    claims,
+		protector: protector,
+	}
+}
+
+func (s *service) collect(ctx context.Context, subject *Subject, reveal bool) (*Export, error) {
+	export := &Export{
+		Subject:    subject,
+		ExportedAt: time.Now(),
+		Mentions:   make([]*ExportedLocation, 0),
+		Submitted:  make([]*ExportedLocation, 0),
+		Users:      make([]*users.User, 0),
+	}
+
+	mentions, err := s.locations.FindMentions(ctx, subject.Phone, subject.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Telefon numarası sadece mağdurlarda var, gönüllüler isimle bulunur
+	if len(subject.Name) > 0 {
+		if export.Users, err = s.users.FindByName(ctx, subject.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(export.Users))
+	for _, user := range export.Users {
+		user.AuthKeyHash = 0
+		ids = append(ids, user.ID)
+	}
+
+	submitted, err := s.locations.FindSentBy(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range []struct {
+		locs []*locations.LocationDB
+		to   *[]*ExportedLocation
+	}{{mentions, &export.Mentions}, {submitted, &export.Submitted}} {
+		for _, loc := range group.locs {
+			exported := &ExportedLocation{LocationDB: loc}
+
+			if reveal && len(loc.TweetContentsEncrypted) > 0 {
+				if exported.RawTweetContents, err = s.protector.Reveal(loc.TweetContentsEncrypted); err != nil {
+					return nil, err
+				}
+			}
+
+			*group.to = append(*group.to, exported)
+		}
+	}
+
+	return export, nil
+}
+
+// record writes the audit entry with the projects of the found resolutions, a subject request is refused when it
+// can't be recorded.
+func (s *service) record(ctx context.Context, actor *audit.Record, action string, export *Export, details string) error {
+	record := *actor
+	record.Action = action
+	record.Projects = export.projects()
+	record.Details = details
+
+	return s.audits.Record(ctx, &record)
+}
+
+func (s *service) Lookup(ctx context.Context, subject *Subject, actor *audit.Record) (*Export, error) {
+	export, err := s.collect(ctx, subject, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.record(ctx, actor, audit.ActionLookupSubject, export, export.summary()); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (s *service) Export(ctx context.Context, subject *Subject, actor *audit.Record) (*Export, error) {
+	export, err := s.collect(ctx, subject, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.record(ctx, actor, audit.ActionExportSubject, export, export.summary()); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// purge purges the resolutions project by project, entry ids are only unique within a project.
+func (s *service) purge(ctx context.Context, locs []*ExportedLocation, fields []string) (int64, error) {
+	byProject := make(map[string][]int)
+	for _, loc := range locs {
+		byProject[loc.ProjectID] = append(byProject[loc.ProjectID], loc.EntryID)
+	}
+
+	var total int64
+	for project, ids := range byProject {
+		purged, err := s.locations.WithProject(project).Purge(ctx, ids, fields)
+		if err != nil {
+			return total, err
+		}
+		total += purged
+	}
+
+	return total, nil
+}
+
+// Erase purges the content of the mentions and the sender of the submitted resolutions, a resolution made by the
+// subject still describes somebody else.
+func (s *service) Erase(ctx context.Context, subject *Subject, mode string, dryRun bool, actor *audit.Record) (*Erasure, error) {
+	if mode != ModeAnonymize && mode != ModeDelete {
+		return nil, validation.Errors{{Field: "mode", Message: fmt.Sprintf("must be %s or %s", ModeAnonymize, ModeDelete)}}
+	}
+
+	export, err := s.collect(ctx, subject, false)
+	if err != nil {
+		return nil, err
+	}
+
+	erasure := &Erasure{
+		Mode:      mode,
+		DryRun:    dryRun,
+		Mentions:  entryIDs(export.Mentions),
+		Submitted: entryIDs(export.Submitted),
+		Users:     make([]string, 0, len(export.Users)),
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(export.Users))
+	for _, user := range export.Users {
+		ids = append(ids, user.ID)
+		erasure.Users = append(erasure.Users, user.ID.Hex())
+	}
+
+	if !dryRun {
+		purged, err := s.purge(ctx, export.Mentions, locations.ContentFields)
+		if err != nil {
+			return nil, err
+		}
+		erasure.Purged += purged
+
+		if purged, err = s.purge(ctx, export.Submitted, []string{locations.FieldSender}); err != nil {
+			return nil, err
+		}
+		erasure.Purged += purged
+
+		if mode == ModeDelete {
+			if err := s.users.DeleteUsers(ctx, ids); err != nil {
+				return nil, err
+			}
+			erasure.Purged += int64(len(ids))
+		} else {
+			if purged, err = s.users.Purge(ctx, ids, users.PersonalFields); err != nil {
+				return nil, err
+			}
+			erasure.Purged += purged
+		}
+	}
+
+	details := fmt.Sprintf("%s, %s", mode, export.summary())
+	if dryRun {
+		details += ", dry run"
+	}
+
+	if err := s.record(ctx, actor, audit.ActionEraseSubject, export, details); err != nil {
+		return nil, err
+	}
+
+	return erasure, nil
+}
+
+func entryIDs(locs []*ExportedLocation) []int {
+	ids := make([]int, 0, len(locs))
+	for _, loc := range locs {
+		ids = append(ids, loc.EntryID)
+	}
+
+	return ids
+}
+
+// Purge applies every retention policy once, dry runs only count the documents that would change.
+func (s *service) Purge(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+		Purged:    make(map[string]int64),
+	}
+
+	for field, keep := range map[string]time.Duration{
+		locations.FieldTweetContents: s.cfg.TweetContents,
+		locations.FieldOpenAddress:   s.cfg.OpenAddress,
+		locations.FieldApartment:     s.cfg.Apartment,
+		locations.FieldSender:        s.cfg.Sender,
+	} {
+		if keep <= 0 {
+			continue
+		}
+
+		purged, err := s.locations.PurgeExpired(ctx, field, report.StartedAt.Add(-keep), dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("locations.%s: %w", field, err)
+		}
+
+		report.Purged["locations."+field] = purged
+	}
+
+	for field, keep := range map[string]time.Duration{
+		users.FieldName:    s.cfg.UserName,
+		users.FieldDiscord: s.cfg.UserDiscord,
+	} {
+		if keep <= 0 {
+			continue
+		}
+
+		purged, err := s.purgeUsers(ctx, field, report.StartedAt.Add(-keep), dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("users.%s: %w", field, err)
+		}
+
+		report.Purged["users."+field] = purged
+	}
+
+	return report, nil
+}
+
+// purgeUsers skips the users who resolved an entry since before, they are still volunteering.
+func (s *service) purgeUsers(ctx context.Context, field string, before time.Time, dryRun bool) (int64, error) {
+	expired, err := s.users.GetExpired(ctx, field, before)
+	if err != nil {
+		return 0, err
+	}
+
+	active, err := s.locations.GetActiveSenders(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(expired))
+	for _, user := range expired {
+		if !active[user.ID.Hex()] {
+			ids = append(ids, user.ID)
+		}
+	}
+
+	if dryRun {
+		return int64(len(ids)), nil
+	}
+
+	return s.users.Purge(ctx, ids, []string{field})
+}
+
+// Run purges every Interval, only the replica that claims the interval does the work.
+func (s *service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		claimed, err := s.claims.Claim(ctx, "retention", time.Now().UnixNano()/int64(s.cfg.Interval))
+		if err != nil || !claimed {
+			if err != nil && ctx.Err() == nil {
+				logrus.Errorf("couldn't claim the retention run: %s", err)
+			}
+
+			continue
+		}
+
+		report, err := s.Purge(ctx, false)
+		if err != nil {
+			if ctx.Err() == nil {
+				logrus.Errorf("retention purge failed: %s", err)
+			}
+
+			continue
+		}
+
+		logrus.WithField("purged", report.Purged).Infoln("Retention purge complete")
+	}
+}
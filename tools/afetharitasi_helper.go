@@ -4,11 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/metrics"
 	"github.com/YusufOzmen01/veri-kontrol-backend/core/network"
 	"github.com/YusufOzmen01/veri-kontrol-backend/core/sources"
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/tracing"
 	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/projects"
+	"github.com/YusufOzmen01/veri-kontrol-backend/util"
 	log "github.com/sirupsen/logrus"
-	"time"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type SingleResponse struct {
@@ -16,53 +25,154 @@ type SingleResponse struct {
 	FormattedAddress string `json:"formatted_address"`
 }
 
-func GetAllLocations(ctx context.Context, cache sources.Cache) ([]*locations.Location, error) {
+type Feed interface {
+	GetAllLocations(ctx context.Context) ([]*locations.Location, error)
+	GetSingleLocation(ctx context.Context, locationID int) (*SingleResponse, error)
+	// LastFetch returns when the area feed was last fetched successfully, zero if it never was.
+	LastFetch() time.Time
+}
+
+type feed struct {
+	cfg   config.Feed
+	cache sources.Cache
+	// prefix keeps the cache keys of the feeds of different projects apart
+	prefix    string
+	lastFetch int64
+}
+
+func NewFeed(cfg config.Feed, cache sources.Cache) Feed {
+	return &feed{
+		cfg:   cfg,
+		cache: cache,
+	}
+}
+
+// Feeds keeps one feed per project so every project has its own cache and LastFetch.
+type Feeds interface {
+	For(project *projects.Project) Feed
+}
+
+type feeds struct {
+	cfg   config.Feed
+	cache sources.Cache
+	mu    sync.Mutex
+	feeds map[string]*feed
+}
+
+func NewFeeds(cfg config.Feed, cache sources.Cache) Feeds {
+	return &feeds{
+		cfg:   cfg,
+		cache: cache,
+		feeds: make(map[string]*feed),
+	}
+}
+
+func (f *feeds) For(project *projects.Project) Feed {
+	cfg := f.cfg
+	if len(project.Feed.AreasURL) > 0 {
+		cfg.AreasURL = project.Feed.AreasURL
+	}
+	if len(project.Feed.SingleURL) > 0 {
+		cfg.SingleURL = project.Feed.SingleURL
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if existing, ok := f.feeds[project.ID]; ok && existing.cfg == cfg {
+		return existing
+	}
+
+	created := &feed{
+		cfg:   cfg,
+		cache: f.cache,
+		// Projenin feed adresi değiştiyse eski önbellek kullanılmamalı
+		prefix: fmt.Sprintf("project_%s_%d_", project.ID, util.Hash(cfg.AreasURL+" "+cfg.SingleURL)),
+	}
+	f.feeds[project.ID] = created
+
+	return created
+}
+
+func (f *feed) LastFetch() time.Time {
+	last := atomic.LoadInt64(&f.lastFetch)
+	if last == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, last)
+}
+
+func (f *feed) GetAllLocations(ctx context.Context) (_ []*locations.Location, err error) {
 	var d struct {
 		Locations []*locations.Location `json:"results"`
 	}
 
-	data, exists := cache.Get("locations")
+	data, exists := f.cache.Get(f.prefix + "locations")
 	if exists {
 		return data.([]*locations.Location), nil
 	}
 
-	res, _, err := network.ProcessGet(ctx, "https://apigo.afetharita.com/feeds/areas?ne_lat=39.91618777305531&ne_lng=47.85149904303703&sw_lat=36.07272886939253&sw_lng=23.872389299415502", map[string]string{
-		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
+	ctx, span := tracing.Start(ctx, "feed.GetAllLocations")
+	defer func() { tracing.End(span, err) }()
+
+	started := time.Now()
+
+	res, _, err := network.ProcessGet(ctx, f.cfg.AreasURL, map[string]string{
+		"User-Agent": f.cfg.UserAgent,
 	})
 	if err != nil {
+		metrics.ObserveUpstream("areas", started, err)
+
 		return nil, err
 	}
 
-	if err := json.Unmarshal(res, &d); err != nil {
+	err = json.Unmarshal(res, &d)
+	metrics.ObserveUpstream("areas", started, err)
+	if err != nil {
 		return nil, err
 	}
 
-	cache.SetWithTTL("locations", d.Locations, int64(time.Minute*15), 0)
+	atomic.StoreInt64(&f.lastFetch, time.Now().UnixNano())
+
+	f.cache.SetWithTTL(f.prefix+"locations", d.Locations, 1, f.cfg.AreasTTL)
 
 	return d.Locations, nil
 }
 
-func GetSingleLocation(ctx context.Context, locationID int, cache sources.Cache) (*SingleResponse, error) {
-	data, exists := cache.Get(fmt.Sprintf("single_location_%d", locationID))
+func (f *feed) GetSingleLocation(ctx context.Context, locationID int) (_ *SingleResponse, err error) {
+	data, exists := f.cache.Get(fmt.Sprintf("%ssingle_location_%d", f.prefix, locationID))
 	if exists {
 		return data.(*SingleResponse), nil
 	}
 
-	resp, _, err := network.ProcessGet(ctx, fmt.Sprintf("https://apigo.afetharita.com/feeds/%d", locationID), map[string]string{
-		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
+	ctx, span := tracing.Start(ctx, "feed.GetSingleLocation", attribute.Int("entry_id", locationID))
+	defer func() { tracing.End(span, err) }()
+
+	started := time.Now()
+
+	resp, status, err := network.ProcessGet(ctx, fmt.Sprintf(f.cfg.SingleURL, locationID), map[string]string{
+		"User-Agent": f.cfg.UserAgent,
 	})
+	if err == nil && status >= 400 {
+		err = fmt.Errorf("feed returned status %d for location %d", status, locationID)
+	}
 	if err != nil {
+		metrics.ObserveUpstream("single", started, err)
+
 		return nil, err
 	}
 
 	singleData := &SingleResponse{}
-	if err := json.Unmarshal(resp, singleData); err != nil {
+	err = json.Unmarshal(resp, singleData)
+	metrics.ObserveUpstream("single", started, err)
+	if err != nil {
 		log.Errorln(string(resp))
 
-		panic(err)
+		return nil, err
 	}
 
-	cache.Set(fmt.Sprintf("single_location_%d", locationID), singleData, 0)
+	f.cache.Set(fmt.Sprintf("%ssingle_location_%d", f.prefix, locationID), singleData, 0)
 
 	return singleData, nil
 }
@@ -1,10 +1,9 @@
 package util
 
 import (
+	"fmt"
 	"hash/fnv"
-	"math/rand"
 	"net/http"
-	"regexp"
 
 	"github.com/sirupsen/logrus"
 )
@@ -15,33 +14,9 @@ func Hash(s string) uint32 {
 	return h.Sum32()
 }
 
-var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
-
-func RandomString(n int) string {
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
-	}
-	return string(b)
-}
-
-func URLtoLatLng(url string) map[string]string {
-	re := regexp.MustCompile(`!3d(-?\d+.\d+)`)
-	lat := re.FindStringSubmatch(url)
-	if len(lat) != 2 {
-		return nil
-	}
-
-	re = regexp.MustCompile(`!4d(-?\d+.\d+)`)
-	lng := re.FindStringSubmatch(url)
-	if len(lng) != 2 {
-		return nil
-	}
-
-	return map[string]string{
-		"lat": lat[1],
-		"lng": lng[1],
-	}
+// MapLink is the Google Maps link of a lat, lng pair stored as the original address of a resolution.
+func MapLink(loc []float64) string {
+	return fmt.Sprintf("https://www.google.com/maps/?q=%f,%f&ll=%f,%f&z=21", loc[0], loc[1], loc[0], loc[1])
 }
 
 // Gathering the long URL from the short google maps url
@@ -0,0 +1,171 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxOpenAddressLength   = 500
+	MaxApartmentLength     = 200
+	MaxTweetContentsLength = 5000
+	MaxNoteLength          = 500
+
+	// Bir gönüllünün düzelttiği konumun orijinal konumdan en fazla bu kadar uzakta olmasını bekliyoruz
+	MaxShiftKm = 50.0
+
+	earthRadiusKm = 6371.0
+)
+
+type Bounds struct {
+	MinLat float64
+	MaxLat float64
+	MinLng float64
+	MaxLng float64
+}
+
+func (b Bounds) Contains(lat, lng float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// Area is the union of the boxes a project accepts locations in, an empty area accepts every valid coordinate.
+type Area []Bounds
+
+func (a Area) Contains(lat, lng float64) bool {
+	if len(a) == 0 {
+		return true
+	}
+
+	for _, b := range a {
+		if b.Contains(lat, lng) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	Turkey = Bounds{MinLat: 35.8, MaxLat: 42.2, MinLng: 25.6, MaxLng: 44.9}
+
+	// Depremden etkilenen iller (Adana'dan Diyarbakır'a, Hatay'dan Malatya'ya), varsayılan projenin şehirleri bunların
+	// hepsini kapsamıyor
+	AffectedRegion = Bounds{MinLat: 35.8, MaxLat: 39.8, MinLng: 34.5, MaxLng: 41.5}
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fe := range e {
+		messages = append(messages, fmt.Sprintf("%s: %s", fe.Field, fe.Message))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (e *Errors) Add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Resolution is the data of a single /resolve submission after the new address has been parsed.
+type Resolution struct {
+	Category string
+	// RequiredFields come from the category registry, the parent category's included.
+	RequiredFields []string
+	// Area is the area of the project the entry belongs to.
+	Area          Area
+	Location      []float64
+	Original      []float64
+	OpenAddress   string
+	Apartment     string
+	TweetContents string
+}
+
+func Resolve(r *Resolution) error {
+	errs := make(Errors, 0)
+
+	Coordinates(&errs, "new_address", r.Location, r.Area)
+	Shift(&errs, "new_address", r.Original, r.Location, r.Area)
+	Required(&errs, r.Category, r.RequiredFields, map[string]string{
+		"open_address":   r.OpenAddress,
+		"apartment":      r.Apartment,
+		"tweet_contents": r.TweetContents,
+	})
+	Length(&errs, "open_address", r.OpenAddress, MaxOpenAddressLength)
+	Length(&errs, "apartment", r.Apartment, MaxApartmentLength)
+	Length(&errs, "tweet_contents", r.TweetContents, MaxTweetContentsLength)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func Coordinates(errs *Errors, field string, loc []float64, area Area) {
+	if len(loc) != 2 {
+		errs.Add(field, "location must have exactly 2 coordinates")
+
+		return
+	}
+
+	lat, lng := loc[0], loc[1]
+
+	if math.IsNaN(lat) || math.IsNaN(lng) || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		errs.Add(field, "coordinates %f,%f are out of range", lat, lng)
+
+		return
+	}
+
+	if !area.Contains(lat, lng) {
+		errs.Add(field, "coordinates %f,%f are not in the regions of the project", lat, lng)
+	}
+}
+
+// Shift skips the entries the feed placed outside the area, the volunteer is moving them into it.
+func Shift(errs *Errors, field string, original, loc []float64, area Area) {
+	if len(original) != 2 || len(loc) != 2 || (original[0] == 0 && original[1] == 0) || !area.Contains(original[0], original[1]) {
+		return
+	}
+
+	if distance := DistanceKm(original, loc); distance > MaxShiftKm {
+		errs.Add(field, "location is %.1f km away from the original location (max %.0f km)", distance, MaxShiftKm)
+	}
+}
+
+func Required(errs *Errors, category string, fields []string, values map[string]string) {
+	for _, field := range fields {
+		if len(strings.TrimSpace(values[field])) == 0 {
+			errs.Add(field, "required for category %s", category)
+		}
+	}
+}
+
+func Length(errs *Errors, field, value string, max int) {
+	if l := utf8.RuneCountInString(value); l > max {
+		errs.Add(field, "must be at most %d characters, got %d", max, l)
+	}
+}
+
+// DistanceKm returns the haversine distance between two lat/lng pairs.
+func DistanceKm(a, b []float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(b[0] - a[0])
+	dLng := toRad(b[1] - a[1])
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(a[0]))*math.Cos(toRad(b[0]))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
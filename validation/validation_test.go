@@ -0,0 +1,232 @@
+package validation
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Antakya ve İskenderun, aralarında 50 km'den az var
+var (
+	antakya    = []float64{36.2021, 36.1601}
+	iskenderun = []float64{36.5872, 36.1735}
+	istanbul   = []float64{41.0082, 28.9784}
+)
+
+func fields(errs Errors) []string {
+	result := make([]string, 0, len(errs))
+	for _, e := range errs {
+		result = append(result, e.Field)
+	}
+
+	return result
+}
+
+func TestAreaContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		area     Area
+		lat, lng float64
+		want     bool
+	}{
+		{"empty area accepts everything", Area{}, -33.9, 151.2, true},
+		{"inside the only box", Area{AffectedRegion}, antakya[0], antakya[1], true},
+		{"outside the only box", Area{AffectedRegion}, istanbul[0], istanbul[1], false},
+		{"inside the second box", Area{AffectedRegion, Turkey}, istanbul[0], istanbul[1], true},
+		{"on the edge", Area{AffectedRegion}, AffectedRegion.MinLat, AffectedRegion.MaxLng, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.area.Contains(tt.lat, tt.lng); got != tt.want {
+				t.Errorf("Contains(%f, %f) = %t, want %t", tt.lat, tt.lng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoordinates(t *testing.T) {
+	tests := []struct {
+		name    string
+		loc     []float64
+		area    Area
+		message string
+	}{
+		{"inside the area", antakya, Area{AffectedRegion}, ""},
+		{"any valid coordinate without an area", istanbul, nil, ""},
+		{"outside the area", istanbul, Area{AffectedRegion}, "not in the regions of the project"},
+		{"single coordinate", []float64{36.2}, nil, "exactly 2 coordinates"},
+		{"no coordinates", nil, nil, "exactly 2 coordinates"},
+		{"latitude out of range", []float64{91, 36.1}, nil, "out of range"},
+		{"longitude out of range", []float64{36.2, -181}, nil, "out of range"},
+		{"nan", []float64{math.NaN(), 36.1}, nil, "out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(Errors, 0)
+			Coordinates(&errs, "new_address", tt.loc, tt.area)
+
+			if len(tt.message) == 0 {
+				if len(errs) > 0 {
+					t.Fatalf("Coordinates(%v) = %s, want no errors", tt.loc, errs)
+				}
+
+				return
+			}
+
+			if len(errs) != 1 || errs[0].Field != "new_address" || !strings.Contains(errs[0].Message, tt.message) {
+				t.Errorf("Coordinates(%v) = %v, want one new_address error containing %q", tt.loc, errs, tt.message)
+			}
+		})
+	}
+}
+
+func TestShift(t *testing.T) {
+	tests := []struct {
+		name     string
+		original []float64
+		loc      []float64
+		area     Area
+		wantErr  bool
+	}{
+		{"nearby", antakya, iskenderun, nil, false},
+		{"too far", antakya, istanbul, nil, true},
+		{"no original", nil, istanbul, nil, false},
+		{"original at zero", []float64{0, 0}, istanbul, nil, false},
+		{"original outside the area is being moved in", istanbul, antakya, Area{AffectedRegion}, false},
+		{"original inside the area", antakya, []float64{38.0, 38.3}, Area{AffectedRegion}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(Errors, 0)
+			Shift(&errs, "new_address", tt.original, tt.loc, tt.area)
+
+			if got := len(errs) > 0; got != tt.wantErr {
+				t.Errorf("Shift(%v, %v) = %v, want error %t", tt.original, tt.loc, errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequired(t *testing.T) {
+	values := map[string]string{
+		"open_address": "Cumhuriyet Mah. 12. Sok.",
+		"apartment":    "  ",
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   []string
+	}{
+		{"nothing required", nil, []string{}},
+		{"filled", []string{"open_address"}, []string{}},
+		{"whitespace only", []string{"apartment"}, []string{"apartment"}},
+		{"missing", []string{"open_address", "tweet_contents"}, []string{"tweet_contents"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(Errors, 0)
+			Required(&errs, "enkaz", tt.fields, values)
+
+			if got := fields(errs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Required(%v) fields = %v, want %v", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		max     int
+		wantErr bool
+	}{
+		{"empty", "", 3, false},
+		{"at the limit", "abc", 3, false},
+		{"over the limit", "abcd", 3, true},
+		{"runes are counted, not bytes", "ığü", 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(Errors, 0)
+			Length(&errs, "apartment", tt.value, tt.max)
+
+			if got := len(errs) > 0; got != tt.wantErr {
+				t.Errorf("Length(%q, %d) = %v, want error %t", tt.value, tt.max, errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"same point", antakya, antakya, 0},
+		{"antakya to iskenderun", antakya, iskenderun, 42.9},
+		{"one degree of latitude", []float64{36, 36}, []float64{37, 36}, 111.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DistanceKm(tt.a, tt.b); math.Abs(got-tt.want) > 0.5 {
+				t.Errorf("DistanceKm(%v, %v) = %.1f, want %.1f", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolution *Resolution
+		want       []string
+	}{
+		{"valid", &Resolution{
+			Category:       "enkaz",
+			RequiredFields: []string{"open_address"},
+			Area:           Area{AffectedRegion},
+			Location:       iskenderun,
+			Original:       antakya,
+			OpenAddress:    "Cumhuriyet Mah.",
+		}, nil},
+		{"every error is reported", &Resolution{
+			Category:       "enkaz",
+			RequiredFields: []string{"open_address", "apartment"},
+			Area:           Area{AffectedRegion},
+			Location:       istanbul,
+			Original:       antakya,
+			Apartment:      strings.Repeat("a", MaxApartmentLength+1),
+		}, []string{"new_address", "new_address", "open_address", "apartment"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Resolve(tt.resolution)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Resolve() = %s, want nil", err)
+				}
+
+				return
+			}
+
+			errs, ok := err.(Errors)
+			if !ok {
+				t.Fatalf("Resolve() = %v, want Errors", err)
+			}
+			if got := fields(errs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"id":"1","event":"verified"}`)
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		want      error
+	}{
+		{"valid", secret, Sign(secret, now, body), strconv.FormatInt(now, 10), body, nil},
+		{"within tolerance", secret, Sign(secret, now-240, body), strconv.FormatInt(now-240, 10), body, nil},
+		{"wrong secret", "other", Sign(secret, now, body), strconv.FormatInt(now, 10), body, ErrInvalidSignature},
+		{"tampered body", secret, Sign(secret, now, body), strconv.FormatInt(now, 10), []byte(`{"id":"2","event":"verified"}`), ErrInvalidSignature},
+		{"timestamp not signed", secret, Sign(secret, now, body), strconv.FormatInt(now-1, 10), body, ErrInvalidSignature},
+		{"signature without prefix", secret, strings.TrimPrefix(Sign(secret, now, body), "sha256="), strconv.FormatInt(now, 10), body, ErrInvalidSignature},
+		{"empty signature", secret, "", strconv.FormatInt(now, 10), body, ErrInvalidSignature},
+		{"invalid timestamp", secret, Sign(secret, now, body), "yesterday", body, ErrInvalidSignature},
+		{"expired", secret, Sign(secret, now-600, body), strconv.FormatInt(now-600, 10), body, ErrExpiredTimestamp},
+		{"from the future", secret, Sign(secret, now+600, body), strconv.FormatInt(now+600, 10), body, ErrExpiredTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, 5*time.Minute)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte("{}")
+
+	signature := Sign("s3cret", 1700000000, body)
+	if !strings.HasPrefix(signature, "sha256=") || len(signature) != len("sha256=")+64 {
+		t.Errorf("Sign = %q, want sha256= and a hex SHA-256", signature)
+	}
+
+	if Sign("s3cret", 1700000000, body) != signature {
+		t.Errorf("Sign is not deterministic")
+	}
+	if Sign("s3cret", 1700000001, body) == signature {
+		t.Errorf("Sign ignores the timestamp")
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a) != 64 || a == b {
+		t.Errorf("NewSecret = %q and %q, want two different 32 byte hex secrets", a, b)
+	}
+}
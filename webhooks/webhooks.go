@@ -0,0 +1,375 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/YusufOzmen01/veri-kontrol-backend/core/config"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/categories"
+	"github.com/YusufOzmen01/veri-kontrol-backend/repository/locations"
+	webhooksRepository "github.com/YusufOzmen01/veri-kontrol-backend/repository/webhooks"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventVerified  = "verified"
+	EventCorrected = "corrected"
+)
+
+var Events = []string{EventVerified, EventCorrected}
+
+// Payload is the body of every delivery. Location is the resolution with what the teams need to reach the victim: the
+// location, the open address, the apartment, the parsed address and the redacted tweet. The volunteer who resolved it
+// is left out, the phone numbers in the signals are masked and the raw tweet is never sent.
+type Payload struct {
+	ID        string                `json:"id"`
+	Event     string                `json:"event"`
+	CreatedAt time.Time             `json:"created_at"`
+	Location  *locations.LocationDB `json:"location"`
+}
+
+type Dispatcher interface {
+	// Enqueue adds the deliveries of a written location to the outbox.
+	Enqueue(ctx context.Context, location *locations.LocationDB) error
+	// Follow enqueues the writes to locations after the cursor stored in the outbox until ctx is done, a restart
+	// continues where it stopped. Every replica follows, the dedupe keys keep the deliveries unique.
+	Follow(locations locations.Repository) func(ctx context.Context) error
+	// Work sends the due deliveries of the outbox until ctx is done.
+	Work(ctx context.Context) error
+}
+
+// Categories loads the category registry, the endpoints of a top level category get its sub-categories too.
+type Categories interface {
+	Load(ctx context.Context) (*categories.Registry, error)
+}
+
+type dispatcher struct {
+	cfg        config.Webhooks
+	repo       webhooksRepository.Repository
+	categories Categories
+	client     *http.Client
+}
+
+func NewDispatcher(cfg config.Webhooks, repo webhooksRepository.Repository, categories Categories) Dispatcher {
+	return &dispatcher{
+		cfg:        cfg,
+		repo:       repo,
+		categories: categories,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+	}
+}
+
+// eventsOf sends corrected only when the location was moved away from the feed, Provenance is set then. Corrected is
+// also set on the entries confirmed unchanged.
+func eventsOf(location *locations.LocationDB) []string {
+	names := make([]string, 0)
+	if location.Verified {
+		names = append(names, EventVerified)
+	}
+	if location.Provenance != nil {
+		names = append(names, EventCorrected)
+	}
+
+	return names
+}
+
+func subscribed(endpoint *webhooksRepository.Endpoint, event string, category *categories.Category) bool {
+	matched := false
+	for _, e := range endpoint.Events {
+		if e == event {
+			matched = true
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	if len(endpoint.Categories) == 0 {
+		return true
+	}
+
+	if category == nil {
+		return false
+	}
+
+	for _, id := range endpoint.Categories {
+		if id == category.ID || id == category.Parent {
+			return true
+		}
+	}
+
+	return false
+}
+
+// snapshotOf is the location as it is sent to the subscribers, see Payload.
+func snapshotOf(location *locations.LocationDB) *locations.LocationDB {
+	// Ekipler gönüllünün bilgilerine ihtiyaç duymuyor
+	snapshot := *location
+	snapshot.Sender = nil
+
+	if snapshot.Signals != nil {
+		snapshot.Signals = snapshot.Signals.Masked()
+	}
+
+	return &snapshot
+}
+
+func (d *dispatcher) Enqueue(ctx context.Context, location *locations.LocationDB) error {
+	endpoints, err := d.repo.GetEndpoints(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	registry, err := d.categories.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.enqueue(ctx, endpoints, registry, location)
+}
+
+func (d *dispatcher) enqueue(ctx context.Context, endpoints []*webhooksRepository.Endpoint, registry *categories.Registry, location *locations.LocationDB) error {
+	names := eventsOf(location)
+	if len(names) == 0 {
+		return nil
+	}
+
+	snapshot := snapshotOf(location)
+	category := registry.Get(location.Category)
+
+	for _, endpoint := range endpoints {
+		for _, name := range names {
+			if !subscribed(endpoint, name, category) {
+				continue
+			}
+
+			delivery := &webhooksRepository.Delivery{
+				ID:         primitive.NewObjectID(),
+				EndpointID: endpoint.ID,
+				DedupeKey:  fmt.Sprintf("%s:%s:%d:%s:%d", endpoint.ID.Hex(), location.ProjectID, location.EntryID, name, location.UpdatedAt.UnixNano()),
+				Event:      name,
+				EntryID:    location.EntryID,
+			}
+
+			payload, err := json.Marshal(&Payload{
+				ID:        delivery.ID.Hex(),
+				Event:     name,
+				CreatedAt: time.Now(),
+				Location:  snapshot,
+			})
+			if err != nil {
+				return err
+			}
+			delivery.Payload = string(payload)
+
+			if err := d.repo.Enqueue(ctx, delivery); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// followOverlap is read again on every poll, a write can commit after a later updated_at was already read.
+const followOverlap = 10 * time.Second
+
+func (d *dispatcher) Follow(locations locations.Repository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(d.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			if err := d.follow(ctx, locations); err != nil && ctx.Err() == nil {
+				logrus.Errorf("couldn't enqueue webhooks: %s", err)
+			}
+		}
+	}
+}
+
+func (d *dispatcher) follow(ctx context.Context, locations locations.Repository) error {
+	cursor, err := d.repo.GetCursor(ctx)
+	if err != nil {
+		return err
+	}
+
+	// İlk çalışmada geçmişteki bütün çözümleri göndermiyoruz
+	if cursor.IsZero() {
+		return d.repo.SetCursor(ctx, time.Now())
+	}
+
+	written, err := locations.GetUpdatedSince(ctx, cursor.Add(-followOverlap))
+	if err != nil || len(written) == 0 {
+		return err
+	}
+
+	endpoints, err := d.repo.GetEndpoints(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	registry, err := d.categories.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Cursor sadece kuyruğa eklenen son kayda kadar ilerler, hata olursa bir sonraki turda kaldığı yerden devam eder
+	for _, location := range written {
+		if err := d.enqueue(ctx, endpoints, registry, location); err != nil {
+			return fmt.Errorf("entry %d: %w", location.EntryID, err)
+		}
+
+		if location.UpdatedAt.After(cursor) {
+			cursor = location.UpdatedAt
+		}
+	}
+
+	return d.repo.SetCursor(ctx, cursor)
+}
+
+func (d *dispatcher) Work(ctx context.Context) error {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// Endpoint'ler her turda yeniden okunur, devre dışı bırakılanlara gönderim yapılmaz
+		list, err := d.repo.GetEndpoints(ctx, true)
+		if err != nil {
+			logrus.Errorf("couldn't load webhook endpoints: %s", err)
+
+			continue
+		}
+
+		endpoints := make(map[primitive.ObjectID]*webhooksRepository.Endpoint, len(list))
+		for _, endpoint := range list {
+			endpoints[endpoint.ID] = endpoint
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < d.cfg.Workers; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for ctx.Err() == nil {
+					// Gönderim bitmeden başka bir replika almasın
+					delivery, err := d.repo.ClaimDue(ctx, time.Now().Add(2*d.cfg.Timeout))
+					if err != nil {
+						if !errors.Is(err, webhooksRepository.ErrNotFound) && ctx.Err() == nil {
+							logrus.Errorf("couldn't claim webhook delivery: %s", err)
+						}
+
+						return
+					}
+
+					d.deliver(ctx, endpoints[delivery.EndpointID], delivery)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func (d *dispatcher) deliver(ctx context.Context, endpoint *webhooksRepository.Endpoint, delivery *webhooksRepository.Delivery) {
+	started := time.Now()
+
+	statusCode, err := d.send(ctx, endpoint, delivery)
+
+	attempt := &webhooksRepository.Attempt{
+		At:         started,
+		StatusCode: statusCode,
+		DurationMs: float64(time.Since(started).Microseconds()) / 1000,
+	}
+
+	status := webhooksRepository.StatusDelivered
+	next := started
+
+	if err != nil {
+		attempt.Error = err.Error()
+
+		if delivery.AttemptCount+1 >= d.cfg.MaxAttempts || endpoint == nil {
+			status = webhooksRepository.StatusFailed
+		} else {
+			status = webhooksRepository.StatusPending
+			next = time.Now().Add(d.backoff(delivery.AttemptCount + 1))
+		}
+
+		logrus.Warnf("webhook delivery %s to endpoint %s failed: %s", delivery.ID.Hex(), delivery.EndpointID.Hex(), err)
+	}
+
+	// Kapanırken context iptal edilmiş olabilir, sonuç yine de kaydedilmeli
+	if err := d.repo.RecordAttempt(context.Background(), delivery.ID, attempt, status, next); err != nil {
+		logrus.Errorf("couldn't record webhook attempt of %s: %s", delivery.ID.Hex(), err)
+	}
+}
+
+func (d *dispatcher) send(ctx context.Context, endpoint *webhooksRepository.Endpoint, delivery *webhooksRepository.Delivery) (int, error) {
+	if endpoint == nil {
+		return 0, errors.New("endpoint is deactivated")
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, delivery.ID.Hex())
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (d *dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.BackoffBase
+	for i := 1; i < attempt && wait < d.cfg.BackoffMax; i++ {
+		wait *= 2
+	}
+
+	if wait > d.cfg.BackoffMax {
+		wait = d.cfg.BackoffMax
+	}
+
+	return wait
+}